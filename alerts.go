@@ -0,0 +1,152 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"web-scrappers/alerts"
+
+	"github.com/gorilla/mux"
+	bolt "go.etcd.io/bbolt"
+)
+
+const (
+	// defaultAlertMaxAttempts is how often a webhook is tried before giving up
+	defaultAlertMaxAttempts = 5
+
+	// defaultAlertLogSize is the number of deliveries kept in the log
+	defaultAlertLogSize = 1000
+
+	// defaultDeliveriesLimit is the page size of the delivery log endpoint
+	defaultDeliveriesLimit = 50
+)
+
+// alertManager checks scrapes against alert rules; set up in main
+var alertManager *alerts.Manager
+
+// AlertListResponse is returned by GET /api/v1/alerts
+type AlertListResponse struct {
+	Rules []alerts.Rule `json:"rules"`
+	Count int           `json:"count"`
+}
+
+// DeliveryListResponse is returned by GET /api/v1/alerts/deliveries
+type DeliveryListResponse struct {
+	Deliveries []alerts.Delivery `json:"deliveries"`
+	Count      int               `json:"count"`
+}
+
+// openAlerts starts the alert manager. ALERT_MAX_ATTEMPTS is how often a
+// webhook is tried, with backoff starting at 30 seconds, and
+// ALERT_LOG_SIZE the number of deliveries kept.
+func openAlerts(db *bolt.DB) (*alerts.Manager, error) {
+	return alerts.NewManager(db, alerts.Config{
+		MaxAttempts: envInt("ALERT_MAX_ATTEMPTS", defaultAlertMaxAttempts),
+		BaseBackoff: 30 * time.Second,
+		Timeout:     10 * time.Second,
+		MaxLog:      envInt("ALERT_LOG_SIZE", defaultAlertLogSize),
+	})
+}
+
+func handleListAlerts(w http.ResponseWriter, r *http.Request) {
+	rules := alertManager.Rules()
+	// Secrets are only shown when a rule is created
+	for i := range rules {
+		rules[i].Secret = ""
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(AlertListResponse{Rules: rules, Count: len(rules)})
+}
+
+func handleCreateAlert(w http.ResponseWriter, r *http.Request) {
+	var req alerts.Rule
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, ErrorResponse{
+			Error:   "invalid_body",
+			Message: fmt.Sprintf("Request body must be JSON: %v", err),
+		})
+		return
+	}
+
+	rule, err := alertManager.AddRule(req)
+	if err != nil {
+		if errors.Is(err, alerts.ErrInvalid) {
+			writeError(w, http.StatusBadRequest, ErrorResponse{
+				Error:   "invalid_parameters",
+				Message: err.Error(),
+			})
+			return
+		}
+		writeAlertError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Location", "/api/v1/alerts/"+rule.ID)
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(rule)
+}
+
+func handleGetAlert(w http.ResponseWriter, r *http.Request) {
+	rule, err := alertManager.Rule(mux.Vars(r)["id"])
+	if err != nil {
+		writeAlertError(w, err)
+		return
+	}
+	rule.Secret = ""
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(rule)
+}
+
+func handleDeleteAlert(w http.ResponseWriter, r *http.Request) {
+	if err := alertManager.DeleteRule(mux.Vars(r)["id"]); err != nil {
+		writeAlertError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func handleListDeliveries(w http.ResponseWriter, r *http.Request) {
+	limit := defaultDeliveriesLimit
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			writeError(w, http.StatusBadRequest, ErrorResponse{
+				Error:   "invalid_parameters",
+				Message: fmt.Sprintf("Invalid 'limit' value '%s', expected a positive number", v),
+			})
+			return
+		}
+		limit = n
+	}
+
+	deliveries, err := alertManager.Deliveries(r.URL.Query().Get("rule_id"), limit)
+	if err != nil {
+		writeAlertError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(DeliveryListResponse{Deliveries: deliveries, Count: len(deliveries)})
+}
+
+// writeAlertError reports a failed rule lookup or update
+func writeAlertError(w http.ResponseWriter, err error) {
+	if errors.Is(err, alerts.ErrNotFound) {
+		writeError(w, http.StatusNotFound, ErrorResponse{
+			Error:   "alert_not_found",
+			Message: "No alert rule exists with this ID",
+		})
+		return
+	}
+	writeError(w, http.StatusInternalServerError, ErrorResponse{
+		Error:   "alert_store_failed",
+		Message: fmt.Sprintf("Failed to access alert rules: %v", err),
+	})
+}
@@ -0,0 +1,480 @@
+// Package alerts evaluates alert rules against successive scrapes of a
+// product and delivers the alerts that fire as signed webhooks, retrying
+// failed deliveries and keeping a log of every attempt
+package alerts
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"web-scrappers/history"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+var (
+	// rulesBucket maps rule IDs to their Rule
+	rulesBucket = []byte("alert_rules")
+
+	// deliveriesBucket maps delivery IDs, which sort by creation time, to
+	// their Delivery
+	deliveriesBucket = []byte("alert_deliveries")
+)
+
+// ErrNotFound is returned when a rule ID is unknown
+var ErrNotFound = errors.New("alert rule not found")
+
+// ErrInvalid is returned, wrapped, for incomplete rules
+var ErrInvalid = errors.New("invalid alert rule")
+
+// Event is the payload of an alert webhook
+type Event struct {
+	Type        RuleType             `json:"type"`
+	RuleID      string               `json:"rule_id"`
+	ProductID   string               `json:"product_id"`
+	Site        string               `json:"site"`
+	URL         string               `json:"url"`
+	Name        string               `json:"name"`
+	Previous    *history.Observation `json:"previous,omitempty"`
+	Current     history.Observation  `json:"current"`
+	TriggeredAt time.Time            `json:"triggered_at"`
+}
+
+// DeliveryStatus is the state of a webhook delivery
+type DeliveryStatus string
+
+const (
+	StatusPending   DeliveryStatus = "pending"
+	StatusDelivered DeliveryStatus = "delivered"
+	StatusFailed    DeliveryStatus = "failed"
+)
+
+// Delivery is one alert sent, or being sent, to a webhook
+type Delivery struct {
+	ID         string         `json:"id"`
+	RuleID     string         `json:"rule_id"`
+	WebhookURL string         `json:"webhook_url"`
+	Event      Event          `json:"event"`
+	Status     DeliveryStatus `json:"status"`
+	Attempts   int            `json:"attempts"`
+
+	// ResponseCode and LastError describe the latest attempt
+	ResponseCode int    `json:"response_code,omitempty"`
+	LastError    string `json:"last_error,omitempty"`
+
+	CreatedAt   time.Time  `json:"created_at"`
+	DeliveredAt *time.Time `json:"delivered_at,omitempty"`
+}
+
+// Config controls webhook delivery
+type Config struct {
+	// MaxAttempts is the number of tries before a delivery is marked failed
+	MaxAttempts int
+
+	// BaseBackoff is the wait after the first failure; it doubles after
+	// each later one
+	BaseBackoff time.Duration
+
+	// Timeout bounds a single webhook request
+	Timeout time.Duration
+
+	// MaxLog is the number of deliveries kept in the log
+	MaxLog int
+}
+
+// Manager stores rules, checks scrapes against them and sends webhooks
+type Manager struct {
+	db     *bolt.DB
+	cfg    Config
+	client *http.Client
+
+	mu    sync.Mutex
+	rules map[string]*Rule
+
+	stop    context.Context
+	stopAll context.CancelFunc
+	wg      sync.WaitGroup
+}
+
+// NewManager loads the rules from db and resumes deliveries that were
+// pending when the process last stopped
+func NewManager(db *bolt.DB, cfg Config) (*Manager, error) {
+	if cfg.MaxAttempts <= 0 {
+		cfg.MaxAttempts = 1
+	}
+	m := &Manager{
+		db:     db,
+		cfg:    cfg,
+		client: &http.Client{Timeout: cfg.Timeout},
+		rules:  make(map[string]*Rule),
+	}
+	m.stop, m.stopAll = context.WithCancel(context.Background())
+
+	var pending []*Delivery
+	err := db.Update(func(tx *bolt.Tx) error {
+		rules, err := tx.CreateBucketIfNotExists(rulesBucket)
+		if err != nil {
+			return err
+		}
+		deliveries, err := tx.CreateBucketIfNotExists(deliveriesBucket)
+		if err != nil {
+			return err
+		}
+		err = rules.ForEach(func(k, v []byte) error {
+			var rule Rule
+			if err := json.Unmarshal(v, &rule); err != nil {
+				log.Printf("alerts: skipping unreadable rule %s: %v", k, err)
+				return nil
+			}
+			m.rules[rule.ID] = &rule
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		return deliveries.ForEach(func(k, v []byte) error {
+			var d Delivery
+			if err := json.Unmarshal(v, &d); err == nil && d.Status == StatusPending {
+				pending = append(pending, &d)
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to load alert rules: %w", err)
+	}
+
+	for _, d := range pending {
+		m.send(d)
+	}
+	if len(pending) > 0 {
+		log.Printf("alerts: resumed %d pending deliveries", len(pending))
+	}
+	return m, nil
+}
+
+// AddRule validates and stores a rule. A random secret is generated when
+// none is given.
+func (m *Manager) AddRule(rule Rule) (*Rule, error) {
+	if err := rule.Validate(); err != nil {
+		return nil, err
+	}
+
+	id, err := newID(8)
+	if err != nil {
+		return nil, err
+	}
+	rule.ID = id
+	rule.CreatedAt = time.Now().UTC()
+	if rule.Secret == "" {
+		if rule.Secret, err = newID(24); err != nil {
+			return nil, err
+		}
+	}
+
+	data, err := json.Marshal(rule)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode rule: %w", err)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	err = m.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(rulesBucket).Put([]byte(rule.ID), data)
+	})
+	if err != nil {
+		return nil, err
+	}
+	m.rules[rule.ID] = &rule
+	return &rule, nil
+}
+
+// Rules returns every rule, oldest first
+func (m *Manager) Rules() []Rule {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	rules := make([]Rule, 0, len(m.rules))
+	for _, rule := range m.rules {
+		rules = append(rules, *rule)
+	}
+	sort.Slice(rules, func(a, b int) bool { return rules[a].CreatedAt.Before(rules[b].CreatedAt) })
+	return rules
+}
+
+// Rule returns a rule by ID
+func (m *Manager) Rule(id string) (*Rule, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	rule, ok := m.rules[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	r := *rule
+	return &r, nil
+}
+
+// DeleteRule removes a rule; its delivery log is kept
+func (m *Manager) DeleteRule(id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.rules[id]; !ok {
+		return ErrNotFound
+	}
+	delete(m.rules, id)
+	return m.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(rulesBucket).Delete([]byte(id))
+	})
+}
+
+// Check evaluates every matching rule for a product scraped from uri on
+// site, whose state went from previous (nil on the first scrape) to
+// current, and queues a webhook for each rule that fires
+func (m *Manager) Check(site, uri string, record *history.ProductRecord, previous *history.Observation, current history.Observation) {
+	m.mu.Lock()
+	var fired []Rule
+	for _, rule := range m.rules {
+		if rule.Matches(site, uri, record.ID) && rule.Fires(previous, current) {
+			fired = append(fired, *rule)
+		}
+	}
+	m.mu.Unlock()
+
+	for _, rule := range fired {
+		id, err := deliveryID()
+		if err != nil {
+			log.Printf("alerts: %v", err)
+			continue
+		}
+		d := &Delivery{
+			ID:         id,
+			RuleID:     rule.ID,
+			WebhookURL: rule.WebhookURL,
+			Event: Event{
+				Type:        rule.Type,
+				RuleID:      rule.ID,
+				ProductID:   record.ID,
+				Site:        site,
+				URL:         uri,
+				Name:        record.Name,
+				Previous:    previous,
+				Current:     current,
+				TriggeredAt: time.Now().UTC(),
+			},
+			Status:    StatusPending,
+			CreatedAt: time.Now().UTC(),
+		}
+		if err := m.saveDelivery(d); err != nil {
+			log.Printf("alerts: failed to store delivery for rule %s: %v", rule.ID, err)
+			continue
+		}
+		m.send(d)
+	}
+
+	if len(fired) > 0 {
+		if err := m.pruneDeliveries(); err != nil {
+			log.Printf("alerts: failed to prune delivery log: %v", err)
+		}
+	}
+}
+
+// Deliveries returns the newest deliveries first, optionally only those of
+// one rule, up to limit
+func (m *Manager) Deliveries(ruleID string, limit int) ([]Delivery, error) {
+	deliveries := []Delivery{}
+	err := m.db.View(func(tx *bolt.Tx) error {
+		c := tx.Bucket(deliveriesBucket).Cursor()
+		for k, v := c.Last(); k != nil && (limit <= 0 || len(deliveries) < limit); k, v = c.Prev() {
+			var d Delivery
+			if err := json.Unmarshal(v, &d); err != nil {
+				return err
+			}
+			if ruleID == "" || d.RuleID == ruleID {
+				deliveries = append(deliveries, d)
+			}
+		}
+		return nil
+	})
+	return deliveries, err
+}
+
+// errStopped is returned by attempt when Close interrupted the request
+var errStopped = errors.New("alerts manager stopped")
+
+// Close stops retrying; pending deliveries are resumed on the next start
+func (m *Manager) Close() {
+	m.stopAll()
+	m.wg.Wait()
+}
+
+// send delivers d in the background, retrying with exponential backoff
+func (m *Manager) send(d *Delivery) {
+	m.wg.Add(1)
+	go func() {
+		defer m.wg.Done()
+
+		backoff := m.cfg.BaseBackoff
+		for d.Status == StatusPending {
+			if err := m.attempt(d); err != nil {
+				if errors.Is(err, errStopped) {
+					// Left pending as stored, for the next start
+					return
+				}
+				d.LastError = err.Error()
+				if d.Attempts >= m.cfg.MaxAttempts {
+					d.Status = StatusFailed
+				}
+			}
+			if err := m.saveDelivery(d); err != nil {
+				log.Printf("alerts: failed to save delivery %s: %v", d.ID, err)
+			}
+			if d.Status != StatusPending {
+				return
+			}
+
+			select {
+			case <-time.After(backoff):
+				backoff *= 2
+			case <-m.stop.Done():
+				return
+			}
+		}
+	}()
+}
+
+// attempt posts d's event once, marking it delivered on a 2xx response
+func (m *Manager) attempt(d *Delivery) error {
+	m.mu.Lock()
+	rule, ok := m.rules[d.RuleID]
+	secret := ""
+	if ok {
+		secret = rule.Secret
+	}
+	m.mu.Unlock()
+	if !ok {
+		d.Status = StatusFailed
+		return errors.New("rule was deleted")
+	}
+
+	body, err := json.Marshal(d.Event)
+	if err != nil {
+		d.Status = StatusFailed
+		return err
+	}
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+
+	req, err := http.NewRequestWithContext(m.stop, "POST", d.WebhookURL, bytes.NewReader(body))
+	if err != nil {
+		d.Status = StatusFailed
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Ucuzu-Event", string(d.Event.Type))
+	req.Header.Set("X-Ucuzu-Delivery", d.ID)
+	req.Header.Set("X-Ucuzu-Timestamp", timestamp)
+	req.Header.Set("X-Ucuzu-Signature", Sign(secret, timestamp, body))
+
+	d.Attempts++
+	resp, err := m.client.Do(req)
+	if err != nil {
+		if m.stop.Err() != nil && errors.Is(err, context.Canceled) {
+			// Cut short by Close; the webhook never answered
+			d.Attempts--
+			return errStopped
+		}
+		d.ResponseCode = 0
+		return err
+	}
+	resp.Body.Close()
+	d.ResponseCode = resp.StatusCode
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook answered %d", resp.StatusCode)
+	}
+
+	now := time.Now().UTC()
+	d.Status = StatusDelivered
+	d.DeliveredAt = &now
+	d.LastError = ""
+	return nil
+}
+
+// Sign returns the X-Ucuzu-Signature of a payload: "sha256=" followed by
+// the hex HMAC-SHA256, keyed by secret, of the timestamp, a dot and the body
+func Sign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// saveDelivery writes d to the log
+func (m *Manager) saveDelivery(d *Delivery) error {
+	data, err := json.Marshal(d)
+	if err != nil {
+		return fmt.Errorf("failed to encode delivery: %w", err)
+	}
+	return m.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(deliveriesBucket).Put([]byte(d.ID), data)
+	})
+}
+
+// pruneDeliveries drops the oldest finished deliveries beyond MaxLog
+func (m *Manager) pruneDeliveries() error {
+	if m.cfg.MaxLog <= 0 {
+		return nil
+	}
+	return m.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(deliveriesBucket)
+		excess := b.Stats().KeyN - m.cfg.MaxLog
+
+		var drop [][]byte
+		c := b.Cursor()
+		for k, v := c.First(); k != nil && len(drop) < excess; k, v = c.Next() {
+			var d Delivery
+			if json.Unmarshal(v, &d) == nil && d.Status == StatusPending {
+				continue
+			}
+			drop = append(drop, k)
+		}
+		for _, k := range drop {
+			if err := b.Delete(k); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// deliveryID returns an identifier that sorts by creation time
+func deliveryID() (string, error) {
+	suffix, err := newID(4)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%016x%s", time.Now().UnixNano(), suffix), nil
+}
+
+// newID returns n random bytes, hex encoded
+func newID(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate ID: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,183 @@
+package alerts
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"web-scrappers/history"
+	"web-scrappers/scrappers"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+func price(minor int64) *scrappers.Money {
+	return &scrappers.Money{Minor: minor, Currency: "AZN"}
+}
+
+func TestRuleFires(t *testing.T) {
+	tests := []struct {
+		name     string
+		rule     Rule
+		previous *history.Observation
+		current  history.Observation
+		want     bool
+	}{
+		{"below on first scrape", Rule{Type: RulePriceBelow, Threshold: 1000}, nil, history.Observation{Price: price(99900)}, true},
+		{"below crossing", Rule{Type: RulePriceBelow, Threshold: 1000}, &history.Observation{Price: price(105000)}, history.Observation{Price: price(100000)}, true},
+		{"below already", Rule{Type: RulePriceBelow, Threshold: 1000}, &history.Observation{Price: price(99000)}, history.Observation{Price: price(98000)}, false},
+		{"drop enough", Rule{Type: RulePriceDrop, Percent: 10}, &history.Observation{Price: price(100000)}, history.Observation{Price: price(89000)}, true},
+		{"drop too small", Rule{Type: RulePriceDrop, Percent: 10}, &history.Observation{Price: price(100000)}, history.Observation{Price: price(95000)}, false},
+		{"price rise", Rule{Type: RulePriceDrop}, &history.Observation{Price: price(100000)}, history.Observation{Price: price(110000)}, false},
+		{"availability", Rule{Type: RuleAvailabilityChange}, &history.Observation{Availability: "Stokda var"}, history.Observation{Availability: "Stokda yoxdur"}, true},
+		{"back in stock", Rule{Type: RuleBackInStock}, &history.Observation{Availability: "Stokda yoxdur"}, history.Observation{Availability: "Stokda var"}, true},
+		{"still in stock", Rule{Type: RuleBackInStock}, &history.Observation{Availability: "In stock"}, history.Observation{Availability: "Stokda var"}, false},
+		{"new discount", Rule{Type: RuleNewDiscount}, &history.Observation{}, history.Observation{DiscountPercent: 15}, true},
+		{"old discount", Rule{Type: RuleNewDiscount}, &history.Observation{DiscountPercent: 10}, history.Observation{DiscountPercent: 15}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.rule.Fires(tt.previous, tt.current); got != tt.want {
+				t.Errorf("Fires = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestInStock(t *testing.T) {
+	tests := []struct {
+		text      string
+		in, known bool
+	}{
+		{"Stokda var", true, true},
+		{"Stokda yoxdur", false, true},
+		{"Нет в наличии", false, true},
+		{"In stock", true, true},
+		{"Available", true, true},
+		{"Not available", false, true},
+		{"Available on order", false, true},
+		{"No disponible", false, true},
+		{"Pre-order", false, true},
+		{"Под заказ", false, true},
+		{"Variantlar", false, false},
+		{"", false, false},
+	}
+	for _, tt := range tests {
+		if in, known := InStock(tt.text); in != tt.in || known != tt.known {
+			t.Errorf("InStock(%q) = %v, %v; want %v, %v", tt.text, in, known, tt.in, tt.known)
+		}
+	}
+}
+
+func TestWebhookDelivery(t *testing.T) {
+	var calls atomic.Int32
+	received := make(chan *http.Request, 1)
+	var body []byte
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Fail the first attempt so the delivery is retried
+		if calls.Add(1) == 1 {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		body, _ = io.ReadAll(r.Body)
+		received <- r
+	}))
+	defer srv.Close()
+
+	db, err := bolt.Open(filepath.Join(t.TempDir(), "alerts.db"), 0600, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	m, err := NewManager(db, Config{MaxAttempts: 3, BaseBackoff: 10 * time.Millisecond, Timeout: time.Second})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer m.Close()
+
+	rule, err := m.AddRule(Rule{Type: RulePriceDrop, Percent: 5, URL: "https://kontakt.az/a-b-c", WebhookURL: srv.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	record := &history.ProductRecord{ID: "kontakt:1", Name: "Phone"}
+
+	// Another product and a rise in price do not fire
+	m.Check("kontakt", "https://kontakt.az/x-y-z", record, &history.Observation{Price: price(1000)}, history.Observation{Price: price(500)})
+	m.Check("kontakt", "https://kontakt.az/a-b-c", record, &history.Observation{Price: price(1000)}, history.Observation{Price: price(1100)})
+	m.Check("kontakt", "https://www.kontakt.az/a-b-c/", record, &history.Observation{Price: price(1000)}, history.Observation{Price: price(900)})
+
+	select {
+	case r := <-received:
+		want := Sign(rule.Secret, r.Header.Get("X-Ucuzu-Timestamp"), body)
+		if got := r.Header.Get("X-Ucuzu-Signature"); got != want {
+			t.Errorf("signature = %q, want %q", got, want)
+		}
+		if r.Header.Get("X-Ucuzu-Event") != "price_drop" {
+			t.Errorf("unexpected event header %q", r.Header.Get("X-Ucuzu-Event"))
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("webhook was not retried")
+	}
+
+	// The log is written right after the response
+	var deliveries []Delivery
+	for deadline := time.Now().Add(time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		if deliveries, err = m.Deliveries(rule.ID, 10); err != nil {
+			t.Fatal(err)
+		}
+		if len(deliveries) == 1 && deliveries[0].Status == StatusDelivered {
+			break
+		}
+	}
+	if len(deliveries) != 1 || deliveries[0].Status != StatusDelivered || deliveries[0].Attempts != 2 {
+		t.Fatalf("unexpected delivery log %+v", deliveries)
+	}
+}
+
+func TestCloseKeepsInterruptedDeliveryPending(t *testing.T) {
+	started := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// With the body read, the server notices the client hanging up
+		io.ReadAll(r.Body)
+		close(started)
+		<-r.Context().Done()
+	}))
+	defer srv.Close()
+
+	db, err := bolt.Open(filepath.Join(t.TempDir(), "alerts.db"), 0600, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	m, err := NewManager(db, Config{MaxAttempts: 1, BaseBackoff: 10 * time.Millisecond, Timeout: 10 * time.Second})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rule, err := m.AddRule(Rule{Type: RulePriceDrop, Percent: 5, URL: "https://kontakt.az/a-b-c", WebhookURL: srv.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	record := &history.ProductRecord{ID: "kontakt:1", Name: "Phone"}
+	m.Check("kontakt", "https://kontakt.az/a-b-c", record, &history.Observation{Price: price(1000)}, history.Observation{Price: price(900)})
+
+	select {
+	case <-started:
+	case <-time.After(2 * time.Second):
+		t.Fatal("webhook was not called")
+	}
+	// The only attempt is cut short, so it must not count
+	m.Close()
+
+	deliveries, err := m.Deliveries(rule.ID, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(deliveries) != 1 || deliveries[0].Status != StatusPending || deliveries[0].Attempts != 0 {
+		t.Fatalf("unexpected delivery log %+v", deliveries)
+	}
+}
@@ -0,0 +1,169 @@
+package alerts
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+	"unicode"
+
+	"web-scrappers/cache"
+	"web-scrappers/history"
+)
+
+// RuleType is the condition a rule watches for
+type RuleType string
+
+const (
+	// RulePriceBelow fires when the price falls to Threshold or below
+	RulePriceBelow RuleType = "price_below"
+
+	// RulePriceDrop fires when the price drops by at least Percent since the
+	// previous scrape
+	RulePriceDrop RuleType = "price_drop"
+
+	// RuleAvailabilityChange fires whenever the availability text changes
+	RuleAvailabilityChange RuleType = "availability_change"
+
+	// RuleBackInStock fires when an out-of-stock product is in stock again
+	RuleBackInStock RuleType = "back_in_stock"
+
+	// RuleNewDiscount fires when a product without a discount gets one
+	RuleNewDiscount RuleType = "new_discount"
+)
+
+// Rule is an alert condition and the webhook notified when it fires
+type Rule struct {
+	ID   string   `json:"id"`
+	Type RuleType `json:"type"`
+
+	// Scope: a product ID from price history, a product URL, or else every
+	// product of Site, or of every site when Site is empty
+	ProductID string `json:"product_id,omitempty"`
+	URL       string `json:"url,omitempty"`
+	Site      string `json:"site,omitempty"`
+
+	// Threshold is the price, in major units, of a price_below rule
+	Threshold float64 `json:"threshold,omitempty"`
+
+	// Percent is the smallest drop reported by a price_drop rule
+	Percent float64 `json:"percent,omitempty"`
+
+	WebhookURL string `json:"webhook_url"`
+
+	// Secret signs the webhook payloads
+	Secret string `json:"secret,omitempty"`
+
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Validate checks that the rule is complete
+func (r *Rule) Validate() error {
+	switch r.Type {
+	case RulePriceBelow:
+		if r.Threshold <= 0 {
+			return fmt.Errorf("%w: a price_below rule needs a positive 'threshold'", ErrInvalid)
+		}
+	case RulePriceDrop:
+		if r.Percent < 0 || r.Percent >= 100 {
+			return fmt.Errorf("%w: 'percent' must be between 0 and 100", ErrInvalid)
+		}
+	case RuleAvailabilityChange, RuleBackInStock, RuleNewDiscount:
+	default:
+		return fmt.Errorf("%w: unknown rule type '%s'", ErrInvalid, r.Type)
+	}
+
+	u, err := url.Parse(r.WebhookURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("%w: 'webhook_url' must be an http(s) URL", ErrInvalid)
+	}
+	return nil
+}
+
+// Matches reports whether the rule covers the product scraped from uri
+func (r *Rule) Matches(site, uri, productID string) bool {
+	switch {
+	case r.ProductID != "":
+		return r.ProductID == productID
+	case r.URL != "":
+		return cache.NormalizeURL(r.URL) == cache.NormalizeURL(uri)
+	default:
+		return r.Site == "" || r.Site == site
+	}
+}
+
+// Fires reports whether the change from previous to current satisfies the
+// rule. previous is nil for the first scrape of a product. Rules fire on
+// the scrape where the condition becomes true, not on every later one.
+func (r *Rule) Fires(previous *history.Observation, current history.Observation) bool {
+	switch r.Type {
+	case RulePriceBelow:
+		if current.Price == nil || current.Price.Float() > r.Threshold {
+			return false
+		}
+		return previous == nil || previous.Price == nil || previous.Price.Float() > r.Threshold
+
+	case RulePriceDrop:
+		if previous == nil || previous.Price == nil || current.Price == nil {
+			return false
+		}
+		before, after := previous.Price.Float(), current.Price.Float()
+		if before <= 0 || after >= before {
+			return false
+		}
+		return (before-after)/before*100 >= r.Percent
+
+	case RuleAvailabilityChange:
+		return previous != nil && previous.Availability != "" && current.Availability != "" &&
+			previous.Availability != current.Availability
+
+	case RuleBackInStock:
+		if previous == nil {
+			return false
+		}
+		wasIn, wasKnown := InStock(previous.Availability)
+		isIn, isKnown := InStock(current.Availability)
+		return wasKnown && isKnown && !wasIn && isIn
+
+	case RuleNewDiscount:
+		return previous != nil && previous.DiscountPercent <= 0 && current.DiscountPercent > 0
+	}
+	return false
+}
+
+// outOfStockPhrases and inStockPhrases classify availability texts in
+// Azerbaijani, English and Russian; out-of-stock phrases are checked first
+// since they often contain an in-stock phrase, as in "not available".
+// Items only sold on order are not in stock either.
+var (
+	outOfStockPhrases = []string{
+		"yoxdur", "mövcud deyil", "bitib", "tükənib", "sifarişlə",
+		"out of stock", "unavailable", "not available", "not in stock", "no disponible", "on order", "pre order", "preorder", "backorder",
+		"нет в наличии", "не в наличии", "отсутствует", "недоступен", "не доступен", "под заказ",
+	}
+	inStockPhrases = []string{"var", "mövcuddur", "stokda", "in stock", "available", "в наличии", "есть"}
+)
+
+// InStock classifies an availability text; known is false when the text
+// is empty or not recognized
+func InStock(availability string) (inStock, known bool) {
+	// Phrases match whole words only, so "var" does not match "variant"
+	words := strings.FieldsFunc(strings.ToLower(availability), func(r rune) bool {
+		return !unicode.IsLetter(r)
+	})
+	if len(words) == 0 {
+		return false, false
+	}
+	text := " " + strings.Join(words, " ") + " "
+	for _, phrase := range outOfStockPhrases {
+		if strings.Contains(text, " "+phrase+" ") {
+			return false, true
+		}
+	}
+	for _, phrase := range inStockPhrases {
+		if strings.Contains(text, " "+phrase+" ") {
+			return true, true
+		}
+	}
+	return false, false
+}
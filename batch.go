@@ -0,0 +1,177 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"web-scrappers/scrappers"
+)
+
+const (
+	// defaultBatchSiteConcurrency is how many scrapes of one site run at once
+	defaultBatchSiteConcurrency = 2
+
+	// defaultBatchMaxItems caps the number of items in a single batch
+	defaultBatchMaxItems = 200
+)
+
+// BatchRequest is the body of POST /api/v1/scrape/batch
+type BatchRequest struct {
+	Items []ScrapRequest `json:"items"`
+
+	// PerSiteConcurrency lowers the per-site worker limit for this batch
+	PerSiteConcurrency int `json:"per_site_concurrency,omitempty"`
+
+	// Timeout bounds each item, as a Go duration such as "30s"
+	Timeout string `json:"timeout,omitempty"`
+}
+
+// BatchItemResult is the outcome of one batch item; exactly one of Product
+// and Error is set
+type BatchItemResult struct {
+	Index   int                `json:"index"`
+	Site    string             `json:"site"`
+	URI     string             `json:"uri"`
+	Product *scrappers.Product `json:"product,omitempty"`
+	Error   *ErrorResponse     `json:"error,omitempty"`
+}
+
+// BatchResponse is returned by POST /api/v1/scrape/batch
+type BatchResponse struct {
+	Results   []BatchItemResult `json:"results"`
+	Succeeded int               `json:"succeeded"`
+	Failed    int               `json:"failed"`
+}
+
+// envInt reads a positive integer from the environment, or returns fallback
+func envInt(name string, fallback int) int {
+	if v, err := strconv.Atoi(os.Getenv(name)); err == nil && v > 0 {
+		return v
+	}
+	return fallback
+}
+
+func handleScrapeBatch(w http.ResponseWriter, r *http.Request) {
+	var req BatchRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, ErrorResponse{
+			Error:   "invalid_body",
+			Message: fmt.Sprintf("Request body must be JSON: %v", err),
+		})
+		return
+	}
+
+	maxItems := envInt("BATCH_MAX_ITEMS", defaultBatchMaxItems)
+	if len(req.Items) == 0 || len(req.Items) > maxItems {
+		writeError(w, http.StatusBadRequest, ErrorResponse{
+			Error:   "invalid_parameters",
+			Message: fmt.Sprintf("'items' must contain between 1 and %d entries", maxItems),
+		})
+		return
+	}
+
+	opts := scrappers.ScrapeOptions{}
+	if req.Timeout != "" {
+		timeout, err := time.ParseDuration(req.Timeout)
+		if err != nil || timeout <= 0 {
+			writeError(w, http.StatusBadRequest, ErrorResponse{
+				Error:   "invalid_parameters",
+				Message: fmt.Sprintf("Invalid 'timeout' value '%s', expected a duration such as 30s", req.Timeout),
+			})
+			return
+		}
+		opts.Timeout = timeout
+	}
+
+	perSite := envInt("BATCH_SITE_CONCURRENCY", defaultBatchSiteConcurrency)
+	if req.PerSiteConcurrency > 0 && req.PerSiteConcurrency < perSite {
+		perSite = req.PerSiteConcurrency
+	}
+
+	results := runBatch(r.Context(), req.Items, perSite, opts)
+	if r.Context().Err() != nil {
+		log.Printf("batch of %d items abandoned: %v", len(req.Items), r.Context().Err())
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(newBatchResponse(results))
+}
+
+// newBatchResponse counts the outcomes of results
+func newBatchResponse(results []BatchItemResult) BatchResponse {
+	resp := BatchResponse{Results: results}
+	for _, res := range results {
+		if res.Error != nil {
+			resp.Failed++
+		} else {
+			resp.Succeeded++
+		}
+	}
+	return resp
+}
+
+// runBatch scrapes every item concurrently, allowing at most perSite scrapes
+// of the same site at a time. Results keep the order of items.
+func runBatch(ctx context.Context, items []ScrapRequest, perSite int, opts scrappers.ScrapeOptions) []BatchItemResult {
+	results := make([]BatchItemResult, len(items))
+	slots := make(map[string]chan struct{})
+
+	var wg sync.WaitGroup
+	for i, item := range items {
+		results[i] = BatchItemResult{Index: i, Site: item.Site, URI: item.URI}
+
+		if item.URI == "" {
+			results[i].Error = &ErrorResponse{
+				Error:   "missing_parameters",
+				Message: "The 'uri' field is required",
+			}
+			continue
+		}
+
+		site, scraper, err := resolveScraper(item.Site, item.URI)
+		if err != nil {
+			errorResp := resolveErrorResponse(item.Site, item.URI, err)
+			results[i].Error = &errorResp
+			continue
+		}
+		results[i].Site = site
+
+		sem, ok := slots[site]
+		if !ok {
+			sem = make(chan struct{}, perSite)
+			slots[site] = sem
+		}
+
+		wg.Add(1)
+		go func(res *BatchItemResult, site string, scraper scrappers.Scraper, sem chan struct{}) {
+			defer wg.Done()
+
+			select {
+			case sem <- struct{}{}:
+				defer func() { <-sem }()
+			case <-ctx.Done():
+				res.Error = &ErrorResponse{Error: "cancelled", Message: ctx.Err().Error()}
+				return
+			}
+
+			product, err := scrapeGroup.Scrape(ctx, site, scraper, res.URI, opts)
+			if err != nil {
+				_, errorResp := scrapeErrorResponse(err)
+				res.Error = &errorResp
+				return
+			}
+			res.Product = product
+		}(&results[i], site, scraper, sem)
+	}
+	wg.Wait()
+
+	return results
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"web-scrappers/scrappers"
+)
+
+// concurrencyScraper records how many scrapes run at once; each takes delay,
+// or blocks until its context is done when delay is zero
+type concurrencyScraper struct {
+	delay  time.Duration
+	active atomic.Int32
+	peak   atomic.Int32
+}
+
+func (s *concurrencyScraper) Scrape(url string) (*scrappers.Product, error) {
+	return s.ScrapeContext(context.Background(), url, scrappers.ScrapeOptions{})
+}
+
+func (s *concurrencyScraper) ScrapeContext(ctx context.Context, url string, opts scrappers.ScrapeOptions) (*scrappers.Product, error) {
+	n := s.active.Add(1)
+	defer s.active.Add(-1)
+	for {
+		peak := s.peak.Load()
+		if n <= peak || s.peak.CompareAndSwap(peak, n) {
+			break
+		}
+	}
+
+	if s.delay == 0 {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	select {
+	case <-time.After(s.delay):
+		return &scrappers.Product{Name: "Test", URL: url}, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (s *concurrencyScraper) GetSiteName() string        { return "Test" }
+func (s *concurrencyScraper) IsValidURL(url string) bool { return true }
+
+// batchScrapers registers the fake scrapers used by the batch tests
+func batchScrapers() (fast, other, blocking *concurrencyScraper) {
+	fast = &concurrencyScraper{delay: 20 * time.Millisecond}
+	other = &concurrencyScraper{delay: 20 * time.Millisecond}
+	blocking = &concurrencyScraper{}
+	scrappers.RegisterScraper("batchfast", fast)
+	scrappers.RegisterScraper("batchother", other)
+	scrappers.RegisterScraper("batchblocking", blocking)
+	return fast, other, blocking
+}
+
+func TestRunBatchLimitsSitesAndKeepsOrder(t *testing.T) {
+	fast, other, _ := batchScrapers()
+
+	var items []ScrapRequest
+	for i := 0; i < 6; i++ {
+		items = append(items, ScrapRequest{Site: "batchfast", URI: "https://fast.test/p" + string(rune('a'+i))})
+		items = append(items, ScrapRequest{Site: "batchother", URI: "https://other.test/p" + string(rune('a'+i))})
+	}
+	items = append(items, ScrapRequest{Site: "batchfast"}, ScrapRequest{Site: "nosuchsite", URI: "https://x.test/"})
+
+	results := runBatch(context.Background(), items, 2, scrappers.ScrapeOptions{})
+	if len(results) != len(items) {
+		t.Fatalf("got %d results, want %d", len(results), len(items))
+	}
+	for i, res := range results[:12] {
+		if res.Index != i || res.Error != nil || res.Product == nil || res.Product.URL != items[i].URI {
+			t.Errorf("result %d out of order or failed: %+v", i, res)
+		}
+	}
+	if res := results[12]; res.Error == nil || res.Error.Error != "missing_parameters" {
+		t.Errorf("item without uri: %+v", res)
+	}
+	if res := results[13]; res.Error == nil || res.Error.Error != "unsupported_site" {
+		t.Errorf("item of an unknown site: %+v", res)
+	}
+
+	if p := fast.peak.Load(); p != 2 {
+		t.Errorf("batchfast peaked at %d concurrent scrapes, want 2", p)
+	}
+	if p := other.peak.Load(); p != 2 {
+		t.Errorf("batchother peaked at %d concurrent scrapes, want 2", p)
+	}
+	if resp := newBatchResponse(results); resp.Succeeded != 12 || resp.Failed != 2 {
+		t.Errorf("unexpected counts %+v", resp)
+	}
+}
+
+func TestRunBatchCancelled(t *testing.T) {
+	_, _, blocking := batchScrapers()
+
+	var items []ScrapRequest
+	for i := 0; i < 4; i++ {
+		items = append(items, ScrapRequest{Site: "batchblocking", URI: "https://blocking.test/p" + string(rune('a'+i))})
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan []BatchItemResult)
+	go func() { done <- runBatch(ctx, items, 1, scrappers.ScrapeOptions{}) }()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for blocking.active.Load() == 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+
+	select {
+	case results := <-done:
+		for i, res := range results {
+			if res.Error == nil {
+				t.Errorf("result %d succeeded after cancellation", i)
+			}
+		}
+		if results[1].Error.Error != "cancelled" {
+			t.Errorf("queued item reported %+v, want cancelled", results[1].Error)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("runBatch did not return after cancellation")
+	}
+	if p := blocking.peak.Load(); p != 1 {
+		t.Errorf("peaked at %d concurrent scrapes, want 1", p)
+	}
+}
+
+func TestScrapeBatchMaxItems(t *testing.T) {
+	t.Setenv("BATCH_MAX_ITEMS", "2")
+
+	for _, tt := range []struct {
+		body string
+		code int
+	}{
+		{`{"items": []}`, http.StatusBadRequest},
+		{`{"items": [{"uri": "a"}, {"uri": "b"}, {"uri": "c"}]}`, http.StatusBadRequest},
+		{`{"items": [{"uri": ""}]}`, http.StatusOK},
+	} {
+		rec := httptest.NewRecorder()
+		handleScrapeBatch(rec, httptest.NewRequest(http.MethodPost, "/api/v1/scrape/batch", strings.NewReader(tt.body)))
+		if rec.Code != tt.code {
+			t.Errorf("%s: status %d, want %d", tt.body, rec.Code, tt.code)
+		}
+		if tt.code == http.StatusBadRequest && !strings.Contains(rec.Body.String(), "invalid_parameters") {
+			t.Errorf("%s: unexpected body %s", tt.body, rec.Body.String())
+		}
+	}
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"web-scrappers/cache"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+const (
+	// defaultCacheTTL is how long scrape results are served from the cache
+	defaultCacheTTL = 10 * time.Minute
+
+	// defaultCacheMaxEntries bounds the in-memory cache
+	defaultCacheMaxEntries = 1000
+
+	// cacheSweepInterval is how often expired results are deleted
+	cacheSweepInterval = 10 * time.Minute
+)
+
+// resultCache holds recent scrape results; set up in main
+var resultCache *cache.Cache
+
+// openCache configures the result cache from the environment. CACHE_TTL is
+// the default TTL, CACHE_SITE_TTLS a JSON object of per-site TTLs such as
+// {"kontakt":"30m"} and CACHE_MAX_ENTRIES the in-memory size. CACHE_DISK=1
+// also keeps results in db so they survive restarts.
+func openCache(db *bolt.DB) (*cache.Cache, error) {
+	cfg := cache.Config{
+		MaxEntries: envInt("CACHE_MAX_ENTRIES", defaultCacheMaxEntries),
+		DefaultTTL: defaultCacheTTL,
+		SiteTTLs:   make(map[string]time.Duration),
+	}
+
+	if raw := os.Getenv("CACHE_TTL"); raw != "" {
+		ttl, err := time.ParseDuration(raw)
+		if err != nil {
+			return nil, fmt.Errorf("invalid CACHE_TTL: %w", err)
+		}
+		cfg.DefaultTTL = ttl
+	}
+
+	if raw := os.Getenv("CACHE_SITE_TTLS"); raw != "" {
+		var ttls map[string]string
+		if err := json.Unmarshal([]byte(raw), &ttls); err != nil {
+			return nil, fmt.Errorf("invalid CACHE_SITE_TTLS: %w", err)
+		}
+		for site, value := range ttls {
+			ttl, err := time.ParseDuration(value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid CACHE_SITE_TTLS entry for %s: %w", site, err)
+			}
+			cfg.SiteTTLs[site] = ttl
+		}
+	}
+
+	if os.Getenv("CACHE_DISK") == "1" {
+		cfg.DB = db
+	}
+
+	return cache.New(cfg)
+}
+
+// cachePolicy is how a request wants the cache to be used
+type cachePolicy struct {
+	// MaxAge is the oldest acceptable cached result; negative means any
+	// result within the site TTL
+	MaxAge time.Duration
+
+	// Refresh skips the lookup; the fresh result is still stored
+	Refresh bool
+
+	// NoStore keeps the result out of the cache
+	NoStore bool
+}
+
+// parseCachePolicy reads the Cache-Control header and the max_age and
+// force_refresh query parameters; query parameters win over the header
+func parseCachePolicy(r *http.Request) (cachePolicy, error) {
+	policy := cachePolicy{MaxAge: -1}
+
+	for _, directive := range strings.Split(r.Header.Get("Cache-Control"), ",") {
+		name, value, _ := strings.Cut(strings.TrimSpace(strings.ToLower(directive)), "=")
+		switch name {
+		case "no-cache":
+			policy.Refresh = true
+		case "no-store":
+			policy.Refresh = true
+			policy.NoStore = true
+		case "max-age":
+			if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+				policy.MaxAge = time.Duration(seconds) * time.Second
+			}
+		}
+	}
+
+	query := r.URL.Query()
+	if v := query.Get("max_age"); v != "" {
+		seconds, err := strconv.Atoi(v)
+		if err != nil || seconds < 0 {
+			return policy, fmt.Errorf("invalid 'max_age' value '%s', expected seconds", v)
+		}
+		policy.MaxAge = time.Duration(seconds) * time.Second
+	}
+	if v := query.Get("force_refresh"); v != "" {
+		refresh, err := strconv.ParseBool(v)
+		if err != nil {
+			return policy, fmt.Errorf("invalid 'force_refresh' value '%s', expected true or false", v)
+		}
+		policy.Refresh = refresh
+	}
+
+	if policy.MaxAge == 0 {
+		policy.Refresh = true
+	}
+	return policy, nil
+}
+
+// writeCacheHeaders reports whether the response came from the cache
+func writeCacheHeaders(w http.ResponseWriter, hit bool, age time.Duration) {
+	if !hit {
+		w.Header().Set("X-Cache", "MISS")
+		return
+	}
+	w.Header().Set("X-Cache", "HIT")
+	w.Header().Set("Age", strconv.Itoa(int(age.Seconds())))
+}
@@ -0,0 +1,323 @@
+// Package cache stores scrape results keyed by site and normalized URL, in
+// an in-memory LRU with an optional bbolt-backed second level.
+package cache
+
+import (
+	"container/list"
+	"context"
+	"encoding/json"
+	"log"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"web-scrappers/scrappers"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// cacheBucket is the bbolt bucket of the on-disk backend
+var cacheBucket = []byte("scrape_cache")
+
+// trackingParams are query parameters that never change the page content;
+// utm_* parameters are dropped as well
+var trackingParams = map[string]bool{"fbclid": true, "gclid": true, "yclid": true, "_ga": true}
+
+// Entry is a cached scrape result
+type Entry struct {
+	Product  *scrappers.Product `json:"product"`
+	StoredAt time.Time          `json:"stored_at"`
+}
+
+// Age returns how long ago the entry was stored
+func (e Entry) Age() time.Duration {
+	return time.Since(e.StoredAt)
+}
+
+// Config controls cache size and freshness
+type Config struct {
+	// MaxEntries bounds the in-memory LRU
+	MaxEntries int
+
+	// DefaultTTL applies to sites without an entry in SiteTTLs
+	DefaultTTL time.Duration
+
+	// SiteTTLs overrides the TTL per site identifier
+	SiteTTLs map[string]time.Duration
+
+	// DB, when set, persists entries so they survive restarts
+	DB *bolt.DB
+}
+
+// Cache is a two-level scrape result cache. It is safe for concurrent use.
+type Cache struct {
+	cfg Config
+
+	mu    sync.Mutex
+	order *list.List               // front is most recently used
+	items map[string]*list.Element // values are *lruItem
+}
+
+// lruItem is an element of the in-memory LRU
+type lruItem struct {
+	key   string
+	entry Entry
+}
+
+// New creates a cache; with cfg.DB set, the on-disk bucket is created
+func New(cfg Config) (*Cache, error) {
+	if cfg.MaxEntries <= 0 {
+		cfg.MaxEntries = 1000
+	}
+	if cfg.DB != nil {
+		err := cfg.DB.Update(func(tx *bolt.Tx) error {
+			_, err := tx.CreateBucketIfNotExists(cacheBucket)
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &Cache{
+		cfg:   cfg,
+		order: list.New(),
+		items: make(map[string]*list.Element),
+	}, nil
+}
+
+// TTL returns how long results of site stay fresh
+func (c *Cache) TTL(site string) time.Duration {
+	if ttl, ok := c.cfg.SiteTTLs[site]; ok {
+		return ttl
+	}
+	return c.cfg.DefaultTTL
+}
+
+// Get returns the cached entry for site and rawURL if it is younger than
+// both the site TTL and maxAge (when maxAge >= 0)
+func (c *Cache) Get(site, rawURL string, maxAge time.Duration) (Entry, bool) {
+	key := Key(site, rawURL)
+	limit := c.TTL(site)
+	if maxAge >= 0 && maxAge < limit {
+		limit = maxAge
+	}
+
+	entry, ok := c.getMemory(key)
+	if !ok {
+		entry, ok = c.getDisk(key)
+		if ok && entry.Age() > c.TTL(site) {
+			// Expired for everyone: drop it instead of evicting fresh entries
+			c.deleteDisk(key)
+			return Entry{}, false
+		}
+		if ok {
+			c.setMemory(key, entry)
+		}
+	}
+	if !ok || entry.Age() > limit {
+		return Entry{}, false
+	}
+	return entry, true
+}
+
+// Set stores product as the latest result for site and rawURL
+func (c *Cache) Set(site, rawURL string, product *scrappers.Product) {
+	if c.TTL(site) <= 0 {
+		return
+	}
+	key := Key(site, rawURL)
+	entry := Entry{Product: product, StoredAt: time.Now()}
+	c.setMemory(key, entry)
+	c.setDisk(key, entry)
+}
+
+func (c *Cache) getMemory(key string) (Entry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	el, ok := c.items[key]
+	if !ok {
+		return Entry{}, false
+	}
+	c.order.MoveToFront(el)
+	return el.Value.(*lruItem).entry, true
+}
+
+func (c *Cache) setMemory(key string, entry Entry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.items[key]; ok {
+		el.Value.(*lruItem).entry = entry
+		c.order.MoveToFront(el)
+		return
+	}
+	c.items[key] = c.order.PushFront(&lruItem{key: key, entry: entry})
+	for c.order.Len() > c.cfg.MaxEntries {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.items, oldest.Value.(*lruItem).key)
+	}
+}
+
+func (c *Cache) getDisk(key string) (Entry, bool) {
+	if c.cfg.DB == nil {
+		return Entry{}, false
+	}
+	var entry Entry
+	found := false
+	err := c.cfg.DB.View(func(tx *bolt.Tx) error {
+		data := tx.Bucket(cacheBucket).Get([]byte(key))
+		if data == nil {
+			return nil
+		}
+		found = true
+		return json.Unmarshal(data, &entry)
+	})
+	if err != nil {
+		log.Printf("cache: failed to read %s: %v", key, err)
+		return Entry{}, false
+	}
+	return entry, found
+}
+
+func (c *Cache) setDisk(key string, entry Entry) {
+	if c.cfg.DB == nil {
+		return
+	}
+	data, err := json.Marshal(entry)
+	if err != nil {
+		log.Printf("cache: failed to encode %s: %v", key, err)
+		return
+	}
+	err = c.cfg.DB.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(cacheBucket).Put([]byte(key), data)
+	})
+	if err != nil {
+		log.Printf("cache: failed to write %s: %v", key, err)
+	}
+}
+
+func (c *Cache) deleteDisk(key string) {
+	err := c.cfg.DB.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(cacheBucket).Delete([]byte(key))
+	})
+	if err != nil {
+		log.Printf("cache: failed to delete %s: %v", key, err)
+	}
+}
+
+// Sweep removes the entries older than their site's TTL from memory and
+// disk and returns how many it removed from disk
+func (c *Cache) Sweep() (int, error) {
+	c.mu.Lock()
+	for el := c.order.Front(); el != nil; {
+		next := el.Next()
+		item := el.Value.(*lruItem)
+		if item.entry.Age() > c.TTL(keySite(item.key)) {
+			c.order.Remove(el)
+			delete(c.items, item.key)
+		}
+		el = next
+	}
+	c.mu.Unlock()
+
+	if c.cfg.DB == nil {
+		return 0, nil
+	}
+	removed := 0
+	err := c.cfg.DB.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(cacheBucket)
+		var expired [][]byte
+		err := b.ForEach(func(k, v []byte) error {
+			var entry Entry
+			if err := json.Unmarshal(v, &entry); err != nil || entry.Age() > c.TTL(keySite(string(k))) {
+				expired = append(expired, append([]byte(nil), k...))
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		for _, k := range expired {
+			if err := b.Delete(k); err != nil {
+				return err
+			}
+		}
+		removed = len(expired)
+		return nil
+	})
+	return removed, err
+}
+
+// RunSweeper sweeps the cache every interval until ctx is done
+func (c *Cache) RunSweeper(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if _, err := c.Sweep(); err != nil {
+			log.Printf("cache: sweep failed: %v", err)
+		}
+	}
+}
+
+// keySite returns the site identifier of a cache key
+func keySite(key string) string {
+	site, _, _ := strings.Cut(key, "|")
+	return site
+}
+
+// Key builds the cache key of a scrape
+func Key(site, rawURL string) string {
+	return site + "|" + NormalizeURL(rawURL)
+}
+
+// NormalizeURL canonicalizes rawURL so trivially different spellings of the
+// same product page share a cache entry: scheme and host are lowercased, a
+// leading "www." and the fragment are dropped, tracking parameters are
+// removed, the query is sorted and a trailing slash is trimmed
+func NormalizeURL(rawURL string) string {
+	u, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil {
+		return rawURL
+	}
+
+	u.Scheme = strings.ToLower(u.Scheme)
+	u.Host = strings.TrimPrefix(strings.ToLower(u.Host), "www.")
+	u.Fragment = ""
+	u.RawFragment = ""
+	if len(u.Path) > 1 {
+		u.Path = strings.TrimRight(u.Path, "/")
+		u.RawPath = ""
+	}
+
+	query := u.Query()
+	for name := range query {
+		lower := strings.ToLower(name)
+		if trackingParams[lower] || strings.HasPrefix(lower, "utm_") {
+			query.Del(name)
+		}
+	}
+	keys := make([]string, 0, len(query))
+	for name := range query {
+		keys = append(keys, name)
+	}
+	sort.Strings(keys)
+	var parts []string
+	for _, name := range keys {
+		values := query[name]
+		sort.Strings(values)
+		for _, v := range values {
+			parts = append(parts, url.QueryEscape(name)+"="+url.QueryEscape(v))
+		}
+	}
+	u.RawQuery = strings.Join(parts, "&")
+
+	return u.String()
+}
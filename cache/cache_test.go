@@ -0,0 +1,151 @@
+package cache
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"web-scrappers/scrappers"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+func TestNormalizeURL(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"https://www.Kontakt.az/iphone-13/", "https://kontakt.az/iphone-13"},
+		{"https://kontakt.az/iphone-13#reviews", "https://kontakt.az/iphone-13"},
+		{"https://kontakt.az/p?b=2&utm_source=fb&a=1&fbclid=x", "https://kontakt.az/p?a=1&b=2"},
+		{"HTTPS://irshad.az/", "https://irshad.az/"},
+	}
+
+	for _, tt := range tests {
+		if got := NormalizeURL(tt.in); got != tt.want {
+			t.Errorf("NormalizeURL(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestCacheTTLAndMaxAge(t *testing.T) {
+	c, err := New(Config{
+		DefaultTTL: time.Hour,
+		SiteTTLs:   map[string]time.Duration{"kontakt": 0},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c.Set("irshad", "https://irshad.az/p?utm_medium=x", &scrappers.Product{Name: "p"})
+	entry, ok := c.Get("irshad", "https://www.irshad.az/p", -1)
+	if !ok || entry.Product.Name != "p" {
+		t.Fatalf("expected hit for normalized URL, got %v %+v", ok, entry)
+	}
+
+	time.Sleep(5 * time.Millisecond)
+	if _, ok := c.Get("irshad", "https://irshad.az/p", time.Millisecond); ok {
+		t.Error("expected miss for entry older than max age")
+	}
+	if _, ok := c.Get("optimal", "https://irshad.az/p", -1); ok {
+		t.Error("expected miss for a different site")
+	}
+
+	c.Set("kontakt", "https://kontakt.az/p", &scrappers.Product{Name: "k"})
+	if _, ok := c.Get("kontakt", "https://kontakt.az/p", -1); ok {
+		t.Error("expected site with zero TTL not to be cached")
+	}
+}
+
+func TestCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	c, err := New(Config{MaxEntries: 2, DefaultTTL: time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c.Set("s", "https://a.az/1", &scrappers.Product{})
+	c.Set("s", "https://a.az/2", &scrappers.Product{})
+	c.Get("s", "https://a.az/1", -1)
+	c.Set("s", "https://a.az/3", &scrappers.Product{})
+
+	if _, ok := c.Get("s", "https://a.az/2", -1); ok {
+		t.Error("expected least recently used entry to be evicted")
+	}
+	if _, ok := c.Get("s", "https://a.az/1", -1); !ok {
+		t.Error("expected recently used entry to be kept")
+	}
+}
+
+func TestCacheDiskBackend(t *testing.T) {
+	db, err := bolt.Open(filepath.Join(t.TempDir(), "cache.db"), 0600, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	cfg := Config{DefaultTTL: time.Hour, DB: db}
+	first, err := New(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	first.Set("s", "https://a.az/1", &scrappers.Product{Name: "persisted"})
+
+	// A new cache on the same database starts with an empty LRU
+	second, err := New(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	entry, ok := second.Get("s", "https://a.az/1", -1)
+	if !ok || entry.Product.Name != "persisted" {
+		t.Fatalf("expected hit from disk, got %v %+v", ok, entry)
+	}
+}
+
+func TestCacheDropsExpiredDiskEntries(t *testing.T) {
+	db, err := bolt.Open(filepath.Join(t.TempDir(), "cache.db"), 0600, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	cfg := Config{MaxEntries: 1, DefaultTTL: time.Hour, SiteTTLs: map[string]time.Duration{"short": time.Millisecond}, DB: db}
+	c, err := New(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Set("short", "https://a.az/old", &scrappers.Product{Name: "old"})
+	c.Set("s", "https://a.az/fresh", &scrappers.Product{Name: "fresh"})
+	time.Sleep(5 * time.Millisecond)
+
+	// Reading an expired entry from disk deletes it and keeps the LRU as it is
+	reopened, err := New(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reopened.setMemory(Key("s", "https://a.az/fresh"), Entry{Product: &scrappers.Product{Name: "fresh"}, StoredAt: time.Now()})
+	if _, ok := reopened.Get("short", "https://a.az/old", -1); ok {
+		t.Fatal("expired entry served")
+	}
+	if _, ok := reopened.getMemory(Key("s", "https://a.az/fresh")); !ok {
+		t.Error("expired entry evicted a fresh one")
+	}
+	if _, ok := reopened.getDisk(Key("short", "https://a.az/old")); ok {
+		t.Error("expired entry left on disk")
+	}
+
+	c.Set("short", "https://a.az/other", &scrappers.Product{Name: "other"})
+	time.Sleep(5 * time.Millisecond)
+	removed, err := c.Sweep()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if removed != 1 {
+		t.Errorf("Sweep removed %d entries, want 1", removed)
+	}
+	if _, ok := c.Get("s", "https://a.az/fresh", -1); !ok {
+		t.Error("Sweep removed a fresh entry")
+	}
+	if _, ok := c.getDisk(Key("short", "https://a.az/other")); ok {
+		t.Error("Sweep left an expired entry on disk")
+	}
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+
+	"web-scrappers/cache"
+	"web-scrappers/scrappers"
+)
+
+// flight is one in-progress scrape shared by every caller asking for the
+// same page
+type flight struct {
+	done    chan struct{}
+	product *scrappers.Product
+	err     error
+
+	waiters int // callers still interested, guarded by coalescer.mu
+	cancel  context.CancelFunc
+}
+
+// coalescer deduplicates concurrent scrapes of the same site and normalized
+// URL so they share one browser session or HTTP request
+type coalescer struct {
+	mu      sync.Mutex
+	flights map[string]*flight
+
+	// onScraped, when set, is called once for every successful scrape
+	onScraped func(site, uri string, product *scrappers.Product)
+
+	started   atomic.Int64 // scrapes actually run
+	coalesced atomic.Int64 // calls that joined a scrape already in flight
+}
+
+// scrapeGroup coalesces scrapes from every endpoint
+var scrapeGroup = &coalescer{flights: make(map[string]*flight)}
+
+// CoalesceStats reports how effective request coalescing has been
+type CoalesceStats struct {
+	Started   int64 `json:"started"`
+	Coalesced int64 `json:"coalesced"`
+	InFlight  int   `json:"in_flight"`
+}
+
+// Scrape runs scraper for uri, or waits for an identical scrape already in
+// flight. The shared scrape is detached from any single caller: it is only
+// cancelled once every caller waiting for it has gone away. Its timeout is
+// taken from the caller that started it; a caller joining it stops waiting
+// once its own opts.Timeout has passed.
+func (c *coalescer) Scrape(ctx context.Context, site string, scraper scrappers.Scraper, uri string, opts scrappers.ScrapeOptions) (*scrappers.Product, error) {
+	key := cache.Key(site, uri)
+
+	c.mu.Lock()
+	f, ok := c.flights[key]
+	if ok {
+		f.waiters++
+		c.mu.Unlock()
+		c.coalesced.Add(1)
+
+		if opts.Timeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, opts.Timeout)
+			defer cancel()
+		}
+	} else {
+		scrapeCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+		f = &flight{done: make(chan struct{}), waiters: 1, cancel: cancel}
+		c.flights[key] = f
+		c.mu.Unlock()
+		c.started.Add(1)
+
+		go func() {
+			defer cancel()
+			f.product, f.err = scraper.ScrapeContext(scrapeCtx, uri, opts)
+			if f.err == nil && c.onScraped != nil {
+				c.onScraped(site, uri, f.product)
+			}
+
+			c.mu.Lock()
+			c.forget(key, f)
+			c.mu.Unlock()
+			close(f.done)
+		}()
+	}
+
+	select {
+	case <-f.done:
+		return f.product, f.err
+	case <-ctx.Done():
+		c.mu.Lock()
+		f.waiters--
+		if f.waiters == 0 {
+			// Nobody wants the result any more; later callers start afresh
+			f.cancel()
+			c.forget(key, f)
+		}
+		c.mu.Unlock()
+		return nil, ctx.Err()
+	}
+}
+
+// forget removes f from the in-flight scrapes unless a newer flight has
+// replaced it; c.mu must be held
+func (c *coalescer) forget(key string, f *flight) {
+	if c.flights[key] == f {
+		delete(c.flights, key)
+	}
+}
+
+// Stats returns the coalescing counters
+func (c *coalescer) Stats() CoalesceStats {
+	c.mu.Lock()
+	inFlight := len(c.flights)
+	c.mu.Unlock()
+
+	return CoalesceStats{
+		Started:   c.started.Load(),
+		Coalesced: c.coalesced.Load(),
+		InFlight:  inFlight,
+	}
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"web-scrappers/scrappers"
+)
+
+// blockingScraper counts scrapes and blocks each one until release is closed
+type blockingScraper struct {
+	calls   atomic.Int32
+	release chan struct{}
+}
+
+func (s *blockingScraper) Scrape(url string) (*scrappers.Product, error) {
+	return s.ScrapeContext(context.Background(), url, scrappers.ScrapeOptions{})
+}
+
+func (s *blockingScraper) ScrapeContext(ctx context.Context, url string, opts scrappers.ScrapeOptions) (*scrappers.Product, error) {
+	s.calls.Add(1)
+	select {
+	case <-s.release:
+		return &scrappers.Product{URL: url}, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (s *blockingScraper) GetSiteName() string        { return "Test" }
+func (s *blockingScraper) IsValidURL(url string) bool { return true }
+
+func TestCoalescerSharesInFlightScrape(t *testing.T) {
+	group := &coalescer{flights: make(map[string]*flight)}
+	scraper := &blockingScraper{release: make(chan struct{})}
+
+	const callers = 5
+	products := make([]*scrappers.Product, callers)
+	var wg sync.WaitGroup
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			// URLs differ only in ways the cache key normalizes away
+			uri := "https://test.az/p"
+			if i%2 == 1 {
+				uri = "https://www.test.az/p?utm_source=x"
+			}
+			p, err := group.Scrape(context.Background(), "test", scraper, uri, scrappers.ScrapeOptions{})
+			if err != nil {
+				t.Error(err)
+			}
+			products[i] = p
+		}(i)
+	}
+
+	// Let every caller join before the scrape finishes
+	deadline := time.Now().Add(2 * time.Second)
+	for group.Stats().Coalesced < callers-1 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	close(scraper.release)
+	wg.Wait()
+
+	if n := scraper.calls.Load(); n != 1 {
+		t.Errorf("scraper ran %d times, want 1", n)
+	}
+	for i, p := range products {
+		if p != products[0] {
+			t.Errorf("caller %d got a different product", i)
+		}
+	}
+	stats := group.Stats()
+	if stats.Started != 1 || stats.Coalesced != callers-1 || stats.InFlight != 0 {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+}
+
+func TestCoalescerCancelsWhenAllCallersLeave(t *testing.T) {
+	group := &coalescer{flights: make(map[string]*flight)}
+	scraper := &blockingScraper{release: make(chan struct{})}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		_, err := group.Scrape(ctx, "test", scraper, "https://test.az/p", scrappers.ScrapeOptions{})
+		done <- err
+	}()
+
+	for scraper.calls.Load() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Fatalf("got %v, want context.Canceled", err)
+	}
+
+	// A new caller must not join the abandoned scrape
+	close(scraper.release)
+	if _, err := group.Scrape(context.Background(), "test", scraper, "https://test.az/p", scrappers.ScrapeOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if n := scraper.calls.Load(); n != 2 {
+		t.Errorf("scraper ran %d times, want 2", n)
+	}
+}
+
+func TestCoalescerJoinerKeepsItsTimeout(t *testing.T) {
+	group := &coalescer{flights: make(map[string]*flight)}
+	scraper := &blockingScraper{release: make(chan struct{})}
+	defer close(scraper.release)
+
+	go group.Scrape(context.Background(), "test", scraper, "https://test.az/p", scrappers.ScrapeOptions{})
+	for scraper.calls.Load() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	start := time.Now()
+	_, err := group.Scrape(context.Background(), "test", scraper, "https://test.az/p", scrappers.ScrapeOptions{Timeout: 20 * time.Millisecond})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("got %v, want context.DeadlineExceeded", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("joiner waited %v for the shared scrape", elapsed)
+	}
+	if n := scraper.calls.Load(); n != 1 {
+		t.Errorf("scraper ran %d times, want 1", n)
+	}
+}
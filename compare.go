@@ -0,0 +1,181 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	"web-scrappers/history"
+	"web-scrappers/matching"
+	"web-scrappers/scrappers"
+)
+
+// CompareOffer is one site's latest price for a compared product
+type CompareOffer struct {
+	ProductID    string           `json:"product_id"`
+	Site         string           `json:"site"`
+	Name         string           `json:"name"`
+	URL          string           `json:"url"`
+	Price        *scrappers.Money `json:"price"`
+	Availability string           `json:"availability"`
+	ObservedAt   time.Time        `json:"observed_at"`
+
+	// Confidence that this offer is the same product as the first one found
+	Confidence float64 `json:"confidence"`
+}
+
+// ComparedProduct groups the offers judged to be the same product
+type ComparedProduct struct {
+	Name       string           `json:"name"`
+	Brand      string           `json:"brand,omitempty"`
+	StorageGB  int              `json:"storage_gb,omitempty"`
+	RAMGB      int              `json:"ram_gb,omitempty"`
+	Confidence float64          `json:"confidence"`
+	BestPrice  *scrappers.Money `json:"best_price"`
+	Offers     []CompareOffer   `json:"offers"`
+}
+
+// CompareResponse is returned by GET /api/v1/compare
+type CompareResponse struct {
+	Query    string            `json:"query"`
+	Products []ComparedProduct `json:"products"`
+	Count    int               `json:"count"`
+}
+
+func handleCompare(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query().Get("q")
+	if q == "" {
+		writeError(w, http.StatusBadRequest, ErrorResponse{
+			Error:   "missing_parameters",
+			Message: "The 'q' parameter is required",
+		})
+		return
+	}
+
+	filters, err := scrappers.ParseSpecFilters(r.URL.Query()["spec"])
+	if err != nil {
+		writeError(w, http.StatusBadRequest, ErrorResponse{
+			Error:   "invalid_parameters",
+			Message: fmt.Sprintf("Invalid 'spec' value: %v", err),
+		})
+		return
+	}
+
+	products, err := compareProducts(priceHistory, q, filters)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, ErrorResponse{
+			Error:   "history_store_failed",
+			Message: fmt.Sprintf("Failed to read tracked products: %v", err),
+		})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(CompareResponse{Query: q, Products: products, Count: len(products)})
+}
+
+// compareProducts finds tracked products matching query and filters, groups
+// the same product across sites and orders offers, and then products, by price
+func compareProducts(store *history.Store, query string, filters []scrappers.SpecFilter) ([]ComparedProduct, error) {
+	records, err := store.Products()
+	if err != nil {
+		return nil, err
+	}
+
+	queryFeatures := matching.Extract(matching.Listing{Name: query})
+	var offers []CompareOffer
+	var features []matching.Features
+	for _, record := range records {
+		f := matching.Extract(matching.Listing{
+			Name:           record.Name,
+			Brand:          record.Brand,
+			InternalMemory: record.InternalMemory,
+			RAM:            record.RAM,
+			SKU:            record.SKU,
+			EAN:            record.EAN,
+		})
+		if !matching.MatchesQuery(queryFeatures, f) || !scrappers.MatchSpecFilters(filters, recordQuantities(record)) {
+			continue
+		}
+
+		latest, err := store.Latest(record.ID)
+		if err != nil {
+			return nil, err
+		}
+		if latest == nil || latest.Price == nil {
+			continue
+		}
+		offers = append(offers, CompareOffer{
+			ProductID:    record.ID,
+			Site:         record.Site,
+			Name:         record.Name,
+			URL:          record.URL,
+			Price:        latest.Price,
+			Availability: latest.Availability,
+			ObservedAt:   latest.Time,
+		})
+		features = append(features, f)
+	}
+
+	products := []ComparedProduct{}
+	for _, group := range matching.Cluster(features, matching.DefaultThreshold) {
+		first := features[group.Members[0]]
+		product := ComparedProduct{
+			Name:       offers[group.Members[0]].Name,
+			Brand:      first.Brand,
+			StorageGB:  first.StorageGB,
+			RAMGB:      first.RAMGB,
+			Confidence: group.Confidence,
+		}
+		for _, i := range group.Members {
+			offer := offers[i]
+			offer.Confidence = 1
+			if i != group.Members[0] {
+				offer.Confidence = matching.Score(first, features[i])
+			}
+			product.Offers = append(product.Offers, offer)
+		}
+		sort.SliceStable(product.Offers, func(a, b int) bool {
+			return cheaper(product.Offers[a].Price, product.Offers[b].Price)
+		})
+		product.BestPrice = product.Offers[0].Price
+		products = append(products, product)
+	}
+
+	sort.SliceStable(products, func(a, b int) bool {
+		return cheaper(products[a].BestPrice, products[b].BestPrice)
+	})
+	return products, nil
+}
+
+// compareCurrency is the currency stores price in, ranked before any other
+const compareCurrency = "AZN"
+
+// cheaper orders prices by currency, manat first, and then by amount, so
+// prices in different currencies are never ranked against each other
+func cheaper(a, b *scrappers.Money) bool {
+	if a.Currency != b.Currency {
+		if a.Currency == compareCurrency || b.Currency == compareCurrency {
+			return a.Currency == compareCurrency
+		}
+		return a.Currency < b.Currency
+	}
+	return a.Minor < b.Minor
+}
+
+// recordQuantities returns the measured specifications of a tracked
+// product: its memory attributes, and otherwise what its name states
+func recordQuantities(record history.ProductRecord) map[string]scrappers.Quantity {
+	quantities := scrappers.NameQuantities(record.Name)
+	for label, value := range map[string]string{"internal_memory": record.InternalMemory, "ram": record.RAM} {
+		if key, spec := scrappers.NewSpecValue(label, value); spec.Quantity != nil {
+			if quantities == nil {
+				quantities = make(map[string]scrappers.Quantity)
+			}
+			quantities[key] = *spec.Quantity
+		}
+	}
+	return quantities
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"web-scrappers/history"
+	"web-scrappers/scrappers"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+func TestCompareProducts(t *testing.T) {
+	db, err := bolt.Open(filepath.Join(t.TempDir(), "compare.db"), 0600, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	store, err := history.NewStore(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	seen := []struct {
+		site, uri, name, brand string
+		price                  int64
+		currency               string
+	}{
+		{"kontakt", "https://kontakt.az/iphone-13-128", "iPhone 13 128 GB Midnight", "Apple", 137999, "AZN"},
+		{"irshad", "https://irshad.az/iphone-13-128", "Apple iPhone 13 128GB Starlight", "", 132900, "AZN"},
+		{"optimal", "https://optimal.az/iphone-13-256", "Apple iPhone 13 256GB", "", 159900, "AZN"},
+		{"irshad", "https://irshad.az/galaxy-s23", "Samsung Galaxy S23 8/256GB", "", 149900, "AZN"},
+		// Cheaper as a number, but not in manat
+		{"bakuelectronics", "https://bakuelectronics.az/iphone-13-128", "Apple iPhone 13 128GB Blue", "", 79900, "USD"},
+	}
+	for _, p := range seen {
+		product := &scrappers.Product{
+			Name:              p.name,
+			Brand:             p.brand,
+			CurrentPriceValue: &scrappers.Money{Minor: p.price, Currency: p.currency},
+		}
+		if _, err := store.RecordAt(p.site, p.uri, product, time.Now()); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	products, err := compareProducts(store, "iphone 13", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(products) != 2 {
+		t.Fatalf("expected 2 compared products, got %+v", products)
+	}
+
+	cheapest := products[0]
+	if cheapest.StorageGB != 128 || len(cheapest.Offers) != 3 {
+		t.Fatalf("expected the 128 GB model with 3 offers first, got %+v", cheapest)
+	}
+	if cheapest.Offers[0].Site != "irshad" || cheapest.BestPrice.Minor != 132900 {
+		t.Errorf("expected Irshad's offer to be the cheapest, got %+v", cheapest.Offers)
+	}
+	if cheapest.Offers[2].Price.Currency != "USD" {
+		t.Errorf("expected the USD offer after the manat ones, got %+v", cheapest.Offers)
+	}
+	if products[1].StorageGB != 256 {
+		t.Errorf("expected the 256 GB model second, got %+v", products[1])
+	}
+
+	filters, err := scrappers.ParseSpecFilters([]string{"internal_memory>=256GB"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	large, err := compareProducts(store, "iphone 13", filters)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(large) != 1 || large[0].StorageGB != 256 {
+		t.Errorf("expected only the 256 GB model, got %+v", large)
+	}
+}
@@ -0,0 +1,221 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"time"
+
+	"web-scrappers/jobs"
+	"web-scrappers/scrappers"
+)
+
+// defaultCrawlMaxPages caps the listing pages followed by one crawl
+const defaultCrawlMaxPages = 20
+
+// CrawlRequest is the body of POST /api/v1/crawl
+type CrawlRequest struct {
+	// URL is the first page of the category; Site may be empty to detect it
+	URL  string `json:"url"`
+	Site string `json:"site,omitempty"`
+
+	// MaxPages and MaxItems limit the crawl depth and the products collected
+	MaxPages int `json:"max_pages,omitempty"`
+	MaxItems int `json:"max_items,omitempty"`
+
+	// Specs keeps only the products whose name satisfies these filters,
+	// such as "ram>=8GB"
+	Specs []string `json:"specs,omitempty"`
+
+	// Scrape queues a job that scrapes every product found
+	Scrape bool `json:"scrape,omitempty"`
+
+	// Timeout bounds each product scrape of the job, as a Go duration
+	Timeout string `json:"timeout,omitempty"`
+}
+
+// CrawlResponse is returned by POST /api/v1/crawl
+type CrawlResponse struct {
+	Site string `json:"site"`
+	*scrappers.CrawlResult
+	Count int       `json:"count"`
+	Job   *jobs.Job `json:"job,omitempty"`
+}
+
+func handleCrawl(w http.ResponseWriter, r *http.Request) {
+	var req CrawlRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, ErrorResponse{
+			Error:   "invalid_body",
+			Message: fmt.Sprintf("Request body must be JSON: %v", err),
+		})
+		return
+	}
+	if req.URL == "" {
+		writeError(w, http.StatusBadRequest, ErrorResponse{
+			Error:   "missing_parameters",
+			Message: "The 'url' field is required",
+		})
+		return
+	}
+	if req.Timeout != "" {
+		if timeout, err := time.ParseDuration(req.Timeout); err != nil || timeout <= 0 {
+			writeError(w, http.StatusBadRequest, ErrorResponse{
+				Error:   "invalid_parameters",
+				Message: fmt.Sprintf("Invalid 'timeout' value '%s', expected a duration such as 30s", req.Timeout),
+			})
+			return
+		}
+	}
+
+	filters, err := scrappers.ParseSpecFilters(req.Specs)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, ErrorResponse{
+			Error:   "invalid_parameters",
+			Message: fmt.Sprintf("Invalid 'specs' value: %v", err),
+		})
+		return
+	}
+
+	site, ls, errorResp := resolveListingScraper(req.Site, req.URL)
+	if errorResp != nil {
+		writeError(w, http.StatusBadRequest, *errorResp)
+		return
+	}
+
+	opts := crawlOptions(req.MaxPages, req.MaxItems)
+	if req.Scrape {
+		// The products become one job, so it must fit in one
+		if maxItems := envInt("BATCH_MAX_ITEMS", defaultBatchMaxItems); opts.MaxItems == 0 || opts.MaxItems > maxItems {
+			opts.MaxItems = maxItems
+		}
+	}
+
+	result := scrappers.CrawlCategory(r.Context(), ls, req.URL, opts)
+	if r.Context().Err() != nil {
+		log.Printf("crawl of %s abandoned: %v", req.URL, r.Context().Err())
+		return
+	}
+	result.Items = filterListings(result.Items, filters)
+	resp := CrawlResponse{Site: site, CrawlResult: result, Count: len(result.Items)}
+
+	if req.Scrape && len(result.Items) > 0 {
+		items := make([]jobs.Item, len(result.Items))
+		for i, item := range result.Items {
+			items[i] = jobs.Item{Site: site, URI: item.URL}
+		}
+		job, err := jobManager.Submit(items, req.Timeout)
+		if err != nil {
+			writeError(w, http.StatusServiceUnavailable, ErrorResponse{
+				Error:   "job_rejected",
+				Message: fmt.Sprintf("Failed to queue job: %v", err),
+			})
+			return
+		}
+		resp.Job = job
+		w.Header().Set("Location", "/api/v1/jobs/"+job.ID)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// resolveListingScraper finds the scraper for a category URL and checks
+// that it can read listings
+func resolveListingScraper(site, uri string) (string, scrappers.ListingScraper, *ErrorResponse) {
+	siteID, scraper, err := resolveScraper(site, uri)
+	if err != nil {
+		errorResp := resolveErrorResponse(site, uri, err)
+		return "", nil, &errorResp
+	}
+	ls, ok := scraper.(scrappers.ListingScraper)
+	if !ok {
+		return "", nil, &ErrorResponse{
+			Error:   "unsupported_site",
+			Message: fmt.Sprintf("Site '%s' does not support category crawling", siteID),
+		}
+	}
+	return siteID, ls, nil
+}
+
+// crawlOptions applies the server's page limit to the requested limits;
+// one page is crawled unless more are asked for
+func crawlOptions(maxPages, maxItems int) scrappers.CrawlOptions {
+	if limit := envInt("CRAWL_MAX_PAGES", defaultCrawlMaxPages); maxPages > limit {
+		maxPages = limit
+	}
+	if maxPages <= 0 {
+		maxPages = 1
+	}
+	if maxItems < 0 {
+		maxItems = 0
+	}
+	return scrappers.CrawlOptions{MaxPages: maxPages, MaxItems: maxItems}
+}
+
+// runCrawlCommand implements "crawl": it crawls one category and prints
+// the products found, or with -scrape the scraped products, as JSON
+func runCrawlCommand(args []string) int {
+	fs := flag.NewFlagSet("crawl", flag.ContinueOnError)
+	site := fs.String("site", "", "site identifier; detected from the URL when empty")
+	maxPages := fs.Int("max-pages", 1, "listing pages to follow")
+	maxItems := fs.Int("max-items", 0, "stop after this many products; 0 means no limit")
+	scrape := fs.Bool("scrape", false, "scrape every product found")
+	timeout := fs.Duration("timeout", 0, "timeout of each product scrape")
+	var specs []string
+	fs.Func("spec", "keep products whose name satisfies a filter such as ram>=8GB; repeatable", func(s string) error {
+		specs = append(specs, s)
+		return nil
+	})
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: web-scrappers crawl [flags] <category-url>")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return 2
+	}
+	categoryURL := fs.Arg(0)
+	filters, err := scrappers.ParseSpecFilters(specs)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+
+	siteID, ls, errorResp := resolveListingScraper(*site, categoryURL)
+	if errorResp != nil {
+		fmt.Fprintln(os.Stderr, errorResp.Message)
+		return 1
+	}
+	defer scrappers.SharedBrowserPool().Close()
+
+	ctx := context.Background()
+	result := scrappers.CrawlCategory(ctx, ls, categoryURL, crawlOptions(*maxPages, *maxItems))
+	if result.Error != "" {
+		fmt.Fprintf(os.Stderr, "crawl stopped early: %s\n", result.Error)
+	}
+	result.Items = filterListings(result.Items, filters)
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	if !*scrape {
+		enc.Encode(CrawlResponse{Site: siteID, CrawlResult: result, Count: len(result.Items)})
+		return 0
+	}
+
+	items := make([]ScrapRequest, len(result.Items))
+	for i, item := range result.Items {
+		items[i] = ScrapRequest{Site: siteID, URI: item.URL}
+	}
+	perSite := envInt("BATCH_SITE_CONCURRENCY", defaultBatchSiteConcurrency)
+	results := runBatch(ctx, items, perSite, scrappers.ScrapeOptions{Timeout: *timeout})
+	enc.Encode(newBatchResponse(results))
+	return 0
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"web-scrappers/discovery"
+	"web-scrappers/jobs"
+	"web-scrappers/scrappers"
+)
+
+// defaultDiscoverMaxURLs caps the pages returned, and queued, by one discovery
+const defaultDiscoverMaxURLs = 1000
+
+// sitemapTracker remembers discovered pages and their last scrape; set up in main
+var sitemapTracker *discovery.Tracker
+
+// DiscoverRequest is the body of POST /api/v1/discover
+type DiscoverRequest struct {
+	Site string `json:"site"`
+
+	// MaxURLs limits the pages returned and queued
+	MaxURLs int `json:"max_urls,omitempty"`
+
+	// All returns every product page instead of only new and changed ones
+	All bool `json:"all,omitempty"`
+
+	// Scrape queues jobs that scrape the pages returned
+	Scrape bool `json:"scrape,omitempty"`
+
+	// Timeout bounds each product scrape of the jobs, as a Go duration
+	Timeout string `json:"timeout,omitempty"`
+}
+
+// DiscoverResponse is returned by POST /api/v1/discover
+type DiscoverResponse struct {
+	Site     string                 `json:"site"`
+	Sitemaps int                    `json:"sitemaps"`
+	Found    int                    `json:"found"`
+	Skipped  int                    `json:"skipped"`
+	URLs     []scrappers.SitemapURL `json:"urls"`
+	Count    int                    `json:"count"`
+	Errors   []string               `json:"errors,omitempty"`
+	Jobs     []*jobs.Job            `json:"jobs,omitempty"`
+}
+
+// trackScraped notes a successful scrape so discovery skips the page until
+// its sitemap lastmod changes
+func trackScraped(site, uri string) {
+	if err := sitemapTracker.MarkScraped(site, uri, time.Now().UTC()); err != nil {
+		log.Printf("Failed to record scrape of %s for discovery: %v", uri, err)
+	}
+}
+
+func handleDiscover(w http.ResponseWriter, r *http.Request) {
+	var req DiscoverRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, ErrorResponse{
+			Error:   "invalid_body",
+			Message: fmt.Sprintf("Request body must be JSON: %v", err),
+		})
+		return
+	}
+	if req.Site == "" {
+		writeError(w, http.StatusBadRequest, ErrorResponse{
+			Error:   "missing_parameters",
+			Message: "The 'site' field is required",
+		})
+		return
+	}
+	if _, err := scrappers.GetScraper(req.Site); err != nil {
+		writeError(w, http.StatusBadRequest, ErrorResponse{
+			Error:   "unsupported_site",
+			Message: fmt.Sprintf("Site '%s' is not supported. Use /api/v1/sites to see available sites", req.Site),
+		})
+		return
+	}
+	if req.Timeout != "" {
+		if timeout, err := time.ParseDuration(req.Timeout); err != nil || timeout <= 0 {
+			writeError(w, http.StatusBadRequest, ErrorResponse{
+				Error:   "invalid_parameters",
+				Message: fmt.Sprintf("Invalid 'timeout' value '%s', expected a duration such as 30s", req.Timeout),
+			})
+			return
+		}
+	}
+
+	maxURLs := envInt("DISCOVER_MAX_URLS", defaultDiscoverMaxURLs)
+	if req.MaxURLs > 0 && req.MaxURLs < maxURLs {
+		maxURLs = req.MaxURLs
+	}
+
+	// Every sitemap is read so that changed pages are found wherever they are
+	result, err := scrappers.DiscoverSitemap(r.Context(), req.Site, scrappers.SitemapOptions{})
+	if err != nil {
+		if r.Context().Err() != nil {
+			log.Printf("discovery of %s abandoned: %v", req.Site, r.Context().Err())
+			return
+		}
+		writeError(w, http.StatusBadGateway, ErrorResponse{
+			Error:   "discovery_failed",
+			Message: fmt.Sprintf("Failed to read sitemaps of %s: %v", req.Site, err),
+		})
+		return
+	}
+
+	pages := result.URLs
+	if !req.All {
+		pages, err = sitemapTracker.Changed(req.Site, result.URLs)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, ErrorResponse{
+				Error:   "discovery_store_failed",
+				Message: fmt.Sprintf("Failed to compare with earlier discoveries: %v", err),
+			})
+			return
+		}
+	}
+	if len(pages) > maxURLs {
+		pages = pages[:maxURLs]
+	}
+
+	resp := DiscoverResponse{
+		Site:     req.Site,
+		Sitemaps: result.Sitemaps,
+		Found:    len(result.URLs),
+		Skipped:  result.Skipped,
+		URLs:     pages,
+		Count:    len(pages),
+		Errors:   result.Errors,
+	}
+
+	if req.Scrape {
+		// Each job must respect the batch size limit
+		chunk := envInt("BATCH_MAX_ITEMS", defaultBatchMaxItems)
+		for start := 0; start < len(pages); start += chunk {
+			end := min(start+chunk, len(pages))
+			items := make([]jobs.Item, 0, end-start)
+			uris := make([]string, 0, end-start)
+			for _, page := range pages[start:end] {
+				items = append(items, jobs.Item{Site: req.Site, URI: page.Loc})
+				uris = append(uris, page.Loc)
+			}
+			job, err := jobManager.Submit(items, req.Timeout)
+			if err != nil {
+				writeError(w, http.StatusServiceUnavailable, ErrorResponse{
+					Error:   "job_rejected",
+					Message: fmt.Sprintf("Failed to queue job: %v", err),
+				})
+				return
+			}
+			resp.Jobs = append(resp.Jobs, job)
+
+			// Later discoveries leave the pages to this job
+			if err := sitemapTracker.MarkQueued(req.Site, uris, time.Now().UTC()); err != nil {
+				log.Printf("Failed to record queued pages of %s for discovery: %v", req.Site, err)
+			}
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
@@ -0,0 +1,150 @@
+// Package discovery remembers the pages found in site sitemaps and when
+// they were last scraped, so that only new and changed pages are scraped
+// again
+package discovery
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"web-scrappers/cache"
+	"web-scrappers/scrappers"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// pagesBucket maps cache keys of discovered pages to their PageState
+var pagesBucket = []byte("sitemap_pages")
+
+// PageState is what is known about a discovered page
+type PageState struct {
+	// LastMod is the page's latest <lastmod>; zero when the sitemap gave none
+	LastMod time.Time `json:"lastmod,omitempty"`
+
+	// ScrapedAt is the last successful scrape; zero when never scraped
+	ScrapedAt time.Time `json:"scraped_at,omitempty"`
+
+	// QueuedAt is when a job to scrape the page was last submitted
+	QueuedAt time.Time `json:"queued_at,omitempty"`
+}
+
+// queuedTTL is how long a queued page is left to its job. A job that
+// fails, or is lost, gives the page back to discovery after it.
+const queuedTTL = time.Hour
+
+// pending reports whether a job queued for the page has yet to scrape it
+// as it was at lastMod
+func (s PageState) pending(lastMod, now time.Time) bool {
+	return s.QueuedAt.After(s.ScrapedAt) && !lastMod.After(s.QueuedAt) && now.Sub(s.QueuedAt) < queuedTTL
+}
+
+// Tracker persists PageState in bbolt
+type Tracker struct {
+	db  *bolt.DB
+	now func() time.Time
+}
+
+// NewTracker creates the discovery bucket in db if needed
+func NewTracker(db *bolt.DB) (*Tracker, error) {
+	err := db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(pagesBucket)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open discovery store: %w", err)
+	}
+	return &Tracker{db: db, now: time.Now}, nil
+}
+
+// Changed records the lastmod of pages found on site and returns those that
+// need scraping: pages never scraped, and pages modified after their last
+// scrape. Pages without a lastmod are only returned until scraped once.
+// Pages already queued for a scrape are skipped until their job is done.
+func (t *Tracker) Changed(site string, pages []scrappers.SitemapURL) ([]scrappers.SitemapURL, error) {
+	changed := []scrappers.SitemapURL{}
+	now := t.now()
+	err := t.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(pagesBucket)
+		for _, page := range pages {
+			key := []byte(cache.Key(site, page.Loc))
+
+			var state PageState
+			if data := bucket.Get(key); data != nil {
+				if err := json.Unmarshal(data, &state); err != nil {
+					return err
+				}
+			}
+			if (state.ScrapedAt.IsZero() || page.LastMod.After(state.ScrapedAt)) && !state.pending(page.LastMod, now) {
+				changed = append(changed, page)
+			}
+			if page.LastMod.IsZero() || page.LastMod.Equal(state.LastMod) {
+				continue
+			}
+
+			state.LastMod = page.LastMod
+			data, err := json.Marshal(state)
+			if err != nil {
+				return err
+			}
+			if err := bucket.Put(key, data); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return changed, nil
+}
+
+// MarkScraped records a successful scrape of uri on site at the given time
+func (t *Tracker) MarkScraped(site, uri string, at time.Time) error {
+	key := []byte(cache.Key(site, uri))
+	return t.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(pagesBucket)
+
+		var state PageState
+		if data := bucket.Get(key); data != nil {
+			if err := json.Unmarshal(data, &state); err != nil {
+				return err
+			}
+		}
+		state.ScrapedAt = at
+
+		data, err := json.Marshal(state)
+		if err != nil {
+			return err
+		}
+		return bucket.Put(key, data)
+	})
+}
+
+// MarkQueued records that a job to scrape uris on site was submitted at the
+// given time
+func (t *Tracker) MarkQueued(site string, uris []string, at time.Time) error {
+	return t.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(pagesBucket)
+		for _, uri := range uris {
+			key := []byte(cache.Key(site, uri))
+
+			var state PageState
+			if data := bucket.Get(key); data != nil {
+				if err := json.Unmarshal(data, &state); err != nil {
+					return err
+				}
+			}
+			state.QueuedAt = at
+
+			data, err := json.Marshal(state)
+			if err != nil {
+				return err
+			}
+			if err := bucket.Put(key, data); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
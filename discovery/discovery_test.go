@@ -0,0 +1,67 @@
+package discovery
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"web-scrappers/scrappers"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+func TestChanged(t *testing.T) {
+	db, err := bolt.Open(filepath.Join(t.TempDir(), "discovery.db"), 0600, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	tracker, err := NewTracker(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	day := time.Date(2025, 10, 1, 0, 0, 0, 0, time.UTC)
+	pages := []scrappers.SitemapURL{
+		{Loc: "https://kontakt.az/a-b-c", LastMod: day},
+		{Loc: "https://kontakt.az/d-e-f"},
+	}
+
+	changed, err := tracker.Changed("kontakt", pages)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changed) != 2 {
+		t.Fatalf("expected both unscraped pages, got %v", changed)
+	}
+
+	// Queued pages are left to their job, unless it never got to them
+	tracker.now = func() time.Time { return day.Add(30 * time.Minute) }
+	if err := tracker.MarkQueued("kontakt", []string{pages[0].Loc, pages[1].Loc}, day.Add(10*time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	if changed, _ = tracker.Changed("kontakt", pages); len(changed) != 0 {
+		t.Fatalf("expected queued pages to be skipped, got %v", changed)
+	}
+	tracker.now = func() time.Time { return day.Add(2 * time.Hour) }
+	if changed, _ = tracker.Changed("kontakt", pages); len(changed) != 2 {
+		t.Fatalf("expected pages of a stale job back, got %v", changed)
+	}
+
+	// Scraped after their last change: nothing to do
+	for _, page := range pages {
+		if err := tracker.MarkScraped("kontakt", page.Loc, day.Add(time.Hour)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if changed, _ = tracker.Changed("kontakt", pages); len(changed) != 0 {
+		t.Fatalf("expected no changed pages, got %v", changed)
+	}
+
+	// A newer lastmod brings the page back; the URL is matched normalized
+	pages[0] = scrappers.SitemapURL{Loc: "https://www.kontakt.az/a-b-c/", LastMod: day.Add(48 * time.Hour)}
+	changed, _ = tracker.Changed("kontakt", pages)
+	if len(changed) != 1 || changed[0].Loc != pages[0].Loc {
+		t.Fatalf("expected the modified page, got %v", changed)
+	}
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"web-scrappers/history"
+	"web-scrappers/scrappers"
+
+	"github.com/gorilla/mux"
+)
+
+// priceHistory records every successful scrape; set up in main
+var priceHistory *history.Store
+
+// HistoryResponse is returned by GET /api/v1/products/{id}/history
+type HistoryResponse struct {
+	Product      *history.ProductRecord `json:"product"`
+	Observations []history.Observation  `json:"observations"`
+	Count        int                    `json:"count"`
+}
+
+// recordHistory is the coalescer hook that stores each scraped product and
+// checks how it changed against the alert rules
+func recordHistory(site, uri string, product *scrappers.Product) {
+	at := time.Now().UTC()
+	record, previous, err := priceHistory.RecordChange(site, uri, product, at)
+	if err != nil {
+		log.Printf("Failed to record price history of %s: %v", uri, err)
+		return
+	}
+	alertManager.Check(site, uri, record, previous, history.NewObservation(product, at))
+}
+
+func handleProductHistory(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	var bounds [2]time.Time
+	for i, name := range []string{"from", "to"} {
+		v := r.URL.Query().Get(name)
+		if v == "" {
+			continue
+		}
+		t, err := parseTimeParam(v, name == "to")
+		if err != nil {
+			writeError(w, http.StatusBadRequest, ErrorResponse{
+				Error:   "invalid_parameters",
+				Message: fmt.Sprintf("Invalid '%s' value '%s', expected RFC 3339 time or YYYY-MM-DD", name, v),
+			})
+			return
+		}
+		bounds[i] = t
+	}
+
+	product, err := priceHistory.Product(id)
+	if err != nil {
+		writeHistoryError(w, err)
+		return
+	}
+	observations, err := priceHistory.History(id, bounds[0], bounds[1])
+	if err != nil {
+		writeHistoryError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(HistoryResponse{
+		Product:      product,
+		Observations: observations,
+		Count:        len(observations),
+	})
+}
+
+// parseTimeParam accepts an RFC 3339 timestamp or a YYYY-MM-DD date in UTC.
+// With endOfDay a bare date means the last instant of that day.
+func parseTimeParam(v string, endOfDay bool) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse(time.DateOnly, v)
+	if err == nil && endOfDay {
+		t = t.Add(24*time.Hour - time.Nanosecond)
+	}
+	return t, err
+}
+
+// writeHistoryError reports a failed history lookup
+func writeHistoryError(w http.ResponseWriter, err error) {
+	if errors.Is(err, history.ErrNotFound) {
+		writeError(w, http.StatusNotFound, ErrorResponse{
+			Error:   "product_not_found",
+			Message: "No price history exists for this product ID",
+		})
+		return
+	}
+	writeError(w, http.StatusInternalServerError, ErrorResponse{
+		Error:   "history_store_failed",
+		Message: fmt.Sprintf("Failed to read price history: %v", err),
+	})
+}
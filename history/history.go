@@ -0,0 +1,273 @@
+// Package history keeps a time series of price and availability
+// observations for every product that has been scraped successfully
+package history
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"web-scrappers/cache"
+	"web-scrappers/scrappers"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+var (
+	// productsBucket maps product IDs to their ProductRecord
+	productsBucket = []byte("products")
+
+	// observationsBucket holds one nested bucket per product ID, keyed by
+	// big-endian Unix nanoseconds so cursors walk observations in time order
+	observationsBucket = []byte("observations")
+)
+
+// ErrNotFound is returned for product IDs that were never recorded
+var ErrNotFound = errors.New("product not found")
+
+// skuPattern matches SKUs that are safe to use in a product ID as they are
+var skuPattern = regexp.MustCompile(`^[A-Za-z0-9._-]{1,64}$`)
+
+// ProductRecord identifies a tracked product
+type ProductRecord struct {
+	ID        string    `json:"id"`
+	Site      string    `json:"site"`
+	SKU       string    `json:"sku,omitempty"`
+	EAN       string    `json:"ean,omitempty"`
+	URL       string    `json:"url"`
+	Name      string    `json:"name"`
+	FirstSeen time.Time `json:"first_seen"`
+	LastSeen  time.Time `json:"last_seen"`
+
+	// Attributes used to match the product across sites
+	Brand          string `json:"brand,omitempty"`
+	InternalMemory string `json:"internal_memory,omitempty"`
+	RAM            string `json:"ram,omitempty"`
+}
+
+// Observation is the state of a product at one point in time
+type Observation struct {
+	Time            time.Time        `json:"time"`
+	Price           *scrappers.Money `json:"price,omitempty"`
+	OriginalPrice   *scrappers.Money `json:"original_price,omitempty"`
+	DiscountPercent float64          `json:"discount_percent,omitempty"`
+	Availability    string           `json:"availability"`
+}
+
+// NewObservation returns the state of product at the given time
+func NewObservation(product *scrappers.Product, at time.Time) Observation {
+	return Observation{
+		Time:            at,
+		Price:           product.CurrentPriceValue,
+		OriginalPrice:   product.OriginalPriceValue,
+		DiscountPercent: product.DiscountPercent,
+		Availability:    product.Availability,
+	}
+}
+
+// Store persists products and their observations in bbolt
+type Store struct {
+	db *bolt.DB
+}
+
+// NewStore creates the history buckets in db if needed
+func NewStore(db *bolt.DB) (*Store, error) {
+	err := db.Update(func(tx *bolt.Tx) error {
+		if _, err := tx.CreateBucketIfNotExists(productsBucket); err != nil {
+			return err
+		}
+		_, err := tx.CreateBucketIfNotExists(observationsBucket)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open price history: %w", err)
+	}
+	return &Store{db: db}, nil
+}
+
+// ProductID returns the stable identifier of a product: the site and SKU
+// when the page exposes one, or else the site and a hash of the canonical URL
+func ProductID(site, uri string, product *scrappers.Product) string {
+	if sku := strings.TrimSpace(product.SKU); skuPattern.MatchString(sku) {
+		return site + ":" + strings.ToLower(sku)
+	}
+	sum := sha1.Sum([]byte(cache.NormalizeURL(uri)))
+	return site + ":u" + hex.EncodeToString(sum[:8])
+}
+
+// Record stores an observation of product, scraped from uri on site, at the
+// current time
+func (s *Store) Record(site, uri string, product *scrappers.Product) (*ProductRecord, error) {
+	return s.RecordAt(site, uri, product, time.Now().UTC())
+}
+
+// RecordAt is like Record with an explicit observation time
+func (s *Store) RecordAt(site, uri string, product *scrappers.Product, at time.Time) (*ProductRecord, error) {
+	record, _, err := s.RecordChange(site, uri, product, at)
+	return record, err
+}
+
+// RecordChange is like RecordAt and also returns the observation that was
+// the latest before this one, or nil for a new product. It is read in the
+// same transaction, so concurrent scrapes of a product each see a
+// different previous observation.
+func (s *Store) RecordChange(site, uri string, product *scrappers.Product, at time.Time) (*ProductRecord, *Observation, error) {
+	id := ProductID(site, uri, product)
+	obs := NewObservation(product, at)
+
+	var record ProductRecord
+	var previous *Observation
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		products := tx.Bucket(productsBucket)
+		if data := products.Get([]byte(id)); data != nil {
+			if err := json.Unmarshal(data, &record); err != nil {
+				return err
+			}
+		} else {
+			record = ProductRecord{ID: id, Site: site, SKU: product.SKU, FirstSeen: at}
+		}
+		record.URL = cache.NormalizeURL(uri)
+		// Keep what earlier scrapes found if this one missed a field
+		setIfPresent(&record.Name, product.Name)
+		setIfPresent(&record.EAN, product.EAN)
+		setIfPresent(&record.Brand, product.Brand)
+		setIfPresent(&record.InternalMemory, product.InternalMemory)
+		setIfPresent(&record.RAM, product.RAM)
+		if at.After(record.LastSeen) {
+			record.LastSeen = at
+		}
+
+		data, err := json.Marshal(record)
+		if err != nil {
+			return err
+		}
+		if err := products.Put([]byte(id), data); err != nil {
+			return err
+		}
+
+		series, err := tx.Bucket(observationsBucket).CreateBucketIfNotExists([]byte(id))
+		if err != nil {
+			return err
+		}
+		if _, v := series.Cursor().Last(); v != nil {
+			previous = &Observation{}
+			if err := json.Unmarshal(v, previous); err != nil {
+				return err
+			}
+		}
+		data, err = json.Marshal(obs)
+		if err != nil {
+			return err
+		}
+		return series.Put(timeKey(at), data)
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to record price history: %w", err)
+	}
+	return &record, previous, nil
+}
+
+// Product returns the record of a tracked product
+func (s *Store) Product(id string) (*ProductRecord, error) {
+	var record *ProductRecord
+	err := s.db.View(func(tx *bolt.Tx) error {
+		data := tx.Bucket(productsBucket).Get([]byte(id))
+		if data == nil {
+			return ErrNotFound
+		}
+		record = &ProductRecord{}
+		return json.Unmarshal(data, record)
+	})
+	return record, err
+}
+
+// Products returns every tracked product
+func (s *Store) Products() ([]ProductRecord, error) {
+	var records []ProductRecord
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(productsBucket).ForEach(func(k, v []byte) error {
+			var record ProductRecord
+			if err := json.Unmarshal(v, &record); err != nil {
+				return fmt.Errorf("failed to decode product %s: %w", k, err)
+			}
+			records = append(records, record)
+			return nil
+		})
+	})
+	return records, err
+}
+
+// Latest returns the most recent observation of a product, or nil if it has
+// none
+func (s *Store) Latest(id string) (*Observation, error) {
+	var latest *Observation
+	err := s.db.View(func(tx *bolt.Tx) error {
+		series := tx.Bucket(observationsBucket).Bucket([]byte(id))
+		if series == nil {
+			return nil
+		}
+		_, v := series.Cursor().Last()
+		if v == nil {
+			return nil
+		}
+		latest = &Observation{}
+		return json.Unmarshal(v, latest)
+	})
+	return latest, err
+}
+
+// History returns the observations of a product in [from, to], oldest
+// first. Zero times leave that end of the range open.
+func (s *Store) History(id string, from, to time.Time) ([]Observation, error) {
+	observations := []Observation{}
+	err := s.db.View(func(tx *bolt.Tx) error {
+		if tx.Bucket(productsBucket).Get([]byte(id)) == nil {
+			return ErrNotFound
+		}
+		series := tx.Bucket(observationsBucket).Bucket([]byte(id))
+		if series == nil {
+			return nil
+		}
+
+		c := series.Cursor()
+		var k, v []byte
+		if from.IsZero() {
+			k, v = c.First()
+		} else {
+			k, v = c.Seek(timeKey(from))
+		}
+		for ; k != nil; k, v = c.Next() {
+			if !to.IsZero() && bytes.Compare(k, timeKey(to)) > 0 {
+				break
+			}
+			var obs Observation
+			if err := json.Unmarshal(v, &obs); err != nil {
+				return err
+			}
+			observations = append(observations, obs)
+		}
+		return nil
+	})
+	return observations, err
+}
+
+// setIfPresent overwrites *dst with value unless value is empty
+func setIfPresent(dst *string, value string) {
+	if value != "" {
+		*dst = value
+	}
+}
+
+// timeKey encodes t so that byte order matches time order
+func timeKey(t time.Time) []byte {
+	key := make([]byte, 8)
+	binary.BigEndian.PutUint64(key, uint64(t.UnixNano()))
+	return key
+}
@@ -0,0 +1,92 @@
+package history
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"web-scrappers/scrappers"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+func openTestStore(t *testing.T) *Store {
+	t.Helper()
+	db, err := bolt.Open(filepath.Join(t.TempDir(), "history.db"), 0600, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	s, err := NewStore(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestProductID(t *testing.T) {
+	withSKU := ProductID("kontakt", "https://kontakt.az/p", &scrappers.Product{SKU: "AB-123"})
+	if withSKU != "kontakt:ab-123" {
+		t.Errorf("got %q, want kontakt:ab-123", withSKU)
+	}
+
+	a := ProductID("irshad", "https://irshad.az/p?utm_source=x", &scrappers.Product{})
+	b := ProductID("irshad", "https://www.irshad.az/p/", &scrappers.Product{SKU: "has spaces"})
+	if a != b || !strings.HasPrefix(a, "irshad:u") {
+		t.Errorf("expected URL-based IDs to match, got %q and %q", a, b)
+	}
+}
+
+func TestRecordAndHistory(t *testing.T) {
+	s := openTestStore(t)
+	base := time.Date(2025, 10, 1, 12, 0, 0, 0, time.UTC)
+
+	var last int64
+	for i, price := range []int64{150000, 140000, 145000} {
+		product := &scrappers.Product{
+			Name:              "Phone",
+			SKU:               "X1",
+			Availability:      "in stock",
+			CurrentPriceValue: &scrappers.Money{Minor: price, Currency: "AZN"},
+		}
+		_, previous, err := s.RecordChange("kontakt", "https://kontakt.az/phone", product, base.Add(time.Duration(i)*24*time.Hour))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if (previous == nil) != (i == 0) || (previous != nil && previous.Price.Minor != last) {
+			t.Errorf("observation %d: previous = %+v, want price %d", i, previous, last)
+		}
+		last = price
+	}
+
+	record, err := s.Product("kontakt:x1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !record.FirstSeen.Equal(base) || !record.LastSeen.Equal(base.Add(48*time.Hour)) {
+		t.Errorf("unexpected first/last seen: %v %v", record.FirstSeen, record.LastSeen)
+	}
+
+	all, err := s.History("kontakt:x1", time.Time{}, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all) != 3 || all[0].Price.Minor != 150000 || all[2].Price.Minor != 145000 {
+		t.Fatalf("unexpected history %+v", all)
+	}
+
+	ranged, err := s.History("kontakt:x1", base.Add(time.Hour), base.Add(24*time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ranged) != 1 || ranged[0].Price.Minor != 140000 {
+		t.Errorf("unexpected ranged history %+v", ranged)
+	}
+
+	if _, err := s.History("kontakt:missing", time.Time{}, time.Time{}); !errors.Is(err, ErrNotFound) {
+		t.Errorf("got %v, want ErrNotFound", err)
+	}
+}
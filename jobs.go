@@ -0,0 +1,144 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"web-scrappers/jobs"
+	"web-scrappers/scrappers"
+
+	"github.com/gorilla/mux"
+)
+
+// defaultJobWorkers is how many jobs run at the same time
+const defaultJobWorkers = 2
+
+// jobManager runs asynchronous scrape jobs; set up in main
+var jobManager *jobs.Manager
+
+// JobRequest is the body of POST /api/v1/jobs
+type JobRequest struct {
+	Items []ScrapRequest `json:"items"`
+
+	// Timeout bounds each item, as a Go duration such as "30s"
+	Timeout string `json:"timeout,omitempty"`
+}
+
+// scrapeJobItem is the jobs.RunFunc used by jobManager
+func scrapeJobItem(ctx context.Context, item jobs.Item, opts scrappers.ScrapeOptions) jobs.ItemResult {
+	result := jobs.ItemResult{Site: item.Site, URI: item.URI}
+
+	site, scraper, err := resolveScraper(item.Site, item.URI)
+	if err != nil {
+		errorResp := resolveErrorResponse(item.Site, item.URI, err)
+		result.Error = &jobs.ItemError{Code: errorResp.Error, Message: errorResp.Message}
+		return result
+	}
+	result.Site = site
+
+	product, err := scrapeGroup.Scrape(ctx, site, scraper, item.URI, opts)
+	if err != nil {
+		_, errorResp := scrapeErrorResponse(err)
+		result.Error = &jobs.ItemError{Code: errorResp.Error, Message: errorResp.Message}
+		return result
+	}
+	result.Product = product
+	return result
+}
+
+func handleCreateJob(w http.ResponseWriter, r *http.Request) {
+	var req JobRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, ErrorResponse{
+			Error:   "invalid_body",
+			Message: fmt.Sprintf("Request body must be JSON: %v", err),
+		})
+		return
+	}
+
+	maxItems := envInt("BATCH_MAX_ITEMS", defaultBatchMaxItems)
+	if len(req.Items) == 0 || len(req.Items) > maxItems {
+		writeError(w, http.StatusBadRequest, ErrorResponse{
+			Error:   "invalid_parameters",
+			Message: fmt.Sprintf("'items' must contain between 1 and %d entries", maxItems),
+		})
+		return
+	}
+
+	if req.Timeout != "" {
+		if timeout, err := time.ParseDuration(req.Timeout); err != nil || timeout <= 0 {
+			writeError(w, http.StatusBadRequest, ErrorResponse{
+				Error:   "invalid_parameters",
+				Message: fmt.Sprintf("Invalid 'timeout' value '%s', expected a duration such as 30s", req.Timeout),
+			})
+			return
+		}
+	}
+
+	items := make([]jobs.Item, len(req.Items))
+	for i, item := range req.Items {
+		if item.URI == "" {
+			writeError(w, http.StatusBadRequest, ErrorResponse{
+				Error:   "missing_parameters",
+				Message: fmt.Sprintf("Item %d has no 'uri'", i),
+			})
+			return
+		}
+		items[i] = jobs.Item{Site: item.Site, URI: item.URI}
+	}
+
+	job, err := jobManager.Submit(items, req.Timeout)
+	if err != nil {
+		writeError(w, http.StatusServiceUnavailable, ErrorResponse{
+			Error:   "job_rejected",
+			Message: fmt.Sprintf("Failed to queue job: %v", err),
+		})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Location", "/api/v1/jobs/"+job.ID)
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(job)
+}
+
+func handleGetJob(w http.ResponseWriter, r *http.Request) {
+	job, err := jobManager.Get(mux.Vars(r)["id"])
+	if err != nil {
+		writeJobError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(job)
+}
+
+func handleCancelJob(w http.ResponseWriter, r *http.Request) {
+	job, err := jobManager.Cancel(mux.Vars(r)["id"])
+	if err != nil {
+		writeJobError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(job)
+}
+
+// writeJobError reports a failed job lookup or update
+func writeJobError(w http.ResponseWriter, err error) {
+	if errors.Is(err, jobs.ErrNotFound) {
+		writeError(w, http.StatusNotFound, ErrorResponse{
+			Error:   "job_not_found",
+			Message: "No job exists with this ID",
+		})
+		return
+	}
+	writeError(w, http.StatusInternalServerError, ErrorResponse{
+		Error:   "job_store_failed",
+		Message: fmt.Sprintf("Failed to access job: %v", err),
+	})
+}
@@ -0,0 +1,398 @@
+// Package jobs runs scrape work asynchronously on an in-process worker pool.
+// Jobs are persisted in bbolt so queued and interrupted work is resumed after
+// a restart.
+package jobs
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"web-scrappers/scrappers"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// Status is the lifecycle state of a job
+type Status string
+
+const (
+	StatusQueued    Status = "queued"
+	StatusRunning   Status = "running"
+	StatusCompleted Status = "completed"
+	StatusCancelled Status = "cancelled"
+)
+
+// jobsBucket is the bbolt bucket holding one JSON document per job
+var jobsBucket = []byte("jobs")
+
+// ErrNotFound is returned when a job ID is unknown
+var ErrNotFound = errors.New("job not found")
+
+// ErrClosed is returned when submitting to a manager that is shutting down
+var ErrClosed = errors.New("job manager closed")
+
+// Item identifies a single page to scrape; Site may be empty to detect it
+type Item struct {
+	Site string `json:"site"`
+	URI  string `json:"uri"`
+}
+
+// ItemError describes why an item failed
+type ItemError struct {
+	Code    string `json:"error"`
+	Message string `json:"message"`
+}
+
+// ItemResult is the outcome of one item; exactly one of Product and Error is set
+type ItemResult struct {
+	Index   int                `json:"index"`
+	Site    string             `json:"site"`
+	URI     string             `json:"uri"`
+	Product *scrappers.Product `json:"product,omitempty"`
+	Error   *ItemError         `json:"error,omitempty"`
+}
+
+// Progress counts processed items of a job
+type Progress struct {
+	Total     int `json:"total"`
+	Done      int `json:"done"`
+	Succeeded int `json:"succeeded"`
+	Failed    int `json:"failed"`
+}
+
+// Job is a unit of queued scrape work and its results
+type Job struct {
+	ID         string       `json:"id"`
+	Status     Status       `json:"status"`
+	Items      []Item       `json:"items"`
+	Timeout    string       `json:"timeout,omitempty"`
+	Progress   Progress     `json:"progress"`
+	Results    []ItemResult `json:"results"`
+	CreatedAt  time.Time    `json:"created_at"`
+	StartedAt  *time.Time   `json:"started_at,omitempty"`
+	FinishedAt *time.Time   `json:"finished_at,omitempty"`
+}
+
+// Finished reports whether the job will not run any more
+func (j *Job) Finished() bool {
+	return j.Status == StatusCompleted || j.Status == StatusCancelled
+}
+
+// RunFunc scrapes a single item of a job
+type RunFunc func(ctx context.Context, item Item, opts scrappers.ScrapeOptions) ItemResult
+
+// Manager owns the job queue and its workers
+type Manager struct {
+	db  *bolt.DB
+	run RunFunc
+
+	mu      sync.Mutex
+	cond    *sync.Cond
+	pending []string
+	running map[string]*runningJob
+	closed  bool
+
+	// stop aborts running jobs on shutdown without marking them cancelled
+	stop    context.Context
+	stopAll context.CancelFunc
+	wg      sync.WaitGroup
+}
+
+// NewManager opens the job store in db, requeues any job that was queued or
+// running when the process last stopped, and starts workers goroutines
+func NewManager(db *bolt.DB, workers int, run RunFunc) (*Manager, error) {
+	if workers <= 0 {
+		workers = 1
+	}
+
+	m := &Manager{
+		db:      db,
+		run:     run,
+		running: make(map[string]*runningJob),
+	}
+	m.cond = sync.NewCond(&m.mu)
+	m.stop, m.stopAll = context.WithCancel(context.Background())
+
+	var resumed []*Job
+	err := db.Update(func(tx *bolt.Tx) error {
+		b, err := tx.CreateBucketIfNotExists(jobsBucket)
+		if err != nil {
+			return err
+		}
+		return b.ForEach(func(k, v []byte) error {
+			var job Job
+			if err := json.Unmarshal(v, &job); err != nil {
+				log.Printf("jobs: skipping unreadable job %s: %v", k, err)
+				return nil
+			}
+			if !job.Finished() {
+				resumed = append(resumed, &job)
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to load jobs: %w", err)
+	}
+
+	// Resume in submission order
+	sort.Slice(resumed, func(a, b int) bool { return resumed[a].CreatedAt.Before(resumed[b].CreatedAt) })
+	for _, job := range resumed {
+		job.Status = StatusQueued
+		if err := m.save(job); err != nil {
+			return nil, err
+		}
+		m.pending = append(m.pending, job.ID)
+	}
+	if len(resumed) > 0 {
+		log.Printf("jobs: resumed %d unfinished jobs", len(resumed))
+	}
+
+	for i := 0; i < workers; i++ {
+		m.wg.Add(1)
+		go m.worker()
+	}
+	return m, nil
+}
+
+// Submit stores a new job and queues it for execution
+func (m *Manager) Submit(items []Item, timeout string) (*Job, error) {
+	id, err := newID()
+	if err != nil {
+		return nil, err
+	}
+
+	job := &Job{
+		ID:        id,
+		Status:    StatusQueued,
+		Items:     items,
+		Timeout:   timeout,
+		Progress:  Progress{Total: len(items)},
+		Results:   []ItemResult{},
+		CreatedAt: time.Now().UTC(),
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.closed {
+		return nil, ErrClosed
+	}
+	if err := m.save(job); err != nil {
+		return nil, err
+	}
+	m.pending = append(m.pending, job.ID)
+	m.cond.Signal()
+	return job, nil
+}
+
+// Get returns the current state of a job
+func (m *Manager) Get(id string) (*Job, error) {
+	return m.load(id)
+}
+
+// runningJob lets Cancel interrupt a job a worker is executing and wait
+// until the worker has recorded the outcome
+type runningJob struct {
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// Cancel stops a queued or running job and returns it as cancelled; a
+// running job is returned once its worker has stopped and saved it.
+// Cancelling a finished job deletes it from the store.
+func (m *Manager) Cancel(id string) (*Job, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	job, err := m.load(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if job.Status == StatusRunning {
+		if r, ok := m.running[id]; ok {
+			r.cancel()
+			m.mu.Unlock()
+			<-r.done
+			m.mu.Lock()
+		}
+		// The job may also have completed, or been left running by Close,
+		// before the cancellation took effect
+		return m.load(id)
+	}
+
+	switch job.Status {
+	case StatusQueued:
+		for i, pendingID := range m.pending {
+			if pendingID == id {
+				m.pending = append(m.pending[:i], m.pending[i+1:]...)
+				break
+			}
+		}
+		m.finish(job, StatusCancelled)
+		return job, m.save(job)
+	default:
+		return job, m.db.Update(func(tx *bolt.Tx) error {
+			return tx.Bucket(jobsBucket).Delete([]byte(id))
+		})
+	}
+}
+
+// Close stops accepting jobs, interrupts running ones so they are resumed on
+// the next start, and waits for the workers to exit
+func (m *Manager) Close() {
+	m.mu.Lock()
+	m.closed = true
+	m.cond.Broadcast()
+	m.mu.Unlock()
+
+	m.stopAll()
+	m.wg.Wait()
+}
+
+// worker takes jobs off the queue until the manager is closed
+func (m *Manager) worker() {
+	defer m.wg.Done()
+
+	for {
+		m.mu.Lock()
+		for len(m.pending) == 0 && !m.closed {
+			m.cond.Wait()
+		}
+		if m.closed {
+			m.mu.Unlock()
+			return
+		}
+		id := m.pending[0]
+		m.pending = m.pending[1:]
+
+		job, err := m.load(id)
+		if err != nil {
+			m.mu.Unlock()
+			log.Printf("jobs: failed to load queued job %s: %v", id, err)
+			continue
+		}
+
+		ctx, cancel := context.WithCancel(m.stop)
+		r := &runningJob{cancel: cancel, done: make(chan struct{})}
+		m.running[id] = r
+		now := time.Now().UTC()
+		job.Status = StatusRunning
+		if job.StartedAt == nil {
+			job.StartedAt = &now
+		}
+		if err := m.save(job); err != nil {
+			log.Printf("jobs: failed to save job %s: %v", id, err)
+		}
+		m.mu.Unlock()
+
+		m.execute(ctx, job)
+		cancel()
+
+		m.mu.Lock()
+		delete(m.running, id)
+		m.mu.Unlock()
+		close(r.done)
+	}
+}
+
+// execute runs the remaining items of job, saving progress after each one
+func (m *Manager) execute(ctx context.Context, job *Job) {
+	opts := scrappers.ScrapeOptions{}
+	if job.Timeout != "" {
+		if timeout, err := time.ParseDuration(job.Timeout); err == nil {
+			opts.Timeout = timeout
+		}
+	}
+
+	// Items that already have a result were done before a restart
+	for i := len(job.Results); i < len(job.Items); i++ {
+		if ctx.Err() != nil {
+			break
+		}
+
+		result := m.run(ctx, job.Items[i], opts)
+		if ctx.Err() != nil {
+			// Interrupted items are retried on resume, not recorded
+			break
+		}
+		result.Index = i
+
+		m.mu.Lock()
+		job.Results = append(job.Results, result)
+		job.Progress.Done++
+		if result.Error != nil {
+			job.Progress.Failed++
+		} else {
+			job.Progress.Succeeded++
+		}
+		if err := m.save(job); err != nil {
+			log.Printf("jobs: failed to save progress of job %s: %v", job.ID, err)
+		}
+		m.mu.Unlock()
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	switch {
+	case m.stop.Err() != nil:
+		// Shutting down: leave the job running so it is resumed on restart
+		return
+	case ctx.Err() != nil:
+		m.finish(job, StatusCancelled)
+	default:
+		m.finish(job, StatusCompleted)
+	}
+	if err := m.save(job); err != nil {
+		log.Printf("jobs: failed to save job %s: %v", job.ID, err)
+	}
+}
+
+// finish marks job as done with the given status
+func (m *Manager) finish(job *Job, status Status) {
+	now := time.Now().UTC()
+	job.Status = status
+	job.FinishedAt = &now
+}
+
+// save writes job to the store
+func (m *Manager) save(job *Job) error {
+	data, err := json.Marshal(job)
+	if err != nil {
+		return fmt.Errorf("failed to encode job: %w", err)
+	}
+	return m.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(jobsBucket).Put([]byte(job.ID), data)
+	})
+}
+
+// load reads a job from the store
+func (m *Manager) load(id string) (*Job, error) {
+	var job *Job
+	err := m.db.View(func(tx *bolt.Tx) error {
+		data := tx.Bucket(jobsBucket).Get([]byte(id))
+		if data == nil {
+			return ErrNotFound
+		}
+		job = &Job{}
+		return json.Unmarshal(data, job)
+	})
+	return job, err
+}
+
+// newID returns a random job identifier
+func newID() (string, error) {
+	b := make([]byte, 12)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate job ID: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
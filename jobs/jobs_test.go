@@ -0,0 +1,136 @@
+package jobs
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"web-scrappers/scrappers"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+func openTestDB(t *testing.T, path string) *bolt.DB {
+	t.Helper()
+	db, err := bolt.Open(path, 0600, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func okRun(ctx context.Context, item Item, opts scrappers.ScrapeOptions) ItemResult {
+	return ItemResult{Site: "test", URI: item.URI, Product: &scrappers.Product{Name: item.URI}}
+}
+
+// waitFor polls the job until it reaches status or the test times out
+func waitFor(t *testing.T, m *Manager, id string, status Status) *Job {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		job, err := m.Get(id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if job.Status == status {
+			return job
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("job %s did not reach status %s", id, status)
+	return nil
+}
+
+func TestManagerRunsJob(t *testing.T) {
+	db := openTestDB(t, filepath.Join(t.TempDir(), "jobs.db"))
+	defer db.Close()
+
+	m, err := NewManager(db, 2, okRun)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer m.Close()
+
+	job, err := m.Submit([]Item{{URI: "a"}, {URI: "b"}}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	done := waitFor(t, m, job.ID, StatusCompleted)
+	if done.Progress.Done != 2 || done.Progress.Succeeded != 2 || len(done.Results) != 2 {
+		t.Errorf("unexpected progress %+v with %d results", done.Progress, len(done.Results))
+	}
+	if done.Results[1].Index != 1 || done.Results[1].Product.Name != "b" {
+		t.Errorf("unexpected second result %+v", done.Results[1])
+	}
+}
+
+func TestManagerCancel(t *testing.T) {
+	db := openTestDB(t, filepath.Join(t.TempDir(), "jobs.db"))
+	defer db.Close()
+
+	blockingRun := func(ctx context.Context, item Item, opts scrappers.ScrapeOptions) ItemResult {
+		<-ctx.Done()
+		return ItemResult{URI: item.URI, Error: &ItemError{Code: "cancelled", Message: ctx.Err().Error()}}
+	}
+	m, err := NewManager(db, 1, blockingRun)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer m.Close()
+
+	running, _ := m.Submit([]Item{{URI: "a"}}, "")
+	queued, _ := m.Submit([]Item{{URI: "b"}}, "")
+	waitFor(t, m, running.ID, StatusRunning)
+
+	if job, err := m.Cancel(queued.ID); err != nil || job.Status != StatusCancelled {
+		t.Fatalf("cancel queued job: %v, %+v", err, job)
+	}
+	cancelled, err := m.Cancel(running.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cancelled.Status != StatusCancelled || cancelled.FinishedAt == nil {
+		t.Fatalf("cancel running job returned %+v", cancelled)
+	}
+	if stored, _ := m.Get(running.ID); stored.Status != StatusCancelled {
+		t.Errorf("stored job has status %s", stored.Status)
+	}
+	if len(cancelled.Results) != 0 {
+		t.Errorf("interrupted item should not be recorded, got %+v", cancelled.Results)
+	}
+}
+
+func TestManagerResumesAfterRestart(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "jobs.db")
+	db := openTestDB(t, path)
+
+	started := make(chan struct{}, 1)
+	blockingRun := func(ctx context.Context, item Item, opts scrappers.ScrapeOptions) ItemResult {
+		started <- struct{}{}
+		<-ctx.Done()
+		return ItemResult{}
+	}
+	m, err := NewManager(db, 1, blockingRun)
+	if err != nil {
+		t.Fatal(err)
+	}
+	job, _ := m.Submit([]Item{{URI: "a"}, {URI: "b"}}, "")
+	<-started
+	m.Close()
+	db.Close()
+
+	db = openTestDB(t, path)
+	defer db.Close()
+	m, err = NewManager(db, 1, okRun)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer m.Close()
+
+	done := waitFor(t, m, job.ID, StatusCompleted)
+	if done.Progress.Succeeded != 2 {
+		t.Errorf("expected both items to run after restart, got %+v", done.Progress)
+	}
+}
@@ -1,15 +1,25 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
 	"time"
 
+	"web-scrappers/discovery"
+	"web-scrappers/history"
+	"web-scrappers/jobs"
 	"web-scrappers/scrappers"
 
 	"github.com/gorilla/mux"
+	bolt "go.etcd.io/bbolt"
 )
 
 type ErrorResponse struct {
@@ -17,17 +27,92 @@ type ErrorResponse struct {
 	Message string `json:"message"`
 }
 
+// ScrapRequest identifies a single page to scrape; Site may be empty to
+// detect it from URI
 type ScrapRequest struct {
 	Site string `json:"site"`
 	URI  string `json:"uri"`
 }
 
 func main() {
+	loadScraperConfigs()
+
+	if len(os.Args) > 1 && os.Args[1] == "crawl" {
+		os.Exit(runCrawlCommand(os.Args[2:]))
+	}
+
+	db, err := openStore()
+	if err != nil {
+		log.Fatalf("Failed to open data store: %v", err)
+	}
+	defer db.Close()
+
+	jobManager, err = jobs.NewManager(db, envInt("JOB_WORKERS", defaultJobWorkers), scrapeJobItem)
+	if err != nil {
+		log.Fatalf("Failed to start job manager: %v", err)
+	}
+
+	resultCache, err = openCache(db)
+	if err != nil {
+		log.Fatalf("Failed to set up result cache: %v", err)
+	}
+	go resultCache.RunSweeper(context.Background(), cacheSweepInterval)
+
+	priceHistory, err = history.NewStore(db)
+	if err != nil {
+		log.Fatalf("Failed to open price history: %v", err)
+	}
+	alertManager, err = openAlerts(db)
+	if err != nil {
+		log.Fatalf("Failed to start alert manager: %v", err)
+	}
+
+	sitemapTracker, err = discovery.NewTracker(db)
+	if err != nil {
+		log.Fatalf("Failed to open discovery store: %v", err)
+	}
+	scrapeGroup.onScraped = func(site, uri string, product *scrappers.Product) {
+		recordHistory(site, uri, product)
+		trackScraped(site, uri)
+	}
+
+	watchScheduler, err = openWatches(db)
+	if err != nil {
+		log.Fatalf("Failed to start watch scheduler: %v", err)
+	}
+
+	// Start the browsers used by chromedp scrapers ahead of the first request
+	browserPool := scrappers.SharedBrowserPool()
+	go func() {
+		if err := browserPool.Warm(context.Background()); err != nil {
+			log.Printf("Browser pool warm-up failed, browsers will start on demand: %v", err)
+		}
+	}()
+
 	r := mux.NewRouter()
 
 	// API endpoints
 	api := r.PathPrefix("/api/v1").Subrouter()
 	api.HandleFunc("/scrape", handleScrape).Methods("GET")
+	api.HandleFunc("/scrape/batch", handleScrapeBatch).Methods("POST")
+	api.HandleFunc("/jobs", handleCreateJob).Methods("POST")
+	api.HandleFunc("/jobs/{id}", handleGetJob).Methods("GET")
+	api.HandleFunc("/jobs/{id}", handleCancelJob).Methods("DELETE")
+	api.HandleFunc("/products/{id}/history", handleProductHistory).Methods("GET")
+	api.HandleFunc("/compare", handleCompare).Methods("GET")
+	api.HandleFunc("/search", handleSearch).Methods("GET")
+	api.HandleFunc("/crawl", handleCrawl).Methods("POST")
+	api.HandleFunc("/discover", handleDiscover).Methods("POST")
+	api.HandleFunc("/watches", handleListWatches).Methods("GET")
+	api.HandleFunc("/watches", handleCreateWatch).Methods("POST")
+	api.HandleFunc("/watches/{id}", handleGetWatch).Methods("GET")
+	api.HandleFunc("/watches/{id}", handleDeleteWatch).Methods("DELETE")
+	api.HandleFunc("/alerts", handleListAlerts).Methods("GET")
+	api.HandleFunc("/alerts", handleCreateAlert).Methods("POST")
+	api.HandleFunc("/alerts/deliveries", handleListDeliveries).Methods("GET")
+	api.HandleFunc("/alerts/{id}", handleGetAlert).Methods("GET")
+	api.HandleFunc("/alerts/{id}", handleDeleteAlert).Methods("DELETE")
+	api.HandleFunc("/admin/reload", handleReload).Methods("POST")
 	api.HandleFunc("/health", handleHealth).Methods("GET")
 	api.HandleFunc("/sites", handleListSites).Methods("GET")
 
@@ -37,56 +122,190 @@ func main() {
 
 	fmt.Println("Web Scraper API Server starting on :8080")
 	fmt.Println("Available endpoints:")
-	fmt.Println("  GET /api/v1/scrape?site=kontakt&uri=<product-url>")
+	fmt.Println("  GET /api/v1/scrape?[site=kontakt&]uri=<product-url>[&max_age=<seconds>&force_refresh=true]")
+	fmt.Println("  POST /api/v1/scrape/batch")
+	fmt.Println("  POST /api/v1/jobs")
+	fmt.Println("  GET|DELETE /api/v1/jobs/{id}")
+	fmt.Println("  GET /api/v1/products/{id}/history[?from=&to=]")
+	fmt.Println("  GET /api/v1/compare?q=<query>")
+	fmt.Println("  GET /api/v1/search?q=<query>[&sites=kontakt,irshad&sort=price]")
+	fmt.Println("  POST /api/v1/crawl")
+	fmt.Println("  POST /api/v1/discover")
+	fmt.Println("  GET|POST /api/v1/watches")
+	fmt.Println("  GET|DELETE /api/v1/watches/{id}")
+	fmt.Println("  GET|POST /api/v1/alerts")
+	fmt.Println("  GET /api/v1/alerts/deliveries[?rule_id=&limit=]")
+	fmt.Println("  GET|DELETE /api/v1/alerts/{id}")
+	fmt.Println("  POST /api/v1/admin/reload")
 	fmt.Println("  GET /api/v1/health")
 	fmt.Println("  GET /api/v1/sites")
 
-	log.Fatal(http.ListenAndServe(":8080", r))
+	// Pick up edited scraper definitions without a restart
+	watchCtx, stopWatching := context.WithCancel(context.Background())
+	defer stopWatching()
+	watchScraperConfigs(watchCtx)
+
+	srv := &http.Server{Addr: ":8080", Handler: r}
+	go func() {
+		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Fatal(err)
+		}
+	}()
+
+	// Shut down cleanly so running jobs are left resumable
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
+	<-stop
+
+	log.Println("Shutting down...")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	srv.Shutdown(ctx)
+	watchScheduler.Close()
+	jobManager.Close()
+	alertManager.Close()
+	browserPool.Close()
+}
+
+// openStore opens the bbolt database under DATA_DIR (default "data")
+func openStore() (*bolt.DB, error) {
+	dir := os.Getenv("DATA_DIR")
+	if dir == "" {
+		dir = "data"
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	return bolt.Open(filepath.Join(dir, "ucuzu.db"), 0600, &bolt.Options{Timeout: time.Second})
 }
 
 func handleScrape(w http.ResponseWriter, r *http.Request) {
 	site := r.URL.Query().Get("site")
 	uri := r.URL.Query().Get("uri")
 
-	if site == "" || uri == "" {
-		http.Error(w, `{"error":"missing_parameters","message":"Both 'site' and 'uri' parameters are required"}`, http.StatusBadRequest)
+	if uri == "" {
+		http.Error(w, `{"error":"missing_parameters","message":"The 'uri' parameter is required"}`, http.StatusBadRequest)
 		return
 	}
 
-	scraper, err := scrappers.GetScraper(site)
+	siteID, scraper, err := resolveScraper(site, uri)
 	if err != nil {
-		errorResp := ErrorResponse{
-			Error:   "unsupported_site",
-			Message: fmt.Sprintf("Site '%s' is not supported. Use /api/v1/sites to see available sites", site),
+		writeError(w, http.StatusBadRequest, resolveErrorResponse(site, uri, err))
+		return
+	}
+
+	opts := scrappers.ScrapeOptions{}
+	if t := r.URL.Query().Get("timeout"); t != "" {
+		timeout, err := time.ParseDuration(t)
+		if err != nil || timeout <= 0 {
+			writeError(w, http.StatusBadRequest, ErrorResponse{
+				Error:   "invalid_parameters",
+				Message: fmt.Sprintf("Invalid 'timeout' value '%s', expected a duration such as 30s", t),
+			})
+			return
 		}
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(errorResp)
+		opts.Timeout = timeout
+	}
+
+	policy, err := parseCachePolicy(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, ErrorResponse{
+			Error:   "invalid_parameters",
+			Message: err.Error(),
+		})
 		return
 	}
 
-	product, err := scraper.Scrape(uri)
+	if !policy.Refresh {
+		if entry, ok := resultCache.Get(siteID, uri, policy.MaxAge); ok {
+			writeCacheHeaders(w, true, entry.Age())
+			w.Header().Set("X-Product-Id", history.ProductID(siteID, uri, entry.Product))
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(entry.Product)
+			return
+		}
+	}
+
+	// Tie the scrape to the request so a disconnected client stops it
+	product, err := scrapeGroup.Scrape(r.Context(), siteID, scraper, uri, opts)
 	if err != nil {
-		errorResp := ErrorResponse{
-			Error:   "scraping_failed",
-			Message: fmt.Sprintf("Failed to scrape URL: %v", err),
+		if r.Context().Err() != nil {
+			log.Printf("scrape of %s abandoned: %v", uri, r.Context().Err())
+			return
 		}
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(errorResp)
+		status, errorResp := scrapeErrorResponse(err)
+		writeError(w, status, errorResp)
 		return
 	}
+	if !policy.NoStore {
+		resultCache.Set(siteID, uri, product)
+	}
 
+	writeCacheHeaders(w, false, 0)
+	w.Header().Set("X-Product-Id", history.ProductID(siteID, uri, product))
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(product)
 }
 
+// resolveScraper returns the scraper and its identifier for site, or detects
+// them from uri when site is empty
+func resolveScraper(site, uri string) (string, scrappers.Scraper, error) {
+	if site != "" {
+		scraper, err := scrappers.GetScraper(site)
+		return site, scraper, err
+	}
+	return scrappers.DetectScraper(uri)
+}
+
+// resolveErrorResponse describes why resolveScraper failed
+func resolveErrorResponse(site, uri string, err error) ErrorResponse {
+	switch {
+	case errors.Is(err, scrappers.ErrAmbiguousSite):
+		return ErrorResponse{
+			Error:   "ambiguous_site",
+			Message: fmt.Sprintf("Several sites match this URL, pass 'site' explicitly: %v", err),
+		}
+	case site == "":
+		return ErrorResponse{
+			Error:   "unsupported_site",
+			Message: fmt.Sprintf("No supported site matches '%s'. Use /api/v1/sites to see available sites", uri),
+		}
+	default:
+		return ErrorResponse{
+			Error:   "unsupported_site",
+			Message: fmt.Sprintf("Site '%s' is not supported. Use /api/v1/sites to see available sites", site),
+		}
+	}
+}
+
+// scrapeErrorResponse maps a scraper error to an HTTP status and error body
+func scrapeErrorResponse(err error) (int, ErrorResponse) {
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, scrappers.ErrRateLimitWait) {
+		return http.StatusGatewayTimeout, ErrorResponse{
+			Error:   "scrape_timeout",
+			Message: fmt.Sprintf("Scraping did not finish in time: %v", err),
+		}
+	}
+	return http.StatusInternalServerError, ErrorResponse{
+		Error:   "scraping_failed",
+		Message: fmt.Sprintf("Failed to scrape URL: %v", err),
+	}
+}
+
+// writeError sends errorResp as a JSON body with the given status
+func writeError(w http.ResponseWriter, status int, errorResp ErrorResponse) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(errorResp)
+}
+
 func handleHealth(w http.ResponseWriter, r *http.Request) {
 	health := map[string]interface{}{
 		"status":    "healthy",
 		"timestamp": time.Now().UTC(),
 		"version":   "1.0.0",
 		"service":   "web-scraper-api",
+		"scrapes":   scrapeGroup.Stats(),
 	}
 
 	w.Header().Set("Content-Type", "application/json")
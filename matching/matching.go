@@ -0,0 +1,296 @@
+// Package matching recognizes the same product sold on different sites and
+// groups the offers into one canonical product
+package matching
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// DefaultThreshold is the lowest confidence at which two listings are
+// considered the same product
+const DefaultThreshold = 0.75
+
+var (
+	// tokenPattern splits names into words, keeping Azerbaijani and Cyrillic letters
+	tokenPattern = regexp.MustCompile(`[\p{L}\p{N}]+`)
+
+	// ramStoragePattern matches "8/256 GB" style RAM and storage pairs. \b is
+	// ASCII-only in Go, so the end of the unit is matched explicitly.
+	ramStoragePattern = regexp.MustCompile(`(?i)\b(\d{1,2})\s*(?:gb|qb|гб)?\s*/\s*(\d{2,4})\s*(gb|qb|гб|tb|тб)(?:[^\p{L}]|$)`)
+
+	// sizePattern matches a single memory size such as "128 GB" or "1TB"
+	sizePattern = regexp.MustCompile(`(?i)\b(\d{1,4})\s*(gb|qb|гб|tb|тб)(?:[^\p{L}]|$)`)
+)
+
+// stopWords carry no identity: product types, filler words and colors, which
+// rarely change the price of a model
+var stopWords = toSet(
+	// product types
+	"smartfon", "smartphone", "telefon", "phone", "mobil", "mobile", "смартфон", "телефон",
+	"noutbuk", "notebook", "laptop", "ноутбук", "planşet", "tablet", "планшет",
+	// filler
+	"the", "and", "with", "ilə", "və", "и", "с", "for", "new", "yeni",
+	// colors in en/az/ru
+	"black", "white", "blue", "red", "green", "gold", "silver", "gray", "grey", "purple",
+	"pink", "yellow", "midnight", "starlight", "graphite", "titanium", "natural",
+	"qara", "ağ", "göy", "mavi", "qırmızı", "yaşıl", "qızılı", "gümüşü", "boz", "bənövşəyi", "çəhrayı", "sarı",
+	"черный", "чёрный", "белый", "синий", "красный", "зеленый", "золотой", "серебристый", "серый",
+)
+
+// variantWords distinguish models of the same line and must agree for a match
+var variantWords = toSet("pro", "max", "plus", "ultra", "mini", "lite", "fe", "se", "air", "neo", "slim")
+
+// brandAliases maps name tokens that imply a brand to that brand
+var brandAliases = map[string]string{
+	"iphone": "apple", "ipad": "apple", "macbook": "apple", "airpods": "apple", "imac": "apple",
+	"galaxy": "samsung", "redmi": "xiaomi", "poco": "xiaomi", "pixel": "google",
+	"playstation": "sony", "xbox": "microsoft",
+}
+
+// knownBrands are recognized when they appear in a product name
+var knownBrands = toSet(
+	"apple", "samsung", "xiaomi", "honor", "huawei", "oppo", "realme", "nokia", "sony", "lg",
+	"lenovo", "hp", "asus", "acer", "dell", "msi", "tecno", "infinix", "vivo", "oneplus", "google",
+	"motorola", "microsoft", "jbl", "philips", "bosch", "beko", "tefal", "dyson",
+)
+
+// Listing is the information about one product page used for matching
+type Listing struct {
+	Name           string
+	Brand          string
+	InternalMemory string
+	RAM            string
+	SKU            string
+	EAN            string
+}
+
+// Features is the normalized form of a Listing
+type Features struct {
+	Brand     string
+	Tokens    map[string]bool // identifying name words, without brand, sizes and stop words
+	StorageGB int
+	RAMGB     int
+	SKU       string
+	EAN       string
+}
+
+// Extract normalizes a listing into comparable features
+func Extract(l Listing) Features {
+	f := Features{
+		Tokens: make(map[string]bool),
+		SKU:    strings.ToLower(strings.TrimSpace(l.SKU)),
+		EAN:    digitsOnly(l.EAN),
+	}
+
+	name := strings.ToLower(l.Name)
+	if m := ramStoragePattern.FindStringSubmatch(name); m != nil {
+		f.RAMGB, _ = strconv.Atoi(m[1])
+		f.StorageGB = sizeGB(m[2], m[3])
+		name = strings.Replace(name, m[0], " ", 1)
+	}
+	if f.StorageGB == 0 {
+		if m := sizePattern.FindStringSubmatch(name); m != nil {
+			f.StorageGB = sizeGB(m[1], m[2])
+		}
+	}
+	name = sizePattern.ReplaceAllString(name, " ")
+
+	// Explicit attributes win over what the name suggests
+	if m := sizePattern.FindStringSubmatch(strings.ToLower(l.InternalMemory)); m != nil {
+		f.StorageGB = sizeGB(m[1], m[2])
+	}
+	if m := sizePattern.FindStringSubmatch(strings.ToLower(l.RAM)); m != nil {
+		f.RAMGB = sizeGB(m[1], m[2])
+	}
+
+	f.Brand = strings.ToLower(strings.TrimSpace(l.Brand))
+	for _, token := range tokenPattern.FindAllString(name, -1) {
+		if f.Brand == "" {
+			if knownBrands[token] {
+				f.Brand = token
+			} else if brand, ok := brandAliases[token]; ok {
+				f.Brand = brand
+			}
+		}
+		if stopWords[token] || knownBrands[token] {
+			continue
+		}
+		f.Tokens[token] = true
+	}
+	return f
+}
+
+// Score returns the confidence, between 0 and 1, that a and b describe the
+// same product. A matching EAN is conclusive; conflicting brand, storage or
+// RAM rule a match out; otherwise the score is the overlap of name tokens
+// with small bonuses for agreeing attributes.
+func Score(a, b Features) float64 {
+	if a.EAN != "" && b.EAN != "" {
+		if a.EAN == b.EAN {
+			return 1
+		}
+		return 0
+	}
+	if conflicts(a.Brand, b.Brand) || conflictsInt(a.StorageGB, b.StorageGB) || conflictsInt(a.RAMGB, b.RAMGB) {
+		return 0
+	}
+	// Model numbers and variants must agree: "iPhone 13" is neither an
+	// "iPhone 14" nor an "iPhone 13 Pro"
+	if !equalSets(modelTokens(a.Tokens), modelTokens(b.Tokens)) {
+		return 0
+	}
+	// Manufacturer part numbers such as "MLPF3" are shared across shops
+	if a.SKU != "" && a.SKU == b.SKU && isPartNumber(a.SKU) {
+		return 0.95
+	}
+
+	score := jaccard(a.Tokens, b.Tokens)
+	if a.Brand != "" && a.Brand == b.Brand {
+		score += 0.1
+	}
+	if a.StorageGB != 0 && a.StorageGB == b.StorageGB {
+		score += 0.05
+	}
+	if a.RAMGB != 0 && a.RAMGB == b.RAMGB {
+		score += 0.05
+	}
+	if score > 0.99 {
+		score = 0.99
+	}
+	return score
+}
+
+// MatchesQuery reports whether a listing satisfies a free-text query: every
+// query word must appear in the listing and any size in the query must agree
+func MatchesQuery(query, listing Features) bool {
+	if conflicts(query.Brand, listing.Brand) {
+		return false
+	}
+	if query.StorageGB != 0 && listing.StorageGB != query.StorageGB {
+		return false
+	}
+	if query.RAMGB != 0 && listing.RAMGB != query.RAMGB {
+		return false
+	}
+	for token := range query.Tokens {
+		if !listing.Tokens[token] {
+			return false
+		}
+	}
+	return true
+}
+
+// Group is a set of listings judged to be the same product
+type Group struct {
+	// Members are indexes into the slice passed to Cluster
+	Members []int
+
+	// Confidence is the lowest score of a member against the group's first member
+	Confidence float64
+}
+
+// Cluster groups listings whose score against a group's first member is at
+// least threshold. Each listing joins the group it matches best; groups keep
+// the order in which their first member appeared.
+func Cluster(features []Features, threshold float64) []Group {
+	var groups []Group
+	for i, f := range features {
+		best, bestScore := -1, 0.0
+		for g := range groups {
+			if s := Score(features[groups[g].Members[0]], f); s >= threshold && s > bestScore {
+				best, bestScore = g, s
+			}
+		}
+		if best < 0 {
+			groups = append(groups, Group{Members: []int{i}, Confidence: 1})
+			continue
+		}
+		groups[best].Members = append(groups[best].Members, i)
+		if bestScore < groups[best].Confidence {
+			groups[best].Confidence = bestScore
+		}
+	}
+	return groups
+}
+
+// sizeGB converts a number and unit to gigabytes
+func sizeGB(number, unit string) int {
+	n, _ := strconv.Atoi(number)
+	switch strings.ToLower(unit) {
+	case "tb", "тб":
+		return n * 1024
+	}
+	return n
+}
+
+func conflicts(a, b string) bool {
+	return a != "" && b != "" && a != b
+}
+
+func conflictsInt(a, b int) bool {
+	return a != 0 && b != 0 && a != b
+}
+
+// modelTokens returns the model numbers (tokens containing a digit) and
+// variant words of tokens
+func modelTokens(tokens map[string]bool) map[string]bool {
+	model := make(map[string]bool)
+	for token := range tokens {
+		if variantWords[token] || strings.ContainsAny(token, "0123456789") {
+			model[token] = true
+		}
+	}
+	return model
+}
+
+// isPartNumber reports whether sku mixes letters and digits like a
+// manufacturer part number, unlike a shop's internal numeric ID
+func isPartNumber(sku string) bool {
+	return len(sku) >= 5 && strings.ContainsAny(sku, "0123456789") && strings.IndexFunc(sku, func(r rune) bool {
+		return r >= 'a' && r <= 'z'
+	}) >= 0
+}
+
+func jaccard(a, b map[string]bool) float64 {
+	if len(a) == 0 && len(b) == 0 {
+		return 0
+	}
+	shared := 0
+	for token := range a {
+		if b[token] {
+			shared++
+		}
+	}
+	return float64(shared) / float64(len(a)+len(b)-shared)
+}
+
+func equalSets(a, b map[string]bool) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for k := range a {
+		if !b[k] {
+			return false
+		}
+	}
+	return true
+}
+
+func digitsOnly(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= '0' && r <= '9' {
+			return r
+		}
+		return -1
+	}, s)
+}
+
+func toSet(words ...string) map[string]bool {
+	set := make(map[string]bool, len(words))
+	for _, w := range words {
+		set[w] = true
+	}
+	return set
+}
@@ -0,0 +1,88 @@
+package matching
+
+import "testing"
+
+func TestExtract(t *testing.T) {
+	tests := []struct {
+		listing   Listing
+		brand     string
+		storageGB int
+		ramGB     int
+		tokens    []string
+	}{
+		{Listing{Name: "iPhone 13 128 GB Midnight", Brand: "Apple"}, "apple", 128, 0, []string{"iphone", "13"}},
+		{Listing{Name: "Smartfon Samsung Galaxy A54 8/256GB Qara"}, "samsung", 256, 8, []string{"galaxy", "a54"}},
+		{Listing{Name: "Xiaomi Redmi Note 13", InternalMemory: "1 TB", RAM: "12 GB"}, "xiaomi", 1024, 12, []string{"redmi", "note", "13"}},
+		{Listing{Name: "Смартфон Honor X8 128 гб черный"}, "honor", 128, 0, []string{"x8"}},
+	}
+
+	for _, tt := range tests {
+		f := Extract(tt.listing)
+		if f.Brand != tt.brand || f.StorageGB != tt.storageGB || f.RAMGB != tt.ramGB {
+			t.Errorf("Extract(%q) = brand %q, storage %d, ram %d; want %q, %d, %d",
+				tt.listing.Name, f.Brand, f.StorageGB, f.RAMGB, tt.brand, tt.storageGB, tt.ramGB)
+		}
+		if len(f.Tokens) != len(tt.tokens) {
+			t.Errorf("Extract(%q) tokens = %v, want %v", tt.listing.Name, f.Tokens, tt.tokens)
+			continue
+		}
+		for _, token := range tt.tokens {
+			if !f.Tokens[token] {
+				t.Errorf("Extract(%q) tokens = %v, missing %q", tt.listing.Name, f.Tokens, token)
+			}
+		}
+	}
+}
+
+func TestScore(t *testing.T) {
+	tests := []struct {
+		name  string
+		a, b  Listing
+		match bool
+	}{
+		{"same phone, different shop naming", Listing{Name: "iPhone 13 128 GB Midnight", Brand: "Apple"}, Listing{Name: "Apple iPhone 13 128GB Starlight"}, true},
+		{"storage variant", Listing{Name: "iPhone 13 128 GB"}, Listing{Name: "iPhone 13 256 GB"}, false},
+		{"next model", Listing{Name: "iPhone 13 128 GB"}, Listing{Name: "iPhone 14 128 GB"}, false},
+		{"pro model", Listing{Name: "iPhone 13 128 GB"}, Listing{Name: "iPhone 13 Pro 128 GB"}, false},
+		{"RAM variant", Listing{Name: "Galaxy A54 6/128GB"}, Listing{Name: "Galaxy A54 8/128GB"}, false},
+		{"same EAN", Listing{Name: "Phone A", EAN: "0194252707234"}, Listing{Name: "Totally different", EAN: "0194252707234"}, true},
+		{"shared part number", Listing{Name: "Apple iPhone 13", SKU: "MLPF3"}, Listing{Name: "iPhone 13 Midnight new", SKU: "mlpf3"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			score := Score(Extract(tt.a), Extract(tt.b))
+			if got := score >= DefaultThreshold; got != tt.match {
+				t.Errorf("score %.2f, want match=%v", score, tt.match)
+			}
+		})
+	}
+}
+
+func TestClusterAndQuery(t *testing.T) {
+	listings := []Listing{
+		{Name: "iPhone 13 128 GB Midnight", Brand: "Apple"},
+		{Name: "Apple iPhone 13 256GB"},
+		{Name: "Apple iPhone 13 128GB Blue"},
+		{Name: "Samsung Galaxy S23 8/256GB"},
+	}
+
+	query := Extract(Listing{Name: "iphone 13"})
+	var features []Features
+	for _, l := range listings {
+		if f := Extract(l); MatchesQuery(query, f) {
+			features = append(features, f)
+		}
+	}
+	if len(features) != 3 {
+		t.Fatalf("expected 3 listings to match the query, got %d", len(features))
+	}
+
+	groups := Cluster(features, DefaultThreshold)
+	if len(groups) != 2 {
+		t.Fatalf("expected 2 groups, got %+v", groups)
+	}
+	if len(groups[0].Members) != 2 || groups[0].Members[1] != 2 {
+		t.Errorf("expected 128 GB listings to be grouped, got %+v", groups[0])
+	}
+}
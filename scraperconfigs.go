@@ -0,0 +1,118 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"web-scrappers/scrappers"
+)
+
+const (
+	// defaultScraperConfigDir holds the declarative scraper definitions
+	defaultScraperConfigDir = "sites"
+
+	// scraperConfigDebounce is how long the definitions directory must be
+	// quiet after a change before it is reloaded
+	scraperConfigDebounce = 500 * time.Millisecond
+)
+
+// scraperConfigDir returns SCRAPER_CONFIG_DIR or the default directory
+func scraperConfigDir() string {
+	if dir := os.Getenv("SCRAPER_CONFIG_DIR"); dir != "" {
+		return dir
+	}
+	return defaultScraperConfigDir
+}
+
+// loadScraperConfigs registers the scrapers defined in the config
+// directory. Broken definitions are logged and skipped so one bad file
+// does not keep the other sites from being served.
+func loadScraperConfigs() {
+	dir := scraperConfigDir()
+	result, err := scrappers.ReloadConfigScrapers(dir)
+	logReload(dir, result, err)
+}
+
+// watchScraperConfigs reloads the definitions whenever the directory
+// changes, unless SCRAPER_CONFIG_WATCH=0. Without a directory to watch,
+// only POST /api/v1/admin/reload picks up new definitions.
+func watchScraperConfigs(ctx context.Context) {
+	if os.Getenv("SCRAPER_CONFIG_WATCH") == "0" {
+		return
+	}
+	dir := scraperConfigDir()
+	if _, err := os.Stat(dir); errors.Is(err, os.ErrNotExist) {
+		return
+	}
+	err := scrappers.WatchScraperConfigs(ctx, dir, scraperConfigDebounce, func(result *scrappers.ReloadResult, err error) {
+		logReload(dir, result, err)
+	})
+	if err != nil {
+		log.Printf("Not watching scraper definitions in %s: %v", dir, err)
+	}
+}
+
+// logReload reports the outcome of a definitions reload
+func logReload(dir string, result *scrappers.ReloadResult, err error) {
+	if err != nil {
+		log.Printf("Failed to reload scraper definitions from %s: %v", dir, err)
+		return
+	}
+	for _, msg := range result.Errors {
+		log.Printf("Rejected scraper definition: %s", msg)
+	}
+	if len(result.Loaded) > 0 {
+		log.Printf("Scrapers loaded from %s: %s", dir, strings.Join(result.Loaded, ", "))
+	}
+	if len(result.Unchecked) > 0 {
+		log.Printf("Scrapers loaded without fixtures to check them: %s", strings.Join(result.Unchecked, ", "))
+	}
+	if len(result.Kept) > 0 {
+		log.Printf("Scrapers kept at their previous version: %s", strings.Join(result.Kept, ", "))
+	}
+	if len(result.Removed) > 0 {
+		log.Printf("Scrapers removed: %s", strings.Join(result.Removed, ", "))
+	}
+}
+
+// handleReload reloads the scraper definitions. It answers 200 when every
+// definition loaded and 422 when some were rejected, which leaves those
+// sites at their previous version. When ADMIN_TOKEN is set the request
+// needs it as a bearer token.
+func handleReload(w http.ResponseWriter, r *http.Request) {
+	if token := os.Getenv("ADMIN_TOKEN"); token != "" {
+		given := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if subtle.ConstantTimeCompare([]byte(given), []byte(token)) != 1 {
+			writeError(w, http.StatusUnauthorized, ErrorResponse{
+				Error:   "unauthorized",
+				Message: "A valid admin token is required",
+			})
+			return
+		}
+	}
+
+	dir := scraperConfigDir()
+	result, err := scrappers.ReloadConfigScrapers(dir)
+	logReload(dir, result, err)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, ErrorResponse{
+			Error:   "reload_failed",
+			Message: fmt.Sprintf("Failed to read scraper definitions: %v", err),
+		})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if len(result.Errors) > 0 {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+	}
+	json.NewEncoder(w).Encode(result)
+}
@@ -0,0 +1,202 @@
+package scrappers
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// bakuElectronicsDefaultTimeout bounds a bakuelectronics.az scrape when the caller sets no timeout
+const bakuElectronicsDefaultTimeout = 30 * time.Second
+
+// BakuElectronicsScraper implements the Scraper interface for bakuelectronics.az
+type BakuElectronicsScraper struct {
+	fetcher *Fetcher
+}
+
+// NewBakuElectronicsScraper creates a new instance of BakuElectronicsScraper backed by the
+// shared fetcher
+func NewBakuElectronicsScraper() *BakuElectronicsScraper {
+	return &BakuElectronicsScraper{fetcher: SharedFetcher()}
+}
+
+// GetSiteName returns the site name
+func (b *BakuElectronicsScraper) GetSiteName() string {
+	return "Baku Electronics"
+}
+
+// IsValidURL checks if the URL belongs to bakuelectronics.az
+func (b *BakuElectronicsScraper) IsValidURL(url string) bool {
+	return hostMatches(url, "bakuelectronics.az")
+}
+
+// IsProductURL reports whether url is a product page under /mehsul/
+func (b *BakuElectronicsScraper) IsProductURL(url string) bool {
+	return hasPathPrefix(url, "/mehsul/")
+}
+
+// Scrape extracts product information from bakuelectronics.az URL
+func (b *BakuElectronicsScraper) Scrape(url string) (*Product, error) {
+	return b.ScrapeContext(context.Background(), url, ScrapeOptions{})
+}
+
+// ScrapeContext extracts product information from bakuelectronics.az URL,
+// aborting the request as soon as ctx is done
+func (b *BakuElectronicsScraper) ScrapeContext(ctx context.Context, url string, opts ScrapeOptions) (*Product, error) {
+	if !b.IsValidURL(url) {
+		return nil, fmt.Errorf("URL does not belong to bakuelectronics.az: %s", url)
+	}
+
+	ctx, cancel := opts.withTimeout(ctx, bakuElectronicsDefaultTimeout)
+	defer cancel()
+
+	page, err := b.fetcher.Get(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+
+	return b.parse(url, string(page.Body))
+}
+
+// parse extracts product information from a bakuelectronics.az product page
+func (b *BakuElectronicsScraper) parse(url string, htmlContent string) (*Product, error) {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(htmlContent))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse HTML: %w", err)
+	}
+
+	product := &Product{
+		URL:       url,
+		Site:      "bakuelectronics",
+		ScrapedAt: time.Now().UTC().Format(time.RFC3339),
+	}
+
+	// Structured data comes first; the selectors below fill what it lacks
+	data := ExtractStructuredData(doc)
+	data.apply(product)
+
+	// Extract product name
+	doc.Find("h1.product__title, div.product__info h1, h1").Each(func(i int, s *goquery.Selection) {
+		if product.Name == "" {
+			product.Name = strings.Join(strings.Fields(s.Text()), " ")
+		}
+	})
+
+	// Extract SKU - shown as "Məhsulun kodu: 123456"
+	skuRe := regexp.MustCompile(`(\d{3,})`)
+	doc.Find("div.product__code, span.product__code, [data-product-code]").Each(func(i int, s *goquery.Selection) {
+		if product.SKU != "" {
+			return
+		}
+		if code, ok := s.Attr("data-product-code"); ok && strings.TrimSpace(code) != "" {
+			product.SKU = strings.TrimSpace(code)
+			return
+		}
+		if matches := skuRe.FindStringSubmatch(s.Text()); len(matches) > 1 {
+			product.SKU = matches[1]
+		}
+	})
+
+	// Extract prices - the discounted price is rendered as the "new" price and
+	// the crossed-out regular price as the "old" one
+	doc.Find("div.product__price--new, span.product__price--new, div.product__price-current").Each(func(i int, s *goquery.Selection) {
+		if product.CurrentPrice == "" {
+			product.CurrentPrice = strings.Join(strings.Fields(s.Text()), " ")
+		}
+	})
+	doc.Find("div.product__price--old, span.product__price--old, del.product__price-old").Each(func(i int, s *goquery.Selection) {
+		if product.OriginalPrice == "" {
+			product.OriginalPrice = strings.Join(strings.Fields(s.Text()), " ")
+		}
+	})
+
+	// If only the regular price is shown there is no discount
+	if product.CurrentPrice == "" && product.OriginalPrice != "" {
+		product.CurrentPrice = product.OriginalPrice
+		product.OriginalPrice = ""
+	}
+
+	// Extract discount label such as "-15%" or "-200 ₼"
+	doc.Find("span.product__discount, div.product__discount, span.discount-badge").Each(func(i int, s *goquery.Selection) {
+		text := strings.Join(strings.Fields(s.Text()), " ")
+		if product.Discount == "" && strings.Contains(text, "-") {
+			product.Discount = text
+		}
+	})
+
+	// Set currency
+	if strings.Contains(product.CurrentPrice, "₼") || strings.Contains(strings.ToUpper(product.CurrentPrice), "AZN") {
+		product.Currency = "AZN"
+	}
+
+	// Extract availability
+	doc.Find("div.product__availability, span.product__availability, div.product__stock").Each(func(i int, s *goquery.Selection) {
+		if product.Availability == "" {
+			product.Availability = strings.Join(strings.Fields(s.Text()), " ")
+		}
+	})
+
+	// Extract rating and review count
+	doc.Find("span.product__rating-value, span[itemprop='ratingValue']").Each(func(i int, s *goquery.Selection) {
+		if product.Rating == "" {
+			product.Rating = strings.TrimSpace(s.Text())
+		}
+	})
+	doc.Find("span.product__reviews-count, span[itemprop='reviewCount']").Each(func(i int, s *goquery.Selection) {
+		if product.ReviewCount == "" {
+			product.ReviewCount = strings.TrimSpace(s.Text())
+		}
+	})
+
+	// Extract specifications from the characteristics list
+	doc.Find("li.product-features__item, div.product-features__item, table.product-specs tr").Each(func(i int, s *goquery.Selection) {
+		label := strings.TrimSpace(s.Find(".product-features__name, th").First().Text())
+		value := strings.Join(strings.Fields(s.Find(".product-features__value, td").First().Text()), " ")
+		if label == "" || value == "" {
+			return
+		}
+		product.addSpec(label, value)
+		labelLower := strings.ToLower(label)
+		switch {
+		case strings.Contains(labelLower, "brend") && product.Brand == "":
+			product.Brand = value
+		case strings.Contains(labelLower, "daxili yaddaş") && product.InternalMemory == "":
+			product.InternalMemory = value
+		case strings.Contains(labelLower, "operativ yaddaş") && product.RAM == "":
+			product.RAM = value
+		case strings.Contains(labelLower, "əsas kamera") && product.MainCamera == "":
+			product.MainCamera = value
+		case strings.Contains(labelLower, "ön kamera") && product.FrontCamera == "":
+			product.FrontCamera = value
+		case strings.Contains(labelLower, "prosessor") && product.Processor == "":
+			product.Processor = value
+		case strings.Contains(labelLower, "əməliyyat sistemi") && product.OS == "":
+			product.OS = value
+		case (strings.Contains(labelLower, "ekran") || strings.Contains(labelLower, "displey")) && product.Display == "":
+			product.Display = value
+		}
+	})
+
+	// The OpenGraph title names any page, so it only counts on a page
+	// that has a price
+	if product.Name == "" && product.CurrentPrice != "" {
+		product.Name = data.Title
+	}
+	if product.Name == "" {
+		return nil, fmt.Errorf("no product found on page")
+	}
+
+	// Parse display prices into comparable values
+	product.fillPrices()
+
+	return product, nil
+}
+
+// init function registers the BakuElectronicsScraper when the package is imported
+func init() {
+	RegisterScraper("bakuelectronics", NewBakuElectronicsScraper())
+}
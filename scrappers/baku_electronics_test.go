@@ -0,0 +1,106 @@
+package scrappers
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestBakuElectronicsParse(t *testing.T) {
+	tests := []struct {
+		fixture string
+		want    Product
+		minor   int64
+	}{
+		{
+			fixture: "bakuelectronics_product.html",
+			want: Product{
+				Name:           "Smartfon Samsung Galaxy A55 8/256 GB Navy",
+				SKU:            "165421",
+				CurrentPrice:   "899,99 ₼",
+				OriginalPrice:  "1 059,99 ₼",
+				Discount:       "-15%",
+				Currency:       "AZN",
+				Availability:   "Mövcuddur",
+				Rating:         "4.8",
+				ReviewCount:    "12 rəy",
+				Brand:          "Samsung",
+				InternalMemory: "256 GB",
+				RAM:            "8 GB",
+				MainCamera:     "50 MP + 12 MP + 5 MP",
+				FrontCamera:    "32 MP",
+				Processor:      "Exynos 1480",
+				OS:             "Android 14",
+				Display:        "Super AMOLED",
+				Specs: map[string]SpecValue{
+					"brand":           {Label: "Brend", Value: "Samsung"},
+					"internal_memory": {Label: "Daxili yaddaş", Value: "256 GB", Quantity: quantity(256<<30, UnitBytes)},
+					"ram":             {Label: "Operativ yaddaş", Value: "8 GB", Quantity: quantity(8<<30, UnitBytes)},
+					"main_camera":     {Label: "Əsas kamera", Value: "50 MP + 12 MP + 5 MP", Quantity: quantity(50, UnitMegapixels)},
+					"front_camera":    {Label: "Ön kamera", Value: "32 MP", Quantity: quantity(32, UnitMegapixels)},
+					"processor":       {Label: "Prosessor", Value: "Exynos 1480"},
+					"os":              {Label: "Əməliyyat sistemi", Value: "Android 14"},
+					"display_type":    {Label: "Ekranın növü", Value: "Super AMOLED"},
+				},
+			},
+			minor: 89999,
+		},
+		{
+			fixture: "bakuelectronics_no_discount.html",
+			want: Product{
+				Name:           "Oyun konsolu Sony PlayStation 5 Slim 1 TB",
+				SKU:            "158877",
+				CurrentPrice:   "1.399,99 ₼",
+				Currency:       "AZN",
+				Availability:   "Stokda yoxdur",
+				Brand:          "Sony",
+				InternalMemory: "1 TB",
+				Processor:      "AMD Zen 2",
+				Specs: map[string]SpecValue{
+					"brand":           {Label: "Brend", Value: "Sony"},
+					"internal_memory": {Label: "Daxili yaddaş", Value: "1 TB", Quantity: quantity(1024<<30, UnitBytes)},
+					"processor":       {Label: "Prosessor", Value: "AMD Zen 2"},
+				},
+			},
+			minor: 139999,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.fixture, func(t *testing.T) {
+			html, err := os.ReadFile(filepath.Join("testdata", tt.fixture))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			url := "https://www.bakuelectronics.az/mehsul/" + tt.fixture
+			got, err := NewBakuElectronicsScraper().parse(url, string(html))
+			if err != nil {
+				t.Fatalf("parse: %v", err)
+			}
+
+			if got.CurrentPriceValue == nil || got.CurrentPriceValue.Minor != tt.minor {
+				t.Errorf("CurrentPriceValue = %v, want %d minor units", got.CurrentPriceValue, tt.minor)
+			}
+
+			// Compare the display fields only
+			tt.want.URL, tt.want.Site = url, "bakuelectronics"
+			got.ScrapedAt = ""
+			got.CurrentPriceValue, got.OriginalPriceValue, got.DiscountValue, got.DiscountPercent = nil, nil, nil, 0
+			if !reflect.DeepEqual(*got, tt.want) {
+				t.Errorf("parse mismatch\n got: %+v\nwant: %+v", *got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBakuElectronicsIsValidURL(t *testing.T) {
+	s := NewBakuElectronicsScraper()
+	if !s.IsValidURL("https://www.bakuelectronics.az/mehsul/samsung-galaxy-a55") {
+		t.Error("expected bakuelectronics.az URL to be valid")
+	}
+	if s.IsValidURL("https://kontakt.az/iphone-13-128-gb-midnight") {
+		t.Error("expected kontakt.az URL to be invalid")
+	}
+}
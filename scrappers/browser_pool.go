@@ -0,0 +1,415 @@
+package scrappers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/chromedp/chromedp"
+)
+
+// ErrBrowserPoolClosed is returned by NewTab after the pool has been closed
+var ErrBrowserPoolClosed = errors.New("browser pool closed")
+
+// BrowserPoolConfig controls the size and lifecycle of a BrowserPool
+type BrowserPoolConfig struct {
+	// Size is the number of browser processes kept running
+	Size int
+
+	// MaxTabsPerBrowser caps concurrently open tabs in one browser
+	MaxTabsPerBrowser int
+
+	// MaxUses recycles a browser after it has served this many tabs
+	MaxUses int
+
+	// HealthCheckInterval is how often idle browsers are probed; zero disables it
+	HealthCheckInterval time.Duration
+
+	// AllocatorOptions are passed to chromedp.NewExecAllocator
+	AllocatorOptions []chromedp.ExecAllocatorOption
+
+	// Limiter, when set, applies per-host politeness to every Render
+	Limiter *HostLimiter
+}
+
+// DefaultBrowserPoolConfig returns the pool settings, overridable through the
+// BROWSER_POOL_SIZE, BROWSER_MAX_TABS and BROWSER_MAX_USES environment variables
+func DefaultBrowserPoolConfig() BrowserPoolConfig {
+	return BrowserPoolConfig{
+		Size:                envPositiveInt("BROWSER_POOL_SIZE", 1),
+		MaxTabsPerBrowser:   envPositiveInt("BROWSER_MAX_TABS", 4),
+		MaxUses:             envPositiveInt("BROWSER_MAX_USES", 50),
+		HealthCheckInterval: 30 * time.Second,
+		AllocatorOptions: []chromedp.ExecAllocatorOption{
+			chromedp.NoFirstRun,
+			chromedp.NoDefaultBrowserCheck,
+			chromedp.Headless,
+			chromedp.DisableGPU,
+			chromedp.NoSandbox,
+			chromedp.UserAgent("Mozilla/5.0 (X11; Linux x86_64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36"),
+		},
+	}
+}
+
+// envPositiveInt reads a positive integer from the environment, or returns fallback
+func envPositiveInt(name string, fallback int) int {
+	if v, err := strconv.Atoi(os.Getenv(name)); err == nil && v > 0 {
+		return v
+	}
+	return fallback
+}
+
+// pooledBrowser is one headless Chrome process owned by a BrowserPool
+type pooledBrowser struct {
+	ctx         context.Context // browser context; tabs are derived from it
+	cancel      context.CancelFunc
+	allocCancel context.CancelFunc
+
+	active   int  // open tabs
+	uses     int  // tabs served since start
+	retiring bool // no new tabs; closed once active drops to zero
+}
+
+// close shuts the browser process down
+func (b *pooledBrowser) close() {
+	b.cancel()
+	b.allocCancel()
+}
+
+// BrowserPool keeps warm headless Chrome processes and hands out tabs in
+// them, so chromedp-based scrapers do not pay a browser start per request
+type BrowserPool struct {
+	cfg BrowserPoolConfig
+
+	mu       sync.Mutex
+	browsers []*pooledBrowser
+	starting int           // browsers being launched outside the lock
+	released chan struct{} // closed and replaced whenever a tab or slot frees up
+	closed   bool
+	done     chan struct{}
+
+	// start launches a browser; startBrowser unless replaced in tests
+	start func(ctx context.Context) (*pooledBrowser, error)
+}
+
+// NewBrowserPool creates a pool; browsers are started lazily or by Warm
+func NewBrowserPool(cfg BrowserPoolConfig) *BrowserPool {
+	if cfg.Size <= 0 {
+		cfg.Size = 1
+	}
+	if cfg.MaxTabsPerBrowser <= 0 {
+		cfg.MaxTabsPerBrowser = 1
+	}
+
+	p := &BrowserPool{
+		cfg:      cfg,
+		released: make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	p.start = p.startBrowser
+	if cfg.HealthCheckInterval > 0 {
+		go p.healthLoop()
+	}
+	return p
+}
+
+var (
+	sharedPool     *BrowserPool
+	sharedPoolOnce sync.Once
+)
+
+// SharedBrowserPool returns the process-wide pool used by chromedp scrapers
+func SharedBrowserPool() *BrowserPool {
+	sharedPoolOnce.Do(func() {
+		cfg := DefaultBrowserPoolConfig()
+		cfg.Limiter = SharedHostLimiter()
+		sharedPool = NewBrowserPool(cfg)
+	})
+	return sharedPool
+}
+
+// Warm starts browsers until the pool holds cfg.Size of them
+func (p *BrowserPool) Warm(ctx context.Context) error {
+	for {
+		p.mu.Lock()
+		if p.closed {
+			p.mu.Unlock()
+			return ErrBrowserPoolClosed
+		}
+		if len(p.browsers)+p.starting >= p.cfg.Size {
+			p.mu.Unlock()
+			return nil
+		}
+		p.starting++
+		p.mu.Unlock()
+
+		b, err := p.start(ctx)
+
+		p.mu.Lock()
+		p.starting--
+		if err == nil {
+			if p.closed {
+				b.close()
+			} else {
+				p.browsers = append(p.browsers, b)
+			}
+		}
+		p.mu.Unlock()
+		p.signal()
+
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// NewTab opens a tab in a pooled browser. The returned context drives the
+// tab and is cancelled when ctx is done; release must be called when the
+// caller is finished with the tab.
+func (p *BrowserPool) NewTab(ctx context.Context) (context.Context, func(), error) {
+	for {
+		b, err := p.acquire(ctx)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		tabCtx, cancelTab := chromedp.NewContext(b.ctx)
+		stop := context.AfterFunc(ctx, cancelTab)
+
+		// Open the tab now so a dead browser is detected before the caller runs
+		if err := chromedp.Run(tabCtx); err != nil {
+			stop()
+			cancelTab()
+			// A tab aborted by the caller says nothing about the browser
+			p.release(b, ctx.Err() == nil)
+			if ctx.Err() != nil {
+				return nil, nil, ctx.Err()
+			}
+			log.Printf("browser pool: discarding browser that failed to open a tab: %v", err)
+			continue
+		}
+
+		var once sync.Once
+		release := func() {
+			once.Do(func() {
+				stop()
+				cancelTab()
+				p.release(b, false)
+			})
+		}
+		return tabCtx, release, nil
+	}
+}
+
+// Render loads url in a pooled tab, waits until ready is satisfied, runs
+// any extra actions (e.g. scrolling) and returns the page's HTML. Host
+// politeness limits apply for the whole render.
+func (p *BrowserPool) Render(ctx context.Context, url string, ready ReadySpec, actions ...chromedp.Action) (string, error) {
+	if p.cfg.Limiter != nil {
+		release, err := p.cfg.Limiter.Acquire(ctx, url)
+		if err != nil {
+			return "", err
+		}
+		defer release()
+	}
+
+	tabCtx, release, err := p.NewTab(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to open browser tab: %w", err)
+	}
+	defer release()
+
+	var htmlContent string
+	steps := append([]chromedp.Action{ready.NavigateAndWait(url)}, actions...)
+	steps = append(steps, chromedp.OuterHTML("html", &htmlContent))
+	err = chromedp.Run(tabCtx, steps...)
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch URL with chromedp: %w", err)
+	}
+	return htmlContent, nil
+}
+
+// acquire reserves a tab slot, starting a browser if the pool is not full
+// and waiting for a free slot otherwise
+func (p *BrowserPool) acquire(ctx context.Context) (*pooledBrowser, error) {
+	for {
+		p.mu.Lock()
+		if p.closed {
+			p.mu.Unlock()
+			return nil, ErrBrowserPoolClosed
+		}
+
+		// Prefer the least busy browser that can take another tab
+		var best *pooledBrowser
+		for _, b := range p.browsers {
+			if b.retiring || b.active >= p.cfg.MaxTabsPerBrowser {
+				continue
+			}
+			if best == nil || b.active < best.active {
+				best = b
+			}
+		}
+		if best != nil {
+			best.active++
+			best.uses++
+			if p.cfg.MaxUses > 0 && best.uses >= p.cfg.MaxUses {
+				best.retiring = true
+			}
+			p.mu.Unlock()
+			return best, nil
+		}
+
+		canStart := len(p.browsers)+p.starting < p.cfg.Size
+		if canStart {
+			p.starting++
+		}
+		released := p.released
+		p.mu.Unlock()
+
+		if canStart {
+			b, err := p.start(ctx)
+			p.mu.Lock()
+			p.starting--
+			if err == nil && !p.closed {
+				p.browsers = append(p.browsers, b)
+			} else if err == nil {
+				b.close()
+			}
+			p.mu.Unlock()
+			p.signal()
+			if err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		select {
+		case <-released:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-p.done:
+			return nil, ErrBrowserPoolClosed
+		}
+	}
+}
+
+// release returns a tab slot. A broken browser takes no new tabs; like a
+// retiring one, it is closed once its last tab does, so renders still
+// running in sibling tabs finish.
+func (p *BrowserPool) release(b *pooledBrowser, broken bool) {
+	p.mu.Lock()
+	b.active--
+	if broken {
+		b.retiring = true
+	}
+	if b.retiring && b.active == 0 {
+		p.remove(b)
+	}
+	p.mu.Unlock()
+	p.signal()
+}
+
+// remove drops b from the pool and shuts it down; p.mu must be held
+func (p *BrowserPool) remove(b *pooledBrowser) {
+	for i, other := range p.browsers {
+		if other == b {
+			p.browsers = append(p.browsers[:i], p.browsers[i+1:]...)
+			break
+		}
+	}
+	b.close()
+}
+
+// signal wakes every goroutine waiting in acquire
+func (p *BrowserPool) signal() {
+	p.mu.Lock()
+	close(p.released)
+	p.released = make(chan struct{})
+	p.mu.Unlock()
+}
+
+// startBrowser launches a new Chrome process
+func (p *BrowserPool) startBrowser(ctx context.Context) (*pooledBrowser, error) {
+	allocCtx, allocCancel := chromedp.NewExecAllocator(context.Background(), p.cfg.AllocatorOptions...)
+	browserCtx, cancel := chromedp.NewContext(allocCtx)
+	b := &pooledBrowser{ctx: browserCtx, cancel: cancel, allocCancel: allocCancel}
+
+	// Start the process, but give up if the caller goes away first
+	stop := context.AfterFunc(ctx, b.close)
+	err := chromedp.Run(browserCtx)
+	stop()
+	if err != nil {
+		b.close()
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, fmt.Errorf("failed to start browser: %w", err)
+	}
+
+	if os.Getenv("DEBUG") == "1" {
+		fmt.Println("DEBUG: browser pool started a new browser")
+	}
+	return b, nil
+}
+
+// healthLoop periodically probes idle browsers and drops unresponsive ones
+func (p *BrowserPool) healthLoop() {
+	ticker := time.NewTicker(p.cfg.HealthCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-p.done:
+			return
+		case <-ticker.C:
+		}
+
+		p.mu.Lock()
+		var idle []*pooledBrowser
+		for _, b := range p.browsers {
+			if b.active == 0 && !b.retiring {
+				idle = append(idle, b)
+			}
+		}
+		p.mu.Unlock()
+
+		for _, b := range idle {
+			ctx, cancel := context.WithTimeout(b.ctx, 5*time.Second)
+			_, err := chromedp.Targets(ctx)
+			cancel()
+			if err == nil {
+				continue
+			}
+
+			log.Printf("browser pool: dropping unhealthy browser: %v", err)
+			p.mu.Lock()
+			if b.active == 0 {
+				p.remove(b)
+			} else {
+				b.retiring = true
+			}
+			p.mu.Unlock()
+			p.signal()
+		}
+	}
+}
+
+// Close shuts down every browser; tabs still in use are killed
+func (p *BrowserPool) Close() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		return
+	}
+	p.closed = true
+	close(p.done)
+	for _, b := range p.browsers {
+		b.close()
+	}
+	p.browsers = nil
+}
@@ -0,0 +1,149 @@
+package scrappers
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// fakeBrowsers replaces a pool's browser start with one that launches no
+// process and counts starts and closes
+type fakeBrowsers struct {
+	started atomic.Int32
+	closed  atomic.Int32
+}
+
+func (f *fakeBrowsers) pool(cfg BrowserPoolConfig) *BrowserPool {
+	p := NewBrowserPool(cfg)
+	p.start = func(ctx context.Context) (*pooledBrowser, error) {
+		f.started.Add(1)
+		browserCtx, cancel := context.WithCancel(context.Background())
+		return &pooledBrowser{ctx: browserCtx, cancel: cancel, allocCancel: func() { f.closed.Add(1) }}, nil
+	}
+	return p
+}
+
+// browserCount returns the browsers the pool holds
+func browserCount(p *BrowserPool) int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.browsers)
+}
+
+func TestBrowserPoolAcquireRelease(t *testing.T) {
+	var fake fakeBrowsers
+	p := fake.pool(BrowserPoolConfig{Size: 1, MaxTabsPerBrowser: 2})
+	defer p.Close()
+
+	a, err := p.acquire(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := p.acquire(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a != b || a.active != 2 || fake.started.Load() != 1 {
+		t.Fatalf("expected both tabs in one browser, got %d active and %d starts", a.active, fake.started.Load())
+	}
+
+	// The pool is full, so a third tab waits for a slot
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := p.acquire(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("acquire on a full pool = %v, want a deadline error", err)
+	}
+
+	got := make(chan *pooledBrowser)
+	go func() {
+		c, _ := p.acquire(context.Background())
+		got <- c
+	}()
+	p.release(a, false)
+	select {
+	case c := <-got:
+		if c != a {
+			t.Error("waiting acquire got another browser")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("release did not wake the waiting acquire")
+	}
+
+	p.release(a, false)
+	p.release(a, false)
+	if a.active != 0 || browserCount(p) != 1 || fake.closed.Load() != 0 {
+		t.Errorf("released browser: %d active, %d in pool, %d closed", a.active, browserCount(p), fake.closed.Load())
+	}
+}
+
+func TestBrowserPoolRetiresAfterMaxUses(t *testing.T) {
+	var fake fakeBrowsers
+	p := fake.pool(BrowserPoolConfig{Size: 1, MaxTabsPerBrowser: 4, MaxUses: 2})
+	defer p.Close()
+
+	first, _ := p.acquire(context.Background())
+	second, _ := p.acquire(context.Background())
+	if first != second || !first.retiring {
+		t.Fatal("browser not retiring after MaxUses tabs")
+	}
+
+	p.release(first, false)
+	if fake.closed.Load() != 0 {
+		t.Error("retiring browser closed while a tab is open")
+	}
+	p.release(second, false)
+	if fake.closed.Load() != 1 || browserCount(p) != 0 {
+		t.Errorf("retired browser: %d closed, %d in pool", fake.closed.Load(), browserCount(p))
+	}
+
+	next, err := p.acquire(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if next == first || fake.started.Load() != 2 {
+		t.Error("no fresh browser started after retirement")
+	}
+}
+
+func TestBrowserPoolBrokenWaitsForSiblings(t *testing.T) {
+	var fake fakeBrowsers
+	p := fake.pool(BrowserPoolConfig{Size: 1, MaxTabsPerBrowser: 2})
+	defer p.Close()
+
+	broken, _ := p.acquire(context.Background())
+	sibling, _ := p.acquire(context.Background())
+
+	p.release(broken, true)
+	if fake.closed.Load() != 0 || sibling.ctx.Err() != nil {
+		t.Fatal("broken browser closed under a sibling tab")
+	}
+	if !sibling.retiring {
+		t.Error("broken browser still takes new tabs")
+	}
+
+	p.release(sibling, false)
+	if fake.closed.Load() != 1 || browserCount(p) != 0 {
+		t.Errorf("broken browser: %d closed, %d in pool", fake.closed.Load(), browserCount(p))
+	}
+}
+
+func TestBrowserPoolClosed(t *testing.T) {
+	var fake fakeBrowsers
+	p := fake.pool(BrowserPoolConfig{Size: 2, MaxTabsPerBrowser: 1})
+	if err := p.Warm(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if fake.started.Load() != 2 {
+		t.Errorf("Warm started %d browsers, want 2", fake.started.Load())
+	}
+
+	p.Close()
+	if fake.closed.Load() != 2 {
+		t.Errorf("Close closed %d browsers, want 2", fake.closed.Load())
+	}
+	if _, err := p.acquire(context.Background()); !errors.Is(err, ErrBrowserPoolClosed) {
+		t.Errorf("acquire after Close = %v", err)
+	}
+}
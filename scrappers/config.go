@@ -0,0 +1,702 @@
+package scrappers
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/andybalholm/cascadia"
+	"gopkg.in/yaml.v3"
+)
+
+// configDefaultTimeout bounds a scrape when neither the caller nor the
+// definition sets a timeout
+const configDefaultTimeout = 30 * time.Second
+
+// Fetch modes of a scraper definition
+const (
+	FetchStatic   = "static"
+	FetchChromedp = "chromedp"
+)
+
+// ScraperConfig is a declarative scraper definition, read from a YAML or
+// JSON file. It names the site, how its pages are fetched and where each
+// Product field is found on them.
+type ScraperConfig struct {
+	// ID is the site identifier used by the API, e.g. "maxi"
+	ID      string `yaml:"id"`
+	Name    string `yaml:"name"`
+	BaseURL string `yaml:"base_url"`
+
+	// Hosts are the domains the site is served from; subdomains match too
+	Hosts []string `yaml:"hosts"`
+
+	// Replaces names a built-in scraper the definition takes over, with its
+	// hosts, so a site can be fixed without a release. It requires fixtures.
+	Replaces string `yaml:"replaces"`
+
+	// Fetch is "static" (the default) for plain HTTP or "chromedp" to render
+	// pages in the shared browser pool, waiting for Ready
+	Fetch string      `yaml:"fetch"`
+	Ready ReadyConfig `yaml:"ready"`
+
+	// Timeout bounds a scrape when the caller sets none, as a Go duration
+	Timeout string `yaml:"timeout"`
+
+	// ProductURL is a regular expression matching the URLs of product pages,
+	// used by sitemap discovery; long slugs at the site root by default
+	ProductURL string `yaml:"product_url"`
+
+	// StructuredData reads JSON-LD, microdata and OpenGraph before the
+	// selectors; on unless set to false
+	StructuredData *bool `yaml:"structured_data"`
+
+	// Fields maps Product fields, by their JSON names, to extraction rules
+	Fields map[string]FieldRule `yaml:"fields"`
+
+	// Specs reads "label: value" rows into Product.Specs and, as mapped,
+	// into Product fields
+	Specs SpecRules `yaml:"specs"`
+
+	// Fixtures are saved pages the definition must scrape correctly to be
+	// loaded
+	Fixtures []ConfigFixture `yaml:"fixtures"`
+}
+
+// ConfigFixture is a saved page of the site and the values a definition
+// must extract from it
+type ConfigFixture struct {
+	// File is the saved HTML, relative to the definition file
+	File string `yaml:"file"`
+
+	// URL is the address the page was saved from; base_url by default
+	URL string `yaml:"url"`
+
+	// Expect maps Product fields, by their JSON names, to their values
+	Expect map[string]string `yaml:"expect"`
+}
+
+// ReadyConfig is the ReadySpec of a chromedp definition, with durations
+// written as Go durations
+type ReadyConfig struct {
+	ChallengeCleared bool     `yaml:"challenge_cleared"`
+	Selectors        []string `yaml:"selectors"`
+	NetworkIdle      string   `yaml:"network_idle"`
+	Timeout          string   `yaml:"timeout"`
+}
+
+// FieldRule extracts one Product field. The first element matched by
+// Selector whose value is not empty wins. A rule given as a plain string is
+// a selector.
+type FieldRule struct {
+	// Selector is a CSS selector; without one Regex searches the whole page
+	Selector string `yaml:"selector"`
+
+	// Attr reads an attribute instead of the element's text
+	Attr string `yaml:"attr"`
+
+	// Regex keeps its first capture group, or the whole match, and skips
+	// values it does not match
+	Regex string `yaml:"regex"`
+
+	// Value is a constant, e.g. the currency of a single-currency store
+	Value string `yaml:"value"`
+
+	// Post lists post-processors applied in order, see postProcessors
+	Post []string `yaml:"post"`
+}
+
+// UnmarshalYAML accepts a bare selector as well as a full rule
+func (r *FieldRule) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		r.Selector = node.Value
+		return nil
+	}
+	type plain FieldRule
+	return node.Decode((*plain)(r))
+}
+
+// SpecRules reads specification rows such as table rows with a label and a
+// value cell
+type SpecRules struct {
+	Rows  string `yaml:"rows"`
+	Label string `yaml:"label"`
+	Value string `yaml:"value"`
+
+	// Fields maps Product fields to the label fragments, matched
+	// case-insensitively, of the rows that fill them
+	Fields map[string][]string `yaml:"fields"`
+}
+
+// productFields are the Product fields a definition can fill, by JSON name
+var productFields = map[string]func(*Product) *string{
+	"name":            func(p *Product) *string { return &p.Name },
+	"sku":             func(p *Product) *string { return &p.SKU },
+	"ean":             func(p *Product) *string { return &p.EAN },
+	"current_price":   func(p *Product) *string { return &p.CurrentPrice },
+	"original_price":  func(p *Product) *string { return &p.OriginalPrice },
+	"discount":        func(p *Product) *string { return &p.Discount },
+	"currency":        func(p *Product) *string { return &p.Currency },
+	"availability":    func(p *Product) *string { return &p.Availability },
+	"rating":          func(p *Product) *string { return &p.Rating },
+	"review_count":    func(p *Product) *string { return &p.ReviewCount },
+	"brand":           func(p *Product) *string { return &p.Brand },
+	"internal_memory": func(p *Product) *string { return &p.InternalMemory },
+	"ram":             func(p *Product) *string { return &p.RAM },
+	"main_camera":     func(p *Product) *string { return &p.MainCamera },
+	"front_camera":    func(p *Product) *string { return &p.FrontCamera },
+	"processor":       func(p *Product) *string { return &p.Processor },
+	"os":              func(p *Product) *string { return &p.OS },
+	"display":         func(p *Product) *string { return &p.Display },
+}
+
+// postProcessors transform extracted values. Those taking an argument are
+// written "name:argument", e.g. "after::" or "replace:,=>.".
+var postProcessors = map[string]func(arg string) (func(string) string, error){
+	"lower": noArg(strings.ToLower),
+	"upper": noArg(strings.ToUpper),
+	"first_line": noArg(func(s string) string {
+		for _, line := range strings.Split(s, "\n") {
+			if line = strings.TrimSpace(line); line != "" {
+				return line
+			}
+		}
+		return ""
+	}),
+	"digits": noArg(func(s string) string {
+		return strings.Map(func(r rune) rune {
+			if r >= '0' && r <= '9' {
+				return r
+			}
+			return -1
+		}, s)
+	}),
+	"after": withArg(func(s, sep string) string {
+		if _, after, found := strings.Cut(s, sep); found {
+			return after
+		}
+		return s
+	}),
+	"before": withArg(func(s, sep string) string {
+		before, _, _ := strings.Cut(s, sep)
+		return before
+	}),
+	"strip": withArg(func(s, text string) string {
+		return strings.ReplaceAll(s, text, "")
+	}),
+	"prefix": withArg(func(s, text string) string { return text + s }),
+	"suffix": withArg(func(s, text string) string { return s + text }),
+	"replace": func(arg string) (func(string) string, error) {
+		old, replacement, found := strings.Cut(arg, "=>")
+		if !found || old == "" {
+			return nil, errors.New("replace needs 'old=>new'")
+		}
+		return func(s string) string { return strings.ReplaceAll(s, old, replacement) }, nil
+	},
+}
+
+// noArg wraps a post-processor that takes no argument
+func noArg(fn func(string) string) func(string) (func(string) string, error) {
+	return func(arg string) (func(string) string, error) {
+		if arg != "" {
+			return nil, errors.New("takes no argument")
+		}
+		return fn, nil
+	}
+}
+
+// withArg wraps a post-processor that requires an argument
+func withArg(fn func(s, arg string) string) func(string) (func(string) string, error) {
+	return func(arg string) (func(string) string, error) {
+		if arg == "" {
+			return nil, errors.New("needs an argument")
+		}
+		return func(s string) string { return fn(s, arg) }, nil
+	}
+}
+
+// siteIDPattern restricts site identifiers to what fits in URLs and keys
+var siteIDPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
+// ConfigScraper is a Scraper driven by a ScraperConfig instead of code
+type ConfigScraper struct {
+	cfg        ScraperConfig
+	timeout    time.Duration
+	ready      ReadySpec
+	productURL *regexp.Regexp
+	fields     []configField
+	specs      []configSpec
+
+	fetcher *Fetcher
+	pool    *BrowserPool
+}
+
+// configField is a compiled FieldRule
+type configField struct {
+	name   string
+	target func(*Product) *string
+	rule   FieldRule
+	re     *regexp.Regexp
+	post   []func(string) string
+}
+
+// configSpec is a compiled SpecRules entry
+type configSpec struct {
+	target func(*Product) *string
+	labels []string
+}
+
+// NewConfigScraper validates cfg and compiles it into a scraper
+func NewConfigScraper(cfg ScraperConfig) (*ConfigScraper, error) {
+	if !siteIDPattern.MatchString(cfg.ID) {
+		return nil, fmt.Errorf("invalid id '%s': use lowercase letters, digits, '-' and '_'", cfg.ID)
+	}
+	if len(cfg.Hosts) == 0 {
+		return nil, errors.New("at least one host is required")
+	}
+	for i, host := range cfg.Hosts {
+		cfg.Hosts[i] = strings.TrimSuffix(strings.ToLower(strings.TrimSpace(host)), ".")
+		if cfg.Hosts[i] == "" || strings.ContainsAny(cfg.Hosts[i], "/:") {
+			return nil, fmt.Errorf("invalid host '%s': give a bare domain such as example.az", host)
+		}
+	}
+	if cfg.Name == "" {
+		cfg.Name = cfg.Hosts[0]
+	}
+	if cfg.BaseURL == "" {
+		cfg.BaseURL = "https://" + cfg.Hosts[0]
+	}
+
+	s := &ConfigScraper{cfg: cfg, timeout: configDefaultTimeout}
+	var err error
+	if cfg.Timeout != "" {
+		if s.timeout, err = parsePositiveDuration(cfg.Timeout); err != nil {
+			return nil, fmt.Errorf("invalid timeout: %w", err)
+		}
+	}
+
+	switch cfg.Fetch {
+	case "", FetchStatic:
+		s.fetcher = SharedFetcher()
+	case FetchChromedp:
+		s.pool = SharedBrowserPool()
+		s.ready = ReadySpec{ChallengeCleared: cfg.Ready.ChallengeCleared, Selectors: cfg.Ready.Selectors}
+		for _, selector := range cfg.Ready.Selectors {
+			if err := checkSelector(selector); err != nil {
+				return nil, fmt.Errorf("ready: %w", err)
+			}
+		}
+		if cfg.Ready.NetworkIdle != "" {
+			if s.ready.NetworkIdle, err = parsePositiveDuration(cfg.Ready.NetworkIdle); err != nil {
+				return nil, fmt.Errorf("invalid ready.network_idle: %w", err)
+			}
+		}
+		if cfg.Ready.Timeout != "" {
+			if s.ready.Timeout, err = parsePositiveDuration(cfg.Ready.Timeout); err != nil {
+				return nil, fmt.Errorf("invalid ready.timeout: %w", err)
+			}
+		}
+	default:
+		return nil, fmt.Errorf("unknown fetch mode '%s', expected %s or %s", cfg.Fetch, FetchStatic, FetchChromedp)
+	}
+
+	if cfg.ProductURL != "" {
+		if s.productURL, err = regexp.Compile(cfg.ProductURL); err != nil {
+			return nil, fmt.Errorf("invalid product_url: %w", err)
+		}
+	}
+
+	if _, ok := cfg.Fields["name"]; !ok {
+		return nil, errors.New("a 'name' field rule is required")
+	}
+	// Fields are applied in a fixed order so results do not depend on map
+	// iteration
+	names := make([]string, 0, len(cfg.Fields))
+	for name := range cfg.Fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		field, err := compileField(name, cfg.Fields[name])
+		if err != nil {
+			return nil, fmt.Errorf("field %s: %w", name, err)
+		}
+		s.fields = append(s.fields, field)
+	}
+
+	for i, fixture := range cfg.Fixtures {
+		if fixture.File == "" {
+			return nil, fmt.Errorf("fixture %d: 'file' is required", i+1)
+		}
+		for name := range fixture.Expect {
+			if _, ok := productFields[name]; !ok {
+				return nil, fmt.Errorf("fixture %s: unknown field '%s'", fixture.File, name)
+			}
+		}
+	}
+
+	if cfg.Specs.Rows != "" || len(cfg.Specs.Fields) > 0 {
+		for _, selector := range []struct{ name, value string }{
+			{"rows", cfg.Specs.Rows}, {"label", cfg.Specs.Label}, {"value", cfg.Specs.Value},
+		} {
+			if selector.value == "" {
+				return nil, fmt.Errorf("specs needs a '%s' selector", selector.name)
+			}
+			if err := checkSelector(selector.value); err != nil {
+				return nil, fmt.Errorf("specs %s: %w", selector.name, err)
+			}
+		}
+		names = names[:0]
+		for name := range cfg.Specs.Fields {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			target, ok := productFields[name]
+			if !ok {
+				return nil, fmt.Errorf("specs: unknown field '%s'", name)
+			}
+			var labels []string
+			for _, label := range cfg.Specs.Fields[name] {
+				if label = strings.ToLower(strings.TrimSpace(label)); label != "" {
+					labels = append(labels, label)
+				}
+			}
+			s.specs = append(s.specs, configSpec{target: target, labels: labels})
+		}
+	}
+	return s, nil
+}
+
+// compileField checks a field rule and compiles its regex and post-processors
+func compileField(name string, rule FieldRule) (configField, error) {
+	field := configField{name: name, rule: rule}
+	var ok bool
+	if field.target, ok = productFields[name]; !ok {
+		return field, fmt.Errorf("unknown field")
+	}
+	if rule.Selector == "" && rule.Regex == "" && rule.Value == "" {
+		return field, errors.New("needs a selector, regex or value")
+	}
+	if rule.Selector != "" {
+		if err := checkSelector(rule.Selector); err != nil {
+			return field, err
+		}
+	}
+	if rule.Regex != "" {
+		re, err := regexp.Compile(rule.Regex)
+		if err != nil {
+			return field, fmt.Errorf("invalid regex: %w", err)
+		}
+		field.re = re
+	}
+	for _, spec := range rule.Post {
+		name, arg, _ := strings.Cut(spec, ":")
+		newPost, ok := postProcessors[name]
+		if !ok {
+			return field, fmt.Errorf("unknown post-processor '%s'", name)
+		}
+		post, err := newPost(arg)
+		if err != nil {
+			return field, fmt.Errorf("post-processor %s: %w", name, err)
+		}
+		field.post = append(field.post, post)
+	}
+	return field, nil
+}
+
+// checkSelector reports whether selector is a valid CSS selector; goquery
+// silently matches nothing for invalid ones
+func checkSelector(selector string) error {
+	if _, err := cascadia.Compile(selector); err != nil {
+		return fmt.Errorf("invalid selector: %w", err)
+	}
+	return nil
+}
+
+// parsePositiveDuration parses a Go duration that must be positive
+func parsePositiveDuration(s string) (time.Duration, error) {
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, err
+	}
+	if d <= 0 {
+		return 0, fmt.Errorf("'%s' must be positive", s)
+	}
+	return d, nil
+}
+
+// ID returns the site identifier of the definition
+func (s *ConfigScraper) ID() string {
+	return s.cfg.ID
+}
+
+// BaseURL returns the home page of the site
+func (s *ConfigScraper) BaseURL() string {
+	return s.cfg.BaseURL
+}
+
+// GetSiteName returns the site name
+func (s *ConfigScraper) GetSiteName() string {
+	return s.cfg.Name
+}
+
+// IsValidURL checks if the URL belongs to one of the definition's hosts
+func (s *ConfigScraper) IsValidURL(url string) bool {
+	for _, host := range s.cfg.Hosts {
+		if hostMatches(url, host) {
+			return true
+		}
+	}
+	return false
+}
+
+// overlaps reports whether s and other, registered as id, would both
+// accept some URL: other accepts one of the hosts of s, or s accepts one of
+// the hosts, or the base URL, of other. DetectScraper cannot tell such
+// scrapers apart.
+func (s *ConfigScraper) overlaps(id string, other Scraper) bool {
+	for _, host := range s.cfg.Hosts {
+		if other.IsValidURL("https://" + host + "/") {
+			return true
+		}
+	}
+	if o, ok := other.(*ConfigScraper); ok {
+		for _, host := range o.cfg.Hosts {
+			if s.IsValidURL("https://" + host + "/") {
+				return true
+			}
+		}
+		return false
+	}
+	base := getBaseURL(id)
+	return base != "" && s.IsValidURL(base)
+}
+
+// IsProductURL reports whether url is a product page according to the
+// definition's product_url pattern
+func (s *ConfigScraper) IsProductURL(url string) bool {
+	if s.productURL == nil {
+		return isSlugProductPath(url)
+	}
+	return s.productURL.MatchString(url)
+}
+
+// Scrape extracts product information from url
+func (s *ConfigScraper) Scrape(url string) (*Product, error) {
+	return s.ScrapeContext(context.Background(), url, ScrapeOptions{})
+}
+
+// ScrapeContext extracts product information from url, aborting the
+// request or closing the browser tab as soon as ctx is done
+func (s *ConfigScraper) ScrapeContext(ctx context.Context, url string, opts ScrapeOptions) (*Product, error) {
+	if !s.IsValidURL(url) {
+		return nil, fmt.Errorf("URL does not belong to %s: %s", s.cfg.Name, url)
+	}
+
+	ctx, cancel := opts.withTimeout(ctx, s.timeout)
+	defer cancel()
+
+	if s.pool != nil {
+		html, err := s.pool.Render(ctx, url, s.ready)
+		if err != nil {
+			return nil, err
+		}
+		return s.parse(url, html)
+	}
+
+	page, err := s.fetcher.Get(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+	return s.parse(url, string(page.Body))
+}
+
+// parse extracts product information from a page of the site
+func (s *ConfigScraper) parse(url string, html string) (*Product, error) {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(html))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse HTML: %w", err)
+	}
+
+	product := &Product{
+		URL:       url,
+		Site:      s.cfg.ID,
+		ScrapedAt: time.Now().UTC().Format(time.RFC3339),
+	}
+
+	// Structured data comes first; the rules below fill what it lacks
+	data := &StructuredData{}
+	if s.cfg.StructuredData == nil || *s.cfg.StructuredData {
+		data = ExtractStructuredData(doc)
+		data.apply(product)
+	}
+
+	for _, field := range s.fields {
+		target := field.target(product)
+		if *target == "" {
+			*target = field.extract(doc, html)
+		}
+	}
+
+	if s.cfg.Specs.Rows != "" {
+		doc.Find(s.cfg.Specs.Rows).Each(func(i int, row *goquery.Selection) {
+			label := collapseSpace(row.Find(s.cfg.Specs.Label).First().Text())
+			value := collapseSpace(row.Find(s.cfg.Specs.Value).First().Text())
+			if label == "" || value == "" {
+				return
+			}
+			product.addSpec(label, value)
+			label = strings.ToLower(label)
+			for _, spec := range s.specs {
+				target := spec.target(product)
+				if *target != "" {
+					continue
+				}
+				for _, fragment := range spec.labels {
+					if strings.Contains(label, fragment) {
+						*target = value
+						break
+					}
+				}
+			}
+		})
+	}
+
+	// If only the regular price is shown there is no discount
+	if product.CurrentPrice == "" && product.OriginalPrice != "" {
+		product.CurrentPrice = product.OriginalPrice
+		product.OriginalPrice = ""
+	}
+
+	// The OpenGraph title names any page, so it only counts on a page
+	// that has a price
+	if product.Name == "" && product.CurrentPrice != "" {
+		product.Name = data.Title
+	}
+	if product.Name == "" {
+		return nil, fmt.Errorf("no product found on page")
+	}
+
+	// Parse display prices into comparable values
+	product.fillPrices()
+
+	return product, nil
+}
+
+// extract returns the field's value on the page, or ""
+func (f *configField) extract(doc *goquery.Document, html string) string {
+	if f.rule.Value != "" {
+		return f.rule.Value
+	}
+	if f.rule.Selector == "" {
+		return f.clean(html)
+	}
+
+	var value string
+	doc.Find(f.rule.Selector).EachWithBreak(func(i int, s *goquery.Selection) bool {
+		raw := s.Text()
+		if f.rule.Attr != "" {
+			raw = s.AttrOr(f.rule.Attr, "")
+		}
+		value = f.clean(raw)
+		return value == ""
+	})
+	return value
+}
+
+// clean applies the field's regex and post-processors to raw and collapses
+// whitespace; a value the regex does not match is dropped
+func (f *configField) clean(raw string) string {
+	if f.re != nil {
+		match := f.re.FindStringSubmatch(raw)
+		switch {
+		case match == nil:
+			return ""
+		case len(match) > 1:
+			raw = match[1]
+		default:
+			raw = match[0]
+		}
+	}
+	for _, post := range f.post {
+		raw = post(raw)
+	}
+	return collapseSpace(raw)
+}
+
+// configExtensions are the file types ReloadConfigScrapers reads
+var configExtensions = map[string]bool{".yaml": true, ".yml": true, ".json": true}
+
+// LoadScraperConfig reads and compiles one definition file. JSON files are
+// read by the YAML parser, which accepts them as they are.
+func LoadScraperConfig(path string) (*ConfigScraper, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var cfg ScraperConfig
+	dec := yaml.NewDecoder(bytes.NewReader(raw))
+	dec.KnownFields(true)
+	if err := dec.Decode(&cfg); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	s, err := NewConfigScraper(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if err := s.checkFixtures(filepath.Dir(path)); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return s, nil
+}
+
+// checkFixtures scrapes the definition's fixtures, read relative to dir,
+// and compares the fields they expect
+func (s *ConfigScraper) checkFixtures(dir string) error {
+	for _, fixture := range s.cfg.Fixtures {
+		path := fixture.File
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(dir, path)
+		}
+		html, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("fixture %s: %w", fixture.File, err)
+		}
+
+		url := fixture.URL
+		if url == "" {
+			url = s.cfg.BaseURL
+		}
+		product, err := s.parse(url, string(html))
+		if err != nil {
+			return fmt.Errorf("fixture %s: %w", fixture.File, err)
+		}
+
+		names := make([]string, 0, len(fixture.Expect))
+		for name := range fixture.Expect {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		var mismatches []string
+		for _, name := range names {
+			if got := *productFields[name](product); got != fixture.Expect[name] {
+				mismatches = append(mismatches, fmt.Sprintf("%s is %q, want %q", name, got, fixture.Expect[name]))
+			}
+		}
+		if len(mismatches) > 0 {
+			return fmt.Errorf("fixture %s: %s", fixture.File, strings.Join(mismatches, "; "))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,271 @@
+package scrappers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+var (
+	// reloadMu serializes reloads so each one starts from the last
+	reloadMu sync.Mutex
+
+	// configScrapers are the registered definitions by file path; guarded
+	// by registryMu and only replaced while holding reloadMu
+	configScrapers = make(map[string]*ConfigScraper)
+
+	// replacedScrapers are the built-in scrapers a definition currently
+	// replaces, by identifier, kept to be restored when it goes away;
+	// guarded like configScrapers
+	replacedScrapers = make(map[string]Scraper)
+)
+
+// ReloadResult reports what a reload of the definitions changed
+type ReloadResult struct {
+	// Loaded are the sites now served from the files as they are
+	Loaded []string `json:"loaded"`
+
+	// Kept are the sites whose file no longer loads; their previous
+	// version is still served
+	Kept []string `json:"kept,omitempty"`
+
+	// Unchecked are the new sites whose definition has no fixtures, so
+	// nothing verified it before it was swapped in
+	Unchecked []string `json:"unchecked,omitempty"`
+
+	// Removed are the sites whose file is gone
+	Removed []string `json:"removed,omitempty"`
+
+	// Errors describe the files that were rejected
+	Errors []string `json:"errors,omitempty"`
+}
+
+// ReloadConfigScrapers loads the definitions in dir, checks them against
+// their fixtures and swaps them into the registry in one step. A definition
+// without fixtures is only loaded for a site not served yet, and listed as
+// unchecked; a changed one cannot replace the version being served, nor a
+// built-in scraper. A file that fails keeps serving its previous version; a
+// missing directory holds no definitions. Scrapes already running finish
+// with the version they started with.
+func ReloadConfigScrapers(dir string) (*ReloadResult, error) {
+	reloadMu.Lock()
+	defer reloadMu.Unlock()
+
+	entries, err := os.ReadDir(dir)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+
+	registryMu.RLock()
+	previous, builtins := configScrapers, builtinScrapers()
+	registryMu.RUnlock()
+
+	previousByID := make(map[string]*ConfigScraper, len(previous))
+	for _, s := range previous {
+		previousByID[s.ID()] = s
+	}
+
+	result := &ReloadResult{Loaded: []string{}}
+	next := make(map[string]*ConfigScraper)
+	paths := make(map[string]string)
+	replaced := make(map[string]string) // built-in identifier to definition path
+	for _, entry := range entries {
+		if entry.IsDir() || !configExtensions[strings.ToLower(filepath.Ext(entry.Name()))] {
+			continue
+		}
+		path := filepath.Join(dir, entry.Name())
+
+		s, err := LoadScraperConfig(path)
+		if err == nil && len(s.cfg.Fixtures) == 0 {
+			// Nothing can tell whether a change breaks a site being served
+			served := previousByID[s.ID()]
+			unchanged := served != nil && reflect.DeepEqual(served.cfg, s.cfg)
+			if !unchanged && (served != nil || s.cfg.Replaces != "") {
+				err = fmt.Errorf("%s: no fixtures to check the definition with before it replaces the version of '%s' being served", path, s.ID())
+			}
+		}
+		kept := false
+		if err != nil {
+			result.Errors = append(result.Errors, err.Error())
+			if s = previous[path]; s == nil {
+				continue
+			}
+			kept = true
+		}
+
+		if other, ok := paths[s.ID()]; ok {
+			result.Errors = append(result.Errors, fmt.Sprintf("%s: id '%s' is already defined in %s", path, s.ID(), other))
+			continue
+		}
+		if replaces := s.cfg.Replaces; replaces != "" {
+			if _, ok := builtins[replaces]; !ok {
+				result.Errors = append(result.Errors, fmt.Sprintf("%s: replaces '%s', which is not a built-in scraper", path, replaces))
+				continue
+			}
+			if other, ok := replaced[replaces]; ok {
+				result.Errors = append(result.Errors, fmt.Sprintf("%s: '%s' is already replaced by %s", path, replaces, other))
+				continue
+			}
+		}
+		if _, ok := builtins[s.ID()]; ok && s.ID() != s.cfg.Replaces {
+			result.Errors = append(result.Errors, fmt.Sprintf("%s: id '%s' belongs to a built-in scraper; set replaces to take it over", path, s.ID()))
+			continue
+		}
+
+		if owner := hostOwner(s, builtins, next); owner != "" {
+			result.Errors = append(result.Errors, fmt.Sprintf("%s: hosts %s are already served by '%s'", path, strings.Join(s.cfg.Hosts, ", "), owner))
+			continue
+		}
+
+		paths[s.ID()] = path
+		next[path] = s
+		if s.cfg.Replaces != "" {
+			replaced[s.cfg.Replaces] = path
+		}
+		if kept {
+			result.Kept = append(result.Kept, s.ID())
+		} else {
+			result.Loaded = append(result.Loaded, s.ID())
+			if len(s.cfg.Fixtures) == 0 {
+				result.Unchecked = append(result.Unchecked, s.ID())
+			}
+		}
+	}
+	for _, s := range previous {
+		if _, ok := paths[s.ID()]; !ok {
+			result.Removed = append(result.Removed, s.ID())
+		}
+	}
+	sort.Strings(result.Loaded)
+	sort.Strings(result.Unchecked)
+	sort.Strings(result.Kept)
+	sort.Strings(result.Removed)
+
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	builtins = builtinScrapers()
+	swapped := make(map[string]Scraper, len(builtins)+len(next))
+	nextReplaced := make(map[string]Scraper, len(replaced))
+	for id, s := range builtins {
+		if _, ok := replaced[id]; ok {
+			nextReplaced[id] = s
+		} else {
+			swapped[id] = s
+		}
+	}
+	for _, s := range next {
+		swapped[s.ID()] = s
+	}
+	scraperRegistry = swapped
+	configScrapers = next
+	replacedScrapers = nextReplaced
+
+	return result, nil
+}
+
+// builtinScrapers returns the scrapers registered in Go, including those a
+// definition replaces, by identifier; registryMu must be held
+func builtinScrapers() map[string]Scraper {
+	builtins := make(map[string]Scraper, len(scraperRegistry)+len(replacedScrapers))
+	for id, s := range scraperRegistry {
+		if _, isConfig := s.(*ConfigScraper); !isConfig {
+			builtins[id] = s
+		}
+	}
+	for id, s := range replacedScrapers {
+		builtins[id] = s
+	}
+	return builtins
+}
+
+// hostOwner returns the site already serving one of the hosts of s: a
+// built-in scraper other than the one s replaces, or a definition accepted
+// earlier in the reload, that s overlaps
+func hostOwner(s *ConfigScraper, builtins map[string]Scraper, accepted map[string]*ConfigScraper) string {
+	ids := make([]string, 0, len(builtins))
+	for id := range builtins {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		if id != s.cfg.Replaces && s.overlaps(id, builtins[id]) {
+			return id
+		}
+	}
+	for _, other := range accepted {
+		if s.overlaps(other.ID(), other) {
+			return other.ID()
+		}
+	}
+	return ""
+}
+
+// WatchScraperConfigs reloads the definitions in dir whenever a file in it,
+// or in one of its existing subdirectories such as saved fixtures,
+// changes. Bursts of events, like an editor saving, are folded into one
+// reload once debounce has passed without another. Every reload, or watch
+// error, is passed to report. The watch stops when ctx is done.
+func WatchScraperConfigs(ctx context.Context, dir string, debounce time.Duration, report func(*ReloadResult, error)) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	if err := watcher.Add(dir); err != nil {
+		watcher.Close()
+		return err
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		watcher.Close()
+		return err
+	}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			if err := watcher.Add(filepath.Join(dir, entry.Name())); err != nil {
+				watcher.Close()
+				return err
+			}
+		}
+	}
+
+	go func() {
+		defer watcher.Close()
+
+		timer := time.NewTimer(debounce)
+		timer.Stop()
+		defer timer.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				// Permission and timestamp changes leave the content as it is
+				if event.Op == fsnotify.Chmod {
+					continue
+				}
+				timer.Reset(debounce)
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				report(nil, err)
+			case <-timer.C:
+				report(ReloadConfigScrapers(dir))
+			}
+		}
+	}()
+	return nil
+}
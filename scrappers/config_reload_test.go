@@ -0,0 +1,230 @@
+package scrappers
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeDefinition writes a definition file into dir
+func writeDefinition(t *testing.T, dir, name, content string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// resetConfigScrapers unregisters the definitions a test loaded
+func resetConfigScrapers(t *testing.T) {
+	t.Cleanup(func() {
+		if _, err := ReloadConfigScrapers(t.TempDir()); err != nil {
+			t.Fatal(err)
+		}
+	})
+}
+
+func TestReloadConfigScrapers(t *testing.T) {
+	resetConfigScrapers(t)
+	dir := t.TempDir()
+
+	writeDefinition(t, dir, "shop.yaml", "id: shop\nhosts: [shop.az]\nfields:\n  name: h1\n")
+	writeDefinition(t, dir, "page.html", "<html><body><h1 class='title'>Phone</h1></body></html>")
+	writeDefinition(t, dir, "checked.yaml", `id: checked
+hosts: [checked.az]
+fields:
+  name: h1.title
+fixtures:
+  - file: page.html
+    expect: {name: Phone}
+`)
+	writeDefinition(t, dir, "builtin.json", `{"id": "optimal", "hosts": ["optimal.az"], "fields": {"name": "h1"}}`)
+	writeDefinition(t, dir, "zdup.yml", "id: shop\nhosts: [other.az]\nfields:\n  name: h1\n")
+	writeDefinition(t, dir, "kontakt2.yaml", "id: kontakt2\nhosts: [www.kontakt.az]\nfields:\n  name: h1\n")
+	writeDefinition(t, dir, "shopmobile.yaml", "id: shopmobile\nhosts: [m.shop.az]\nfields:\n  name: h1\n")
+	writeDefinition(t, dir, "notes.txt", "not a definition")
+
+	result, err := ReloadConfigScrapers(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(result.Loaded, []string{"checked", "shop"}) || len(result.Errors) != 4 {
+		t.Fatalf("first load: %+v", result)
+	}
+	if !reflect.DeepEqual(result.Unchecked, []string{"shop"}) {
+		t.Errorf("Unchecked = %q, want the definition without fixtures", result.Unchecked)
+	}
+	for _, want := range []string{"built-in", "already defined", "served by 'kontakt'", "served by 'shop'"} {
+		if !strings.Contains(strings.Join(result.Errors, "\n"), want) {
+			t.Errorf("errors %q do not mention %q", result.Errors, want)
+		}
+	}
+	if s, _ := GetScraper("optimal"); s != nil {
+		if _, isConfig := s.(*ConfigScraper); isConfig {
+			t.Error("a definition replaced the built-in optimal scraper")
+		}
+	}
+	shop, err := GetScraper("shop")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id, _, err := DetectScraper("https://shop.az/phone"); err != nil || id != "shop" {
+		t.Errorf("DetectScraper = %q, %v", id, err)
+	}
+
+	// A definition that no longer loads, or fails its fixture, keeps the
+	// previous version
+	writeDefinition(t, dir, "shop.yaml", "id: shop\nhosts: [shop.az]\nfields:\n  name: h1[\n")
+	writeDefinition(t, dir, "checked.yaml", `id: checked
+hosts: [checked.az]
+fields:
+  name: h1.heading
+fixtures:
+  - file: page.html
+    expect: {name: Phone}
+`)
+	os.Remove(filepath.Join(dir, "zdup.yml"))
+	os.Remove(filepath.Join(dir, "builtin.json"))
+	os.Remove(filepath.Join(dir, "kontakt2.yaml"))
+	os.Remove(filepath.Join(dir, "shopmobile.yaml"))
+
+	result, err = ReloadConfigScrapers(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(result.Kept, []string{"checked", "shop"}) || len(result.Loaded) != 0 || len(result.Unchecked) != 0 || len(result.Errors) != 2 {
+		t.Fatalf("broken reload: %+v", result)
+	}
+	if !strings.Contains(strings.Join(result.Errors, "\n"), "no product found") {
+		t.Errorf("errors %q do not report the failed fixture", result.Errors)
+	}
+	if s, _ := GetScraper("shop"); s != shop {
+		t.Error("shop was not kept at its previous version")
+	}
+
+	// A change without fixtures to check it cannot replace a served version
+	writeDefinition(t, dir, "shop.yaml", "id: shop\nname: Shop\nhosts: [shop.az]\nfields:\n  name: h1\n")
+
+	result, err = ReloadConfigScrapers(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(result.Kept, []string{"checked", "shop"}) || !strings.Contains(strings.Join(result.Errors, "\n"), "no fixtures") {
+		t.Fatalf("unchecked change: %+v", result)
+	}
+	if s, _ := GetScraper("shop"); s != shop {
+		t.Error("shop was replaced by a change nothing checked")
+	}
+
+	// Fixed and deleted files
+	writeDefinition(t, dir, "shop.yaml", `id: shop
+name: Shop
+hosts: [shop.az]
+fields:
+  name: h1
+fixtures:
+  - file: page.html
+    expect: {name: Phone}
+`)
+	os.Remove(filepath.Join(dir, "checked.yaml"))
+
+	result, err = ReloadConfigScrapers(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(result.Loaded, []string{"shop"}) || !reflect.DeepEqual(result.Removed, []string{"checked"}) || len(result.Errors) != 0 {
+		t.Fatalf("fixed reload: %+v", result)
+	}
+	if s, _ := GetScraper("shop"); s == shop || s.GetSiteName() != "Shop" {
+		t.Error("shop was not replaced by its new version")
+	}
+	if _, err := GetScraper("checked"); err == nil {
+		t.Error("removed definition is still registered")
+	}
+}
+
+func TestReloadReplacesBuiltin(t *testing.T) {
+	resetConfigScrapers(t)
+	dir := t.TempDir()
+	builtin, err := GetScraper("kontakt")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	writeDefinition(t, dir, "page.html", "<html><body><h1 class='title'>Phone</h1></body></html>")
+	writeDefinition(t, dir, "kontakt.yaml", `id: kontakt
+replaces: kontakt
+hosts: [kontakt.az]
+fields:
+  name: h1.title
+fixtures:
+  - file: page.html
+    expect: {name: Phone}
+`)
+	// Without fixtures, or naming no built-in, nothing is replaced
+	writeDefinition(t, dir, "optimal.yaml", "id: optimal\nreplaces: optimal\nhosts: [optimal.az]\nfields:\n  name: h1\n")
+	writeDefinition(t, dir, "tempo.yaml", "id: tempo\nreplaces: tempo\nhosts: [tempo.az]\nfields:\n  name: h1\n")
+
+	result, err := ReloadConfigScrapers(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(result.Loaded, []string{"kontakt"}) || len(result.Errors) != 2 {
+		t.Fatalf("reload: %+v", result)
+	}
+	if s, _ := GetScraper("kontakt"); s == builtin {
+		t.Fatal("the built-in kontakt scraper was not replaced")
+	}
+	if id, s, err := DetectScraper("https://kontakt.az/phone"); err != nil || id != "kontakt" || s == builtin {
+		t.Errorf("DetectScraper = %q, %v", id, err)
+	}
+	if _, isConfig := registeredScrapers()["optimal"].(*ConfigScraper); isConfig {
+		t.Error("a definition without fixtures replaced the built-in optimal scraper")
+	}
+
+	// Removing the definition brings the built-in back
+	os.Remove(filepath.Join(dir, "kontakt.yaml"))
+	if _, err := ReloadConfigScrapers(dir); err != nil {
+		t.Fatal(err)
+	}
+	if s, _ := GetScraper("kontakt"); s != builtin {
+		t.Error("the built-in kontakt scraper was not restored")
+	}
+}
+
+func TestWatchScraperConfigs(t *testing.T) {
+	resetConfigScrapers(t)
+	dir := t.TempDir()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	reloads := make(chan *ReloadResult, 10)
+	err := WatchScraperConfigs(ctx, dir, 50*time.Millisecond, func(result *ReloadResult, err error) {
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		reloads <- result
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	writeDefinition(t, dir, "watched.yaml", "id: watched\nhosts: [watched.az]\nfields:\n  name: h1\n")
+
+	select {
+	case result := <-reloads:
+		if !reflect.DeepEqual(result.Loaded, []string{"watched"}) {
+			t.Errorf("reload: %+v", result)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no reload after the definition was written")
+	}
+	if _, err := GetScraper("watched"); err != nil {
+		t.Error(err)
+	}
+}
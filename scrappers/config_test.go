@@ -0,0 +1,188 @@
+package scrappers
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestConfigScraperMatchesGo(t *testing.T) {
+	tests := []struct {
+		definition string
+		native     interface {
+			parse(url, html string) (*Product, error)
+		}
+		fixtures []string
+	}{
+		{"optimal.yaml", NewOptimalScraper(), []string{"optimal_product.html", "optimal_no_discount.html"}},
+		{"bakuelectronics.json", NewBakuElectronicsScraper(), []string{"bakuelectronics_product.html", "bakuelectronics_no_discount.html"}},
+	}
+
+	for _, tt := range tests {
+		s, err := LoadScraperConfig(filepath.Join("testdata", "config", tt.definition))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, fixture := range tt.fixtures {
+			t.Run(fixture, func(t *testing.T) {
+				html, err := os.ReadFile(filepath.Join("testdata", fixture))
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				url := "https://" + s.cfg.Hosts[0] + "/" + fixture
+				want, err := tt.native.parse(url, string(html))
+				if err != nil {
+					t.Fatal(err)
+				}
+				got, err := s.parse(url, string(html))
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				if got.Site != s.ID() {
+					t.Errorf("site = %s, want %s", got.Site, s.ID())
+				}
+				if got.CurrentPriceValue == nil || *got.CurrentPriceValue != *want.CurrentPriceValue || got.DiscountPercent != want.DiscountPercent {
+					t.Errorf("price = %+v (%v%%), want %+v (%v%%)", got.CurrentPriceValue, got.DiscountPercent, want.CurrentPriceValue, want.DiscountPercent)
+				}
+
+				for _, p := range []*Product{got, want} {
+					p.Site, p.ScrapedAt = "", ""
+					p.CurrentPriceValue, p.OriginalPriceValue, p.DiscountValue = nil, nil, nil
+				}
+				if !reflect.DeepEqual(*got, *want) {
+					t.Errorf("got  %+v\nwant %+v", *got, *want)
+				}
+			})
+		}
+	}
+}
+
+func TestConfigScraperURLs(t *testing.T) {
+	s, err := LoadScraperConfig(filepath.Join("testdata", "config", "bakuelectronics.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !s.IsValidURL("https://www.bakuelectronics.az/mehsul/x") || s.IsValidURL("https://evil.com/?bakuelectronics.az") {
+		t.Error("IsValidURL does not follow hosts")
+	}
+	if !s.IsProductURL("https://www.bakuelectronics.az/mehsul/x") || s.IsProductURL("https://www.bakuelectronics.az/catalog/x") {
+		t.Error("IsProductURL does not follow product_url")
+	}
+	if s.GetSiteName() != "Baku Electronics" || s.BaseURL() != "https://bakuelectronics.az" {
+		t.Errorf("name %q, base URL %q", s.GetSiteName(), s.BaseURL())
+	}
+}
+
+func TestConfigScraperOverlaps(t *testing.T) {
+	newScraper := func(id string, hosts ...string) *ConfigScraper {
+		s, err := NewConfigScraper(ScraperConfig{ID: id, Hosts: hosts, Fields: map[string]FieldRule{"name": {Selector: "h1"}}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return s
+	}
+
+	shop := newScraper("shop", "shop.az")
+	tests := []struct {
+		id    string
+		other Scraper
+		want  bool
+	}{
+		{"bakuelectronics", NewBakuElectronicsScraper(), false},
+		{"kontakt", NewKontaktScraper(), false},
+		{"mobile", newScraper("mobile", "m.shop.az"), true},
+		{"all", newScraper("all", "az"), true},
+		{"other", newScraper("other", "other.az"), false},
+	}
+	for _, tt := range tests {
+		if got := shop.overlaps(tt.id, tt.other); got != tt.want {
+			t.Errorf("overlaps(%s) = %v, want %v", tt.id, got, tt.want)
+		}
+	}
+	if !newScraper("kontakt2", "www.kontakt.az").overlaps("kontakt", NewKontaktScraper()) {
+		t.Error("a definition for a built-in site's host does not overlap it")
+	}
+}
+
+func TestConfigFieldRules(t *testing.T) {
+	html := `<html><body>
+		<h1>  Sony
+		PlayStation 5 </h1>
+		<div class="code">Kod: 93528</div>
+		<div class="brand">Brend : Sony
+		Japan</div>
+		<span class="price" data-value="1199.00"></span>
+		<span class="price" data-value="1099.00"></span>
+	</body></html>`
+
+	s, err := NewConfigScraper(ScraperConfig{
+		ID:    "example",
+		Hosts: []string{"example.az"},
+		Fields: map[string]FieldRule{
+			"name":          {Selector: "h1"},
+			"sku":           {Selector: "div.code", Post: []string{"digits"}},
+			"brand":         {Selector: "div.brand", Post: []string{"after::", "first_line", "upper"}},
+			"current_price": {Selector: "span.price", Attr: "data-value", Post: []string{"suffix: AZN"}},
+			"availability":  {Regex: `"stock":"([^"]+)"`},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := s.parse("https://example.az/ps5", strings.Replace(html, "</body>", `<script>{"stock":"Stokda var"}</script></body>`, 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Name != "Sony PlayStation 5" || got.SKU != "93528" || got.Brand != "SONY" || got.Availability != "Stokda var" {
+		t.Errorf("got %+v", *got)
+	}
+	if got.CurrentPrice != "1199.00 AZN" || got.CurrentPriceValue == nil || got.CurrentPriceValue.Minor != 119900 {
+		t.Errorf("price %q = %+v", got.CurrentPrice, got.CurrentPriceValue)
+	}
+
+	if _, err := s.parse("https://example.az/", "<html><body><p>Home</p></body></html>"); err == nil {
+		t.Error("page without a name parsed")
+	}
+}
+
+func TestNewConfigScraperErrors(t *testing.T) {
+	valid := func() ScraperConfig {
+		return ScraperConfig{ID: "example", Hosts: []string{"example.az"}, Fields: map[string]FieldRule{"name": {Selector: "h1"}}}
+	}
+
+	tests := []struct {
+		name   string
+		modify func(*ScraperConfig)
+		want   string
+	}{
+		{"bad id", func(c *ScraperConfig) { c.ID = "Example Site" }, "invalid id"},
+		{"no hosts", func(c *ScraperConfig) { c.Hosts = nil }, "host"},
+		{"url host", func(c *ScraperConfig) { c.Hosts = []string{"https://example.az"} }, "invalid host"},
+		{"fetch mode", func(c *ScraperConfig) { c.Fetch = "curl" }, "unknown fetch mode"},
+		{"no name", func(c *ScraperConfig) { c.Fields = map[string]FieldRule{"sku": {Selector: "b"}} }, "'name'"},
+		{"unknown field", func(c *ScraperConfig) { c.Fields["colour"] = FieldRule{Selector: "b"} }, "unknown field"},
+		{"bad selector", func(c *ScraperConfig) { c.Fields["sku"] = FieldRule{Selector: "div[["} }, "invalid selector"},
+		{"bad regex", func(c *ScraperConfig) { c.Fields["sku"] = FieldRule{Regex: "("} }, "invalid regex"},
+		{"unknown post", func(c *ScraperConfig) { c.Fields["sku"] = FieldRule{Selector: "b", Post: []string{"reverse"}} }, "unknown post-processor"},
+		{"post argument", func(c *ScraperConfig) { c.Fields["sku"] = FieldRule{Selector: "b", Post: []string{"after"}} }, "needs an argument"},
+		{"specs", func(c *ScraperConfig) { c.Specs.Fields = map[string][]string{"ram": {"ram"}} }, "specs needs"},
+		{"timeout", func(c *ScraperConfig) { c.Timeout = "-1s" }, "invalid timeout"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := valid()
+			tt.modify(&cfg)
+			_, err := NewConfigScraper(cfg)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("err = %v, want it to mention %q", err, tt.want)
+			}
+		})
+	}
+}
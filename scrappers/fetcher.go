@@ -0,0 +1,314 @@
+package scrappers
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/andybalholm/brotli"
+	"golang.org/x/net/html/charset"
+)
+
+// ErrBodyTooLarge is returned when a response exceeds FetcherConfig.MaxBodySize
+var ErrBodyTooLarge = errors.New("response body too large")
+
+// HTTPError is returned for responses with a non-2xx status after retries
+type HTTPError struct {
+	StatusCode int
+	URL        string
+}
+
+func (e *HTTPError) Error() string {
+	return fmt.Sprintf("bad status code: %d", e.StatusCode)
+}
+
+// FetcherConfig controls timeouts, retries and limits of a Fetcher
+type FetcherConfig struct {
+	// Timeout bounds a single attempt
+	Timeout time.Duration
+
+	// MaxRetries is the number of attempts after the first one
+	MaxRetries int
+
+	// BaseBackoff and MaxBackoff bound the exponential backoff between attempts
+	BaseBackoff time.Duration
+	MaxBackoff  time.Duration
+
+	// MaxRetryAfter caps how long a Retry-After header may make us wait
+	MaxRetryAfter time.Duration
+
+	// MaxBodySize is the largest response body accepted, in bytes
+	MaxBodySize int64
+
+	// Headers are sent with every request
+	Headers map[string]string
+
+	// Limiter, when set, applies per-host politeness to every attempt
+	Limiter *HostLimiter
+}
+
+// DefaultFetcherConfig returns the settings used by static-HTML scrapers
+func DefaultFetcherConfig() FetcherConfig {
+	return FetcherConfig{
+		Timeout:       20 * time.Second,
+		MaxRetries:    3,
+		BaseBackoff:   500 * time.Millisecond,
+		MaxBackoff:    10 * time.Second,
+		MaxRetryAfter: 30 * time.Second,
+		MaxBodySize:   10 << 20,
+		Headers: map[string]string{
+			// Mimic a real browser
+			"User-Agent":                "Mozilla/5.0 (X11; Linux x86_64; rv:109.0) Gecko/20100101 Firefox/119.0",
+			"Accept":                    "text/html,application/xhtml+xml,application/xml;q=0.9,image/avif,image/webp,*/*;q=0.8",
+			"Accept-Language":           "az,en-US;q=0.7,en;q=0.3",
+			"Accept-Encoding":           "gzip, deflate, br",
+			"DNT":                       "1",
+			"Upgrade-Insecure-Requests": "1",
+		},
+	}
+}
+
+// FetchResult is a successfully fetched page
+type FetchResult struct {
+	// URL is the final URL after redirects
+	URL        string
+	StatusCode int
+	Header     http.Header
+
+	// Body is the decompressed response converted to UTF-8
+	Body []byte
+}
+
+// Fetcher downloads pages over plain HTTP with retries, decompression and
+// charset conversion. It is safe for concurrent use.
+type Fetcher struct {
+	cfg    FetcherConfig
+	client *http.Client
+}
+
+// NewFetcher creates a Fetcher with its own connection pool
+func NewFetcher(cfg FetcherConfig) *Fetcher {
+	// Compression is negotiated and decoded by the fetcher itself so that
+	// brotli is supported too
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DisableCompression = true
+
+	return &Fetcher{
+		cfg:    cfg,
+		client: &http.Client{Transport: transport},
+	}
+}
+
+var (
+	sharedFetcher     *Fetcher
+	sharedFetcherOnce sync.Once
+)
+
+// SharedFetcher returns the process-wide fetcher used by static-HTML scrapers
+func SharedFetcher() *Fetcher {
+	sharedFetcherOnce.Do(func() {
+		cfg := DefaultFetcherConfig()
+		cfg.Limiter = SharedHostLimiter()
+		sharedFetcher = NewFetcher(cfg)
+	})
+	return sharedFetcher
+}
+
+// Get fetches url, retrying on network errors, 429 and 5xx responses
+func (f *Fetcher) Get(ctx context.Context, url string) (*FetchResult, error) {
+	// Malformed URLs are not worth retrying
+	if _, err := http.NewRequest("GET", url, nil); err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	var lastErr error
+	for attempt := 0; attempt <= f.cfg.MaxRetries; attempt++ {
+		if attempt > 0 && os.Getenv("DEBUG") == "1" {
+			fmt.Printf("DEBUG: retrying %s (attempt %d): %v\n", url, attempt+1, lastErr)
+		}
+
+		result, retryAfter, err := f.attempt(ctx, url)
+		if err == nil {
+			return result, nil
+		}
+		lastErr = err
+
+		if ctx.Err() != nil || !retryable(err) || attempt == f.cfg.MaxRetries {
+			break
+		}
+
+		wait := f.backoff(attempt)
+		if retryAfter > 0 {
+			if retryAfter > f.cfg.MaxRetryAfter {
+				return nil, fmt.Errorf("server asked to retry after %v: %w", retryAfter, err)
+			}
+			wait = retryAfter
+		}
+		if err := sleepCtx(ctx, wait); err != nil {
+			return nil, err
+		}
+	}
+	return nil, lastErr
+}
+
+// attempt performs a single request and returns the server's Retry-After
+// delay, if any
+func (f *Fetcher) attempt(ctx context.Context, url string) (*FetchResult, time.Duration, error) {
+	if f.cfg.Limiter != nil {
+		release, err := f.cfg.Limiter.Acquire(ctx, url)
+		if err != nil {
+			return nil, 0, err
+		}
+		defer release()
+	}
+
+	if f.cfg.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, f.cfg.Timeout)
+		defer cancel()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to create request: %w", err)
+	}
+	for name, value := range f.cfg.Headers {
+		req.Header.Set(name, value)
+	}
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to fetch URL: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		// Drain a little so the connection can be reused
+		io.CopyN(io.Discard, resp.Body, 64<<10)
+		return nil, parseRetryAfter(resp.Header.Get("Retry-After")), &HTTPError{StatusCode: resp.StatusCode, URL: url}
+	}
+
+	body, err := f.readBody(resp)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return &FetchResult{
+		URL:        resp.Request.URL.String(),
+		StatusCode: resp.StatusCode,
+		Header:     resp.Header,
+		Body:       body,
+	}, 0, nil
+}
+
+// readBody decompresses and converts the response body to UTF-8, enforcing
+// the size limit on the decompressed data
+func (f *Fetcher) readBody(resp *http.Response) ([]byte, error) {
+	var reader io.Reader = resp.Body
+	switch strings.ToLower(strings.TrimSpace(resp.Header.Get("Content-Encoding"))) {
+	case "gzip", "x-gzip":
+		gz, err := gzip.NewReader(resp.Body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode gzip body: %w", err)
+		}
+		defer gz.Close()
+		reader = gz
+	case "deflate":
+		zr, err := zlib.NewReader(resp.Body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode deflate body: %w", err)
+		}
+		defer zr.Close()
+		reader = zr
+	case "br":
+		reader = brotli.NewReader(resp.Body)
+	}
+
+	if f.cfg.MaxBodySize > 0 {
+		reader = io.LimitReader(reader, f.cfg.MaxBodySize+1)
+	}
+	raw, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+	if f.cfg.MaxBodySize > 0 && int64(len(raw)) > f.cfg.MaxBodySize {
+		return nil, fmt.Errorf("%w: more than %d bytes", ErrBodyTooLarge, f.cfg.MaxBodySize)
+	}
+
+	// Binary bodies such as gzipped sitemaps are returned untouched
+	contentType := resp.Header.Get("Content-Type")
+	if !isTextContent(contentType) {
+		return raw, nil
+	}
+
+	// Detect the charset from the Content-Type header or <meta> tags
+	utf8Reader, err := charset.NewReader(bytes.NewReader(raw), contentType)
+	if err != nil {
+		return raw, nil
+	}
+	body, err := io.ReadAll(utf8Reader)
+	if err != nil {
+		return raw, nil
+	}
+	return body, nil
+}
+
+// isTextContent reports whether a Content-Type denotes text that may need
+// charset conversion; a missing type is assumed to be HTML
+func isTextContent(contentType string) bool {
+	mediaType := strings.ToLower(strings.TrimSpace(strings.Split(contentType, ";")[0]))
+	return mediaType == "" || strings.HasPrefix(mediaType, "text/") ||
+		strings.HasSuffix(mediaType, "+xml") || mediaType == "application/xml" ||
+		mediaType == "application/json"
+}
+
+// backoff returns a randomized exponential delay for the given attempt
+func (f *Fetcher) backoff(attempt int) time.Duration {
+	d := f.cfg.BaseBackoff << attempt
+	if d <= 0 || d > f.cfg.MaxBackoff {
+		d = f.cfg.MaxBackoff
+	}
+	if d <= 0 {
+		return 0
+	}
+	// Full jitter spreads out retries from concurrent requests
+	return time.Duration(rand.Int63n(int64(d)) + 1)
+}
+
+// retryable reports whether err is worth another attempt
+func retryable(err error) bool {
+	var httpErr *HTTPError
+	if errors.As(err, &httpErr) {
+		return httpErr.StatusCode == http.StatusTooManyRequests || httpErr.StatusCode >= 500
+	}
+	// A later attempt would wait for the same limiter
+	return !errors.Is(err, ErrBodyTooLarge) && !errors.Is(err, ErrDisallowedByRobots) && !errors.Is(err, ErrRateLimitWait)
+}
+
+// parseRetryAfter understands both the delay-seconds and HTTP-date forms
+func parseRetryAfter(value string) time.Duration {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if t, err := http.ParseTime(value); err == nil {
+		if d := time.Until(t); d > 0 {
+			return d
+		}
+	}
+	return 0
+}
@@ -0,0 +1,146 @@
+package scrappers
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// testFetcher returns a fetcher with short backoffs for tests
+func testFetcher() *Fetcher {
+	cfg := DefaultFetcherConfig()
+	cfg.BaseBackoff = time.Millisecond
+	cfg.MaxBackoff = 5 * time.Millisecond
+	cfg.MaxRetryAfter = 2 * time.Second
+	return NewFetcher(cfg)
+}
+
+func TestFetcherRetriesServerErrors(t *testing.T) {
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte("<html>ok</html>"))
+	}))
+	defer srv.Close()
+
+	page, err := testFetcher().Get(context.Background(), srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(page.Body) != "<html>ok</html>" || calls != 3 {
+		t.Errorf("got body %q after %d calls", page.Body, calls)
+	}
+}
+
+func TestFetcherDoesNotRetryClientErrors(t *testing.T) {
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer srv.Close()
+
+	_, err := testFetcher().Get(context.Background(), srv.URL)
+	var httpErr *HTTPError
+	if !errors.As(err, &httpErr) || httpErr.StatusCode != http.StatusNotFound {
+		t.Fatalf("expected 404 HTTPError, got %v", err)
+	}
+	if calls != 1 {
+		t.Errorf("expected a single attempt, got %d", calls)
+	}
+}
+
+func TestFetcherDoesNotRetryRateLimitPastDeadline(t *testing.T) {
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	cfg := testFetcher().cfg
+	cfg.MaxRetries = 1000
+	cfg.BaseBackoff, cfg.MaxBackoff = 10*time.Millisecond, 10*time.Millisecond
+	cfg.Limiter = NewHostLimiter(map[string]SiteLimits{"": {RPS: 0.1, Burst: 1}})
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	// The second attempt would have to wait 10s for the limiter
+	_, err := NewFetcher(cfg).Get(ctx, srv.URL)
+	if !errors.Is(err, ErrRateLimitWait) {
+		t.Fatalf("expected ErrRateLimitWait, got %v", err)
+	}
+	if ctx.Err() != nil || calls != 1 {
+		t.Errorf("kept retrying: %d calls, context %v", calls, ctx.Err())
+	}
+}
+
+func TestFetcherHonorsRetryAfter(t *testing.T) {
+	var first time.Time
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			first = time.Now()
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		if time.Since(first) < time.Second {
+			t.Errorf("retried after %v, before Retry-After elapsed", time.Since(first))
+		}
+		w.Write([]byte("ok"))
+	}))
+	defer srv.Close()
+
+	if _, err := testFetcher().Get(context.Background(), srv.URL); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFetcherDecodesGzipAndCharset(t *testing.T) {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	gz.Write([]byte("<html>\xcf\xf0\xe8\xe2\xe5\xf2</html>")) // "Привет" in windows-1251
+	gz.Close()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.Contains(r.Header.Get("Accept-Encoding"), "br") {
+			t.Errorf("expected brotli to be advertised, got %q", r.Header.Get("Accept-Encoding"))
+		}
+		w.Header().Set("Content-Type", "text/html; charset=windows-1251")
+		w.Header().Set("Content-Encoding", "gzip")
+		w.Write(buf.Bytes())
+	}))
+	defer srv.Close()
+
+	page, err := testFetcher().Get(context.Background(), srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(page.Body) != "<html>Привет</html>" {
+		t.Errorf("got body %q", page.Body)
+	}
+}
+
+func TestFetcherMaxBodySize(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(bytes.Repeat([]byte("a"), 2048))
+	}))
+	defer srv.Close()
+
+	f := testFetcher()
+	f.cfg.MaxBodySize = 1024
+	if _, err := f.Get(context.Background(), srv.URL); !errors.Is(err, ErrBodyTooLarge) {
+		t.Fatalf("expected ErrBodyTooLarge, got %v", err)
+	}
+}
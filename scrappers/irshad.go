@@ -1,9 +1,10 @@
 package scrappers
 
 import (
+	"bytes"
+	"context"
 	"fmt"
-	"io"
-	"net/http"
+	"net/url"
 	"regexp"
 	"sort"
 	"strconv"
@@ -13,12 +14,31 @@ import (
 	"github.com/PuerkitoBio/goquery"
 )
 
+// irshadDefaultTimeout bounds an irshad.az scrape when the caller sets no timeout
+const irshadDefaultTimeout = 30 * time.Second
+
+// irshadListing locates product cards on irshad.az search and category pages
+var irshadListing = listingSelectors{
+	Card:          "div.product, div.product-item",
+	Link:          "a.product__name, a.product-item__name, a[href]",
+	Name:          ".product__name, .product-item__name",
+	Price:         ".product__price__current, .product__price--new, .product-item__price .new",
+	OriginalPrice: ".product__price__old, .product__price--old, .product-item__price .old",
+	Image:         "img",
+}
+
+// irshadPagination matches the next-page link of irshad.az listings
+const irshadPagination = ".pagination a.next, .pagination li.active + li a"
+
 // IrshadScraper implements the Scraper interface for irshad.az
-type IrshadScraper struct{}
+type IrshadScraper struct {
+	fetcher *Fetcher
+}
 
-// NewIrshadScraper creates a new instance of IrshadScraper
+// NewIrshadScraper creates a new instance of IrshadScraper backed by the
+// shared fetcher
 func NewIrshadScraper() *IrshadScraper {
-	return &IrshadScraper{}
+	return &IrshadScraper{fetcher: SharedFetcher()}
 }
 
 // GetSiteName returns the site name
@@ -28,54 +48,53 @@ func (i *IrshadScraper) GetSiteName() string {
 
 // IsValidURL checks if the URL belongs to irshad.az
 func (i *IrshadScraper) IsValidURL(url string) bool {
-	return strings.Contains(strings.ToLower(url), "irshad.az")
+	return hostMatches(url, "irshad.az")
+}
+
+// IsProductURL reports whether url is a product page under /mehsullar/
+func (i *IrshadScraper) IsProductURL(url string) bool {
+	return hasPathPrefix(url, "/az/mehsullar/", "/en/mehsullar/", "/ru/mehsullar/", "/mehsullar/")
 }
 
 // Scrape extracts product information from irshad.az URL
 func (i *IrshadScraper) Scrape(url string) (*Product, error) {
+	return i.ScrapeContext(context.Background(), url, ScrapeOptions{})
+}
+
+// ScrapeContext extracts product information from irshad.az URL, aborting
+// the request as soon as ctx is done
+func (i *IrshadScraper) ScrapeContext(ctx context.Context, url string, opts ScrapeOptions) (*Product, error) {
 	if !i.IsValidURL(url) {
 		return nil, fmt.Errorf("URL does not belong to irshad.az: %s", url)
 	}
 
-	product := &Product{
-		URL:       url,
-		Site:      "irshad",
-		ScrapedAt: time.Now().UTC().Format(time.RFC3339),
-	}
+	ctx, cancel := opts.withTimeout(ctx, irshadDefaultTimeout)
+	defer cancel()
 
-	client := &http.Client{}
-	req, err := http.NewRequest("GET", url, nil)
+	page, err := i.fetcher.Get(ctx, url)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
+		return nil, err
 	}
 
-	// Set headers to mimic a real browser
-	req.Header.Set("User-Agent", "Mozilla/5.0 (X11; Linux x86_64; rv:109.0) Gecko/20100101 Firefox/119.0")
-	req.Header.Set("Accept", "text/html,application/xhtml+xml,application/xml;q=0.9,image/avif,image/webp,*/*;q=0.8")
-	req.Header.Set("Accept-Language", "az,en-US;q=0.7,en;q=0.3")
-	req.Header.Set("DNT", "1")
-	req.Header.Set("Connection", "keep-alive")
-	req.Header.Set("Upgrade-Insecure-Requests", "1")
+	return i.parse(url, string(page.Body))
+}
 
-	resp, err := client.Do(req)
+// parse extracts product information from an irshad.az product page
+func (i *IrshadScraper) parse(url string, htmlContent string) (*Product, error) {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(htmlContent))
 	if err != nil {
-		return nil, fmt.Errorf("failed to fetch URL: %w", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != 200 {
-		return nil, fmt.Errorf("bad status code: %d", resp.StatusCode)
+		return nil, fmt.Errorf("failed to parse HTML: %w", err)
 	}
 
-	bodyBytes, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read response body: %w", err)
+	product := &Product{
+		URL:       url,
+		Site:      "irshad",
+		ScrapedAt: time.Now().UTC().Format(time.RFC3339),
 	}
 
-	doc, err := goquery.NewDocumentFromReader(strings.NewReader(string(bodyBytes)))
-	if err != nil {
-		return nil, fmt.Errorf("failed to parse HTML: %w", err)
-	}
+	// Structured data comes first; the selectors below fill what it lacks
+	data := ExtractStructuredData(doc)
+	data.apply(product)
 
 	// Extract product name - look for specific h1 tags and meta data
 	doc.Find("h1").Each(func(index int, s *goquery.Selection) {
@@ -112,106 +131,39 @@ func (i *IrshadScraper) Scrape(url string) (*Product, error) {
 		})
 	}
 
-	// Extract prices dynamically from various sources
-	var currentPrice, originalPrice string
-
-	// Method 1: Try to extract from structured JSON data in script tags
-	doc.Find("script").Each(func(index int, s *goquery.Selection) {
-		content := s.Text()
-
-		// Look for Calculator.init JSON data which contains the correct pricing
-		if strings.Contains(content, "Calculator.init") && strings.Contains(content, "price") {
-			// Extract price from Calculator.init JSON - be more flexible with spacing
-			re := regexp.MustCompile(`"price"\s*:\s*(\d+(?:\.\d+)?)`)
-			matches := re.FindAllStringSubmatch(content, -1)
-			if len(matches) > 0 && len(matches[0]) > 1 {
-				if price, err := strconv.ParseFloat(matches[0][1], 64); err == nil {
-					currentPrice = fmt.Sprintf("%.2f AZN", price)
-				}
-			}
-
-			// Extract installment_price (original price) - be more flexible
-			re2 := regexp.MustCompile(`"installment_price"\s*:\s*(\d+(?:\.\d+)?)`)
-			matches2 := re2.FindAllStringSubmatch(content, -1)
-			if len(matches2) > 0 && len(matches2[0]) > 1 {
-				if price, err := strconv.ParseFloat(matches2[0][1], 64); err == nil {
-					originalPrice = fmt.Sprintf("%.2f AZN", price)
-				}
-			}
-
-			// Break after finding Calculator.init data to prioritize it
-			if currentPrice != "" {
-				return
-			}
-		}
-	})
+	// Extract prices dynamically from various sources, after the
+	// structured data read above
+	currentPrice, originalPrice := product.CurrentPrice, product.OriginalPrice
 
-	// Method 2: Fallback - Look for JSON-LD structured data if Calculator.init failed
+	// Method 1: Calculator.init JSON data in script tags
 	if currentPrice == "" {
 		doc.Find("script").Each(func(index int, s *goquery.Selection) {
 			content := s.Text()
-			if strings.Contains(content, `"@type"`) && strings.Contains(content, "Product") {
-				re := regexp.MustCompile(`"price"\s*:\s*"?(\d+(?:\.\d+)?)"?`)
+
+			// Look for Calculator.init JSON data which contains the correct pricing
+			if currentPrice == "" && strings.Contains(content, "Calculator.init") && strings.Contains(content, "price") {
+				// Extract price from Calculator.init JSON - be more flexible with spacing
+				re := regexp.MustCompile(`"price"\s*:\s*(\d+(?:\.\d+)?)`)
 				matches := re.FindAllStringSubmatch(content, -1)
 				if len(matches) > 0 && len(matches[0]) > 1 {
-					if price, err := strconv.ParseFloat(matches[0][1], 64); err == nil && price > 100 && price < 10000 {
+					if price, err := strconv.ParseFloat(matches[0][1], 64); err == nil {
 						currentPrice = fmt.Sprintf("%.2f AZN", price)
 					}
 				}
-			}
-		})
-	}
 
-	// Method 3: Last resort - extract from visible text
-	if currentPrice == "" {
-		var prices []float64
-		doc.Find("*").Each(func(index int, s *goquery.Selection) {
-			text := strings.TrimSpace(s.Text())
-
-			// Skip payment plan prices and other irrelevant text
-			if strings.Contains(text, "AZN") &&
-				!strings.Contains(text, "x") &&
-				!strings.Contains(strings.ToLower(text), "aylıq") &&
-				!strings.Contains(text, "ay") &&
-				!strings.Contains(strings.ToLower(text), "credit") &&
-				!strings.Contains(strings.ToLower(text), "kredit") &&
-				len(text) < 20 { // Avoid long descriptions
-
-				re := regexp.MustCompile(`^(\d+(?:\.\d+)?)\s*AZN$`)
-				matches := re.FindStringSubmatch(text)
-				if len(matches) > 1 {
-					if price, err := strconv.ParseFloat(matches[1], 64); err == nil && price > 100 && price < 10000 {
-						prices = append(prices, price)
+				// Extract installment_price (original price) - be more flexible
+				re2 := regexp.MustCompile(`"installment_price"\s*:\s*(\d+(?:\.\d+)?)`)
+				matches2 := re2.FindAllStringSubmatch(content, -1)
+				if len(matches2) > 0 && len(matches2[0]) > 1 {
+					if price, err := strconv.ParseFloat(matches2[0][1], 64); err == nil {
+						originalPrice = fmt.Sprintf("%.2f AZN", price)
 					}
 				}
 			}
 		})
-
-		// Sort and pick the most reasonable prices
-		if len(prices) > 0 {
-			// Remove duplicates
-			uniquePrices := make(map[float64]bool)
-			var finalPrices []float64
-			for _, price := range prices {
-				if !uniquePrices[price] {
-					uniquePrices[price] = true
-					finalPrices = append(finalPrices, price)
-				}
-			}
-			sort.Float64s(finalPrices)
-
-			if len(finalPrices) >= 2 {
-				currentPrice = fmt.Sprintf("%.2f AZN", finalPrices[0])
-				originalPrice = fmt.Sprintf("%.2f AZN", finalPrices[len(finalPrices)-1])
-			} else if len(finalPrices) == 1 {
-				currentPrice = fmt.Sprintf("%.2f AZN", finalPrices[0])
-			}
-		}
 	}
 
-	// Assign extracted prices
-	product.CurrentPrice = currentPrice
-	product.OriginalPrice = originalPrice // Method 2: If not found in scripts, try extracting from visible text (as last resort)
+	// Method 2: Last resort - extract from visible text
 	if currentPrice == "" {
 		var prices []float64
 		doc.Find("*").Each(func(index int, s *goquery.Selection) {
@@ -226,10 +178,10 @@ func (i *IrshadScraper) Scrape(url string) (*Product, error) {
 				!strings.Contains(strings.ToLower(text), "kredit") &&
 				len(text) < 20 { // Avoid long descriptions
 
-				re := regexp.MustCompile(`^(\d+\.?\d*)\s*AZN$`)
+				re := regexp.MustCompile(`^(\d+(?:\.\d+)?)\s*AZN$`)
 				matches := re.FindStringSubmatch(text)
 				if len(matches) > 1 {
-					if price, err := strconv.ParseFloat(matches[1], 64); err == nil && price > 100 && price < 10000 {
+					if price, err := strconv.ParseFloat(matches[1], 64); err == nil && price > 0 {
 						prices = append(prices, price)
 					}
 				}
@@ -258,20 +210,26 @@ func (i *IrshadScraper) Scrape(url string) (*Product, error) {
 		}
 	}
 
-	// Assign extracted prices
+	// Assign extracted prices and calculate discount if both are available
 	product.CurrentPrice = currentPrice
-	product.OriginalPrice = originalPrice // Calculate discount if both prices available
+	product.OriginalPrice = originalPrice
 	if product.CurrentPrice != "" && product.OriginalPrice != "" {
 		currentFloat := extractNumericPrice(product.CurrentPrice)
 		originalFloat := extractNumericPrice(product.OriginalPrice)
-		if originalFloat > 0 && currentFloat > 0 {
+		if originalFloat > currentFloat && currentFloat > 0 {
 			discountPercent := ((originalFloat - currentFloat) / originalFloat) * 100
 			product.Discount = fmt.Sprintf("-%.0f%%", discountPercent)
 		}
 	}
 
+	// The OpenGraph title names any page, so it only counts on a page
+	// that has a price
+	if product.Name == "" && product.CurrentPrice != "" {
+		product.Name = data.Title
+	}
+
 	// Set currency
-	if strings.Contains(product.CurrentPrice, "AZN") {
+	if product.Currency == "" && strings.Contains(product.CurrentPrice, "AZN") {
 		product.Currency = "AZN"
 	}
 
@@ -362,32 +320,31 @@ func (i *IrshadScraper) Scrape(url string) (*Product, error) {
 		})
 	}
 
-	// Extract other specifications
+	// Extract specifications written as "Label : Value"
 	doc.Find("*").Each(func(index int, s *goquery.Selection) {
 		text := strings.TrimSpace(s.Text())
 
-		// Look for specifications in the format "Label : Value"
-		if strings.Contains(text, " : ") {
-			parts := strings.Split(text, " : ")
-			if len(parts) >= 2 {
-				label := strings.TrimSpace(strings.ToLower(parts[0]))
-				value := strings.TrimSpace(parts[1])
-
-				switch {
-				case strings.Contains(label, "daxili yaddaş") && product.InternalMemory == "":
-					product.InternalMemory = value
-				case strings.Contains(label, "operativ yaddaş") && product.RAM == "":
-					product.RAM = value
-				case strings.Contains(label, "prosessor") && product.Processor == "":
-					product.Processor = value
-				case strings.Contains(label, "qrafik prosessor") && product.Display == "":
-					// For gaming consoles, GPU info can be stored in display field
-					product.Display = value
-				case strings.Contains(label, "çəki") && product.Brand != "" && product.OS == "":
-					// Store weight or other specs in OS field if available
-					product.OS = fmt.Sprintf("Çəki: %s", value)
-				}
-			}
+		// Containers hold many rows; only an element with a single
+		// one-line label is a specification row
+		parts := strings.Split(text, " : ")
+		if len(parts) != 2 || strings.Contains(parts[0], "\n") || len(parts[0]) > 60 {
+			return
+		}
+		label := strings.TrimSpace(parts[0])
+		value := strings.TrimSpace(strings.Split(parts[1], "\n")[0])
+		if label == "" || value == "" {
+			return
+		}
+		product.addSpec(label, value)
+
+		labelLower := strings.ToLower(label)
+		switch {
+		case strings.Contains(labelLower, "daxili yaddaş") && product.InternalMemory == "":
+			product.InternalMemory = value
+		case strings.Contains(labelLower, "operativ yaddaş") && product.RAM == "":
+			product.RAM = value
+		case strings.Contains(labelLower, "prosessor") && !strings.Contains(labelLower, "qrafik") && product.Processor == "":
+			product.Processor = value
 		}
 	})
 
@@ -404,9 +361,58 @@ func (i *IrshadScraper) Scrape(url string) (*Product, error) {
 		}
 	}
 
+	// Parse display prices into comparable values
+	product.fillPrices()
+
 	return product, nil
 }
 
+// Search fetches irshad.az's search results for query
+func (i *IrshadScraper) Search(ctx context.Context, query string) ([]ListingItem, error) {
+	ctx, cancel := context.WithTimeout(ctx, irshadDefaultTimeout)
+	defer cancel()
+
+	searchURL := "https://irshad.az/az/search?q=" + url.QueryEscape(query)
+	page, err := i.fetcher.Get(ctx, searchURL)
+	if err != nil {
+		return nil, err
+	}
+	listing, err := parseIrshadListingPage(page.URL, page.Body)
+	if err != nil {
+		return nil, err
+	}
+	return listing.Items, nil
+}
+
+// ScrapeListing fetches an irshad.az category page
+func (i *IrshadScraper) ScrapeListing(ctx context.Context, url string) (*ListingPage, error) {
+	if !i.IsValidURL(url) {
+		return nil, fmt.Errorf("URL does not belong to irshad.az: %s", url)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, irshadDefaultTimeout)
+	defer cancel()
+
+	page, err := i.fetcher.Get(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+	return parseIrshadListingPage(page.URL, page.Body)
+}
+
+// parseIrshadListingPage extracts product cards and the next-page link from
+// an irshad.az listing page
+func parseIrshadListingPage(pageURL string, body []byte) (*ListingPage, error) {
+	doc, err := goquery.NewDocumentFromReader(bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse HTML: %w", err)
+	}
+	return &ListingPage{
+		Items:   parseListing(doc, pageURL, "irshad", irshadListing),
+		NextURL: findNextPage(doc, pageURL, irshadPagination),
+	}, nil
+}
+
 // Helper function to extract numeric price from price string
 func extractNumericPrice(priceStr string) float64 {
 	if priceStr == "" {
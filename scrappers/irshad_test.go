@@ -0,0 +1,60 @@
+package scrappers
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestIrshadParse(t *testing.T) {
+	tests := []struct {
+		fixture       string
+		name          string
+		currentPrice  string
+		originalPrice string
+		availability  string
+		minor         int64
+	}{
+		// Structured data wins over the prices visible on the page
+		{"irshad_product.html", "Oyun konsolu Sony PlayStation 5 Slim 1 TB", "1149.99 AZN", "", "In stock", 114999},
+		// Without it, visible prices of any size are read
+		{"irshad_no_structured.html", "Simsiz qulaqlıq JBL Tune 520BT", "69.99 AZN", "89.99 AZN", "Stokda var", 6999},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.fixture, func(t *testing.T) {
+			html, err := os.ReadFile(filepath.Join("testdata", tt.fixture))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := NewIrshadScraper().parse("https://irshad.az/az/mehsullar/"+tt.fixture, string(html))
+			if err != nil {
+				t.Fatalf("parse: %v", err)
+			}
+
+			if got.Name != tt.name {
+				t.Errorf("Name = %q, want %q", got.Name, tt.name)
+			}
+			if got.CurrentPrice != tt.currentPrice || got.OriginalPrice != tt.originalPrice {
+				t.Errorf("prices = %q, %q; want %q, %q", got.CurrentPrice, got.OriginalPrice, tt.currentPrice, tt.originalPrice)
+			}
+			if got.Availability != tt.availability {
+				t.Errorf("Availability = %q, want %q", got.Availability, tt.availability)
+			}
+			if got.CurrentPriceValue == nil || got.CurrentPriceValue.Minor != tt.minor {
+				t.Errorf("CurrentPriceValue = %v, want %d minor units", got.CurrentPriceValue, tt.minor)
+			}
+		})
+	}
+}
+
+func TestIrshadParseIgnoresTitleWithoutPrice(t *testing.T) {
+	// A category page is titled like any other page, but sells nothing
+	html := `<html><head><meta property="og:title" content="Smartfonlar | İrşad"></head><body></body></html>`
+
+	got, err := NewIrshadScraper().parse("https://irshad.az/az/telefonlar", html)
+	if err == nil && got.Name != "" {
+		t.Errorf("Name = %q, want none on a page without a price", got.Name)
+	}
+}
@@ -3,20 +3,61 @@ package scrappers
 import (
 	"context"
 	"fmt"
+	"net/url"
 	"os"
 	"strings"
 	"time"
 
 	"github.com/PuerkitoBio/goquery"
-	"github.com/chromedp/chromedp"
 )
 
+// kontaktDefaultTimeout bounds a kontakt.az scrape when the caller sets no timeout
+const kontaktDefaultTimeout = 45 * time.Second
+
+// kontaktReady captures the page once Cloudflare has let us through, the
+// price block has rendered and the page has stopped loading
+var kontaktReady = ReadySpec{
+	ChallengeCleared: true,
+	Selectors:        []string{"div.prodCart__prices"},
+	NetworkIdle:      500 * time.Millisecond,
+	Timeout:          15 * time.Second,
+}
+
+// kontaktSearchReady captures search results once product cards, or the
+// empty-result notice, have rendered
+var kontaktSearchReady = ReadySpec{
+	ChallengeCleared: true,
+	Selectors:        []string{"div.prodItem", "li.product-item", "div.message.notice"},
+	NetworkIdle:      500 * time.Millisecond,
+	Timeout:          15 * time.Second,
+}
+
+// kontaktListing locates product cards on kontakt.az search and category pages
+var kontaktListing = listingSelectors{
+	Card:          "div.prodItem, li.product-item",
+	Link:          "a.prodItem__title, a.product-item-link, a.prodItem__img, a[href]",
+	Name:          ".prodItem__title, .product-item-link",
+	Price:         ".prodItem__prices strong span, span[data-price-type='finalPrice'] span.price",
+	OriginalPrice: ".prodItem__prices del, .prodItem__prices .prodItem__oldPrice, span[data-price-type='oldPrice'] span.price",
+	Image:         "img",
+}
+
+// kontaktScrollRounds caps how often a category page is scrolled for more products
+const kontaktScrollRounds = 20
+
+// kontaktPagination matches the next-page link of kontakt.az listings
+const kontaktPagination = "a.action.next, li.pages-item-next a"
+
 // KontaktScraper implements the Scraper interface for kontakt.az
-type KontaktScraper struct{}
+type KontaktScraper struct {
+	pool  *BrowserPool
+	ready ReadySpec
+}
 
-// NewKontaktScraper creates a new instance of KontaktScraper
+// NewKontaktScraper creates a new instance of KontaktScraper backed by the
+// shared browser pool
 func NewKontaktScraper() *KontaktScraper {
-	return &KontaktScraper{}
+	return &KontaktScraper{pool: SharedBrowserPool(), ready: kontaktReady}
 }
 
 // GetSiteName returns the site name
@@ -26,11 +67,23 @@ func (k *KontaktScraper) GetSiteName() string {
 
 // IsValidURL checks if the URL belongs to kontakt.az
 func (k *KontaktScraper) IsValidURL(url string) bool {
-	return strings.Contains(strings.ToLower(url), "kontakt.az")
+	return hostMatches(url, "kontakt.az")
+}
+
+// IsProductURL reports whether url is a product page; Kontakt puts
+// products at the root of the site under long slugs
+func (k *KontaktScraper) IsProductURL(url string) bool {
+	return isSlugProductPath(url)
 }
 
 // Scrape extracts product information from kontakt.az URL
 func (k *KontaktScraper) Scrape(url string) (*Product, error) {
+	return k.ScrapeContext(context.Background(), url, ScrapeOptions{})
+}
+
+// ScrapeContext extracts product information from kontakt.az URL, closing
+// its browser tab as soon as ctx is done
+func (k *KontaktScraper) ScrapeContext(ctx context.Context, url string, opts ScrapeOptions) (*Product, error) {
 	if !k.IsValidURL(url) {
 		return nil, fmt.Errorf("URL does not belong to kontakt.az: %s", url)
 	}
@@ -41,44 +94,22 @@ func (k *KontaktScraper) Scrape(url string) (*Product, error) {
 		ScrapedAt: time.Now().UTC().Format(time.RFC3339),
 	}
 
-	// Use chromedp to bypass Cloudflare and execute JavaScript
-	opts := []chromedp.ExecAllocatorOption{
-		chromedp.NoFirstRun,
-		chromedp.NoDefaultBrowserCheck,
-		chromedp.Headless,
-		chromedp.DisableGPU,
-		chromedp.NoSandbox,
-		chromedp.UserAgent("Mozilla/5.0 (X11; Linux x86_64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36"),
-	}
-
-	allocCtx, cancel := chromedp.NewExecAllocator(context.Background(), opts...)
-	defer cancel()
-
-	ctx, cancel := chromedp.NewContext(allocCtx)
-	defer cancel()
-
 	// Set a timeout for the entire operation
-	ctx, cancel = context.WithTimeout(ctx, 45*time.Second)
+	ctx, cancel := opts.withTimeout(ctx, kontaktDefaultTimeout)
 	defer cancel()
 
 	if os.Getenv("DEBUG") == "1" {
 		fmt.Printf("DEBUG: Starting chromedp for URL: %s\n", url)
 	}
 
-	var htmlContent string
-	err := chromedp.Run(ctx,
-		chromedp.Navigate(url),
-		chromedp.Sleep(3*time.Second), // Wait for page load and Cloudflare
-		chromedp.WaitReady("body", chromedp.ByQuery),
-		chromedp.Sleep(2*time.Second), // Additional wait for dynamic content
-		chromedp.OuterHTML("html", &htmlContent),
-	)
-
+	// Render in a pooled chromedp tab to bypass Cloudflare and execute
+	// JavaScript; the tab is closed as soon as ctx is done
+	htmlContent, err := k.pool.Render(ctx, url, k.ready)
 	if err != nil {
 		if os.Getenv("DEBUG") == "1" {
 			fmt.Printf("DEBUG: chromedp error: %v\n", err)
 		}
-		return nil, fmt.Errorf("failed to fetch URL with chromedp: %w", err)
+		return nil, err
 	}
 
 	if os.Getenv("DEBUG") == "1" {
@@ -95,6 +126,10 @@ func (k *KontaktScraper) Scrape(url string) (*Product, error) {
 		return nil, fmt.Errorf("failed to parse HTML: %w", err)
 	}
 
+	// Structured data comes first; the selectors below fill what it lacks
+	data := ExtractStructuredData(doc)
+	data.apply(product)
+
 	// Extract product name
 	doc.Find("h1.page-title span, h1.page-title, span.base").Each(func(i int, s *goquery.Selection) {
 		if product.Name == "" {
@@ -104,7 +139,9 @@ func (k *KontaktScraper) Scrape(url string) (*Product, error) {
 
 	// Extract SKU
 	doc.Find("div.product.attribute.sku div.value").Each(func(i int, s *goquery.Selection) {
-		product.SKU = strings.TrimSpace(s.Text())
+		if product.SKU == "" {
+			product.SKU = strings.TrimSpace(s.Text())
+		}
 	})
 
 	// Extract prices - Kontakt.az specific structure
@@ -197,9 +234,10 @@ func (k *KontaktScraper) Scrape(url string) (*Product, error) {
 		if label == "" || value == "" {
 			return
 		}
+		product.addSpec(label, value)
 		labelLower := strings.ToLower(label)
 		switch {
-		case strings.Contains(labelLower, "brend"):
+		case strings.Contains(labelLower, "brend") && product.Brand == "":
 			product.Brand = value
 		case strings.Contains(labelLower, "daxili yaddaş"):
 			product.InternalMemory = value
@@ -229,6 +267,7 @@ func (k *KontaktScraper) Scrape(url string) (*Product, error) {
 		if label == "" || value == "" {
 			return
 		}
+		product.addSpec(label, value)
 		labelLower := strings.ToLower(label)
 		switch {
 		case strings.Contains(labelLower, "brend") && product.Brand == "":
@@ -250,9 +289,72 @@ func (k *KontaktScraper) Scrape(url string) (*Product, error) {
 		}
 	})
 
+	// The OpenGraph title names any page, so it only counts on a page
+	// that has a price
+	if product.Name == "" && product.CurrentPrice != "" {
+		product.Name = data.Title
+	}
+
+	// A challenge or error page has no product name
+	if product.Name == "" {
+		return nil, fmt.Errorf("no product found on page")
+	}
+
+	// Parse display prices into comparable values
+	product.fillPrices()
+
 	return product, nil
 }
 
+// Search renders kontakt.az's search results for query
+func (k *KontaktScraper) Search(ctx context.Context, query string) ([]ListingItem, error) {
+	ctx, cancel := context.WithTimeout(ctx, kontaktDefaultTimeout)
+	defer cancel()
+
+	searchURL := "https://kontakt.az/catalogsearch/result/?q=" + url.QueryEscape(query)
+	htmlContent, err := k.pool.Render(ctx, searchURL, kontaktSearchReady)
+	if err != nil {
+		return nil, err
+	}
+	return parseKontaktListing(searchURL, htmlContent)
+}
+
+// ScrapeListing renders a kontakt.az category page, scrolling until its
+// infinite scroll stops loading products
+func (k *KontaktScraper) ScrapeListing(ctx context.Context, url string) (*ListingPage, error) {
+	if !k.IsValidURL(url) {
+		return nil, fmt.Errorf("URL does not belong to kontakt.az: %s", url)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 2*kontaktDefaultTimeout)
+	defer cancel()
+
+	htmlContent, err := k.pool.Render(ctx, url, kontaktSearchReady,
+		ScrollToLoad(kontaktListing.Card, "button.loadMore, a.loadMore, .js-load-more", kontaktScrollRounds))
+	if err != nil {
+		return nil, err
+	}
+
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(htmlContent))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse HTML: %w", err)
+	}
+	return &ListingPage{
+		Items:   parseListing(doc, url, "kontakt", kontaktListing),
+		NextURL: findNextPage(doc, url, kontaktPagination),
+	}, nil
+}
+
+// parseKontaktListing extracts product cards from a rendered kontakt.az
+// listing page
+func parseKontaktListing(pageURL, html string) ([]ListingItem, error) {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(html))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse HTML: %w", err)
+	}
+	return parseListing(doc, pageURL, "kontakt", kontaktListing), nil
+}
+
 // init function registers the KontaktScraper when the package is imported
 func init() {
 	RegisterScraper("kontakt", NewKontaktScraper())
@@ -0,0 +1,183 @@
+package scrappers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/chromedp/chromedp"
+)
+
+// ListingPage is one page of a category listing
+type ListingPage struct {
+	Items []ListingItem `json:"items"`
+
+	// NextURL is the following page, or empty on the last one
+	NextURL string `json:"next_url,omitempty"`
+}
+
+// ListingScraper is implemented by scrapers that can read category pages
+type ListingScraper interface {
+	// ScrapeListing returns the product cards on a category page and the
+	// link to the next page. Infinite-scroll pages are scrolled to the end.
+	ScrapeListing(ctx context.Context, url string) (*ListingPage, error)
+}
+
+// CrawlOptions limits a category crawl
+type CrawlOptions struct {
+	// MaxPages is the number of listing pages followed; zero means 1
+	MaxPages int
+
+	// MaxItems stops the crawl once this many products were found; zero means no limit
+	MaxItems int
+}
+
+// CrawlResult is the outcome of a category crawl
+type CrawlResult struct {
+	Items []ListingItem `json:"items"`
+	Pages int           `json:"pages"`
+
+	// Error describes why the crawl stopped early, if it did; items found
+	// before the failure are kept
+	Error string `json:"error,omitempty"`
+}
+
+// CrawlCategory follows a category's pages from startURL, collecting
+// product cards until a limit is reached or there is no next page. Products
+// and pages already seen are skipped, so pagination loops terminate.
+func CrawlCategory(ctx context.Context, ls ListingScraper, startURL string, opts CrawlOptions) *CrawlResult {
+	maxPages := opts.MaxPages
+	if maxPages <= 0 {
+		maxPages = 1
+	}
+
+	result := &CrawlResult{Items: []ListingItem{}}
+	seenItems := make(map[string]bool)
+	seenPages := make(map[string]bool)
+
+	for pageURL := startURL; pageURL != "" && result.Pages < maxPages; {
+		if seenPages[listingKey(pageURL)] {
+			break
+		}
+		seenPages[listingKey(pageURL)] = true
+
+		page, err := ls.ScrapeListing(ctx, pageURL)
+		if err != nil {
+			result.Error = fmt.Sprintf("page %d (%s): %v", result.Pages+1, pageURL, err)
+			break
+		}
+		result.Pages++
+
+		for _, item := range page.Items {
+			key := listingKey(item.URL)
+			if seenItems[key] {
+				continue
+			}
+			seenItems[key] = true
+			result.Items = append(result.Items, item)
+			if opts.MaxItems > 0 && len(result.Items) >= opts.MaxItems {
+				return result
+			}
+		}
+
+		if os.Getenv("DEBUG") == "1" {
+			fmt.Printf("DEBUG: crawled listing page %d (%s), %d products so far\n", result.Pages, pageURL, len(result.Items))
+		}
+		pageURL = page.NextURL
+	}
+	return result
+}
+
+// listingKey identifies a URL for deduplication, ignoring the scheme, a
+// leading "www.", the fragment and a trailing slash
+func listingKey(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+	host := strings.TrimPrefix(strings.ToLower(u.Host), "www.")
+	return host + strings.TrimRight(u.EscapedPath(), "/") + "?" + u.RawQuery
+}
+
+// findNextPage returns the absolute URL of the next listing page, taken
+// from rel="next" links or the given pagination selectors
+func findNextPage(doc *goquery.Document, pageURL string, selectors string) string {
+	base, _ := url.Parse(pageURL)
+	candidates := "link[rel='next'], a[rel='next']"
+	if selectors != "" {
+		candidates += ", " + selectors
+	}
+
+	next := ""
+	doc.Find(candidates).EachWithBreak(func(i int, s *goquery.Selection) bool {
+		next = resolveURL(base, s.AttrOr("href", ""))
+		return next == ""
+	})
+	if next != "" && listingKey(next) == listingKey(pageURL) {
+		return ""
+	}
+	return next
+}
+
+// ScrollToLoad scrolls an infinite-scroll page to the bottom, clicking any
+// "load more" button, until no new cards matching cardSelector appear or
+// maxRounds is reached
+func ScrollToLoad(cardSelector, loadMoreSelector string, maxRounds int) chromedp.Action {
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		cards, err := json.Marshal(cardSelector)
+		if err != nil {
+			return err
+		}
+		countJS := fmt.Sprintf("document.querySelectorAll(%s).length", cards)
+
+		scrollJS := "window.scrollTo(0, document.body.scrollHeight)"
+		if loadMoreSelector != "" {
+			button, err := json.Marshal(loadMoreSelector)
+			if err != nil {
+				return err
+			}
+			scrollJS = fmt.Sprintf(`(function() {
+				var more = document.querySelector(%s);
+				if (more && more.offsetParent !== null) { more.click(); }
+				window.scrollTo(0, document.body.scrollHeight);
+			})()`, button)
+		}
+
+		var count int
+		if err := chromedp.Evaluate(countJS, &count).Do(ctx); err != nil {
+			return err
+		}
+		for round := 0; round < maxRounds; round++ {
+			if err := chromedp.Evaluate(scrollJS, nil).Do(ctx); err != nil {
+				return err
+			}
+
+			// Give the next batch time to arrive; two quiet checks end the scroll
+			grown := false
+			for check := 0; check < 2 && !grown; check++ {
+				if err := sleepCtx(ctx, time.Second); err != nil {
+					return err
+				}
+				var now int
+				if err := chromedp.Evaluate(countJS, &now).Do(ctx); err != nil {
+					return err
+				}
+				grown = now > count
+				count = now
+			}
+			if !grown {
+				break
+			}
+		}
+
+		if os.Getenv("DEBUG") == "1" {
+			fmt.Printf("DEBUG: infinite scroll loaded %d cards\n", count)
+		}
+		return nil
+	})
+}
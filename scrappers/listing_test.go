@@ -0,0 +1,94 @@
+package scrappers
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// fakeListing serves listing pages from a map keyed by URL
+type fakeListing map[string]*ListingPage
+
+func (f fakeListing) ScrapeListing(ctx context.Context, url string) (*ListingPage, error) {
+	page, ok := f[url]
+	if !ok {
+		return nil, errors.New("not found")
+	}
+	return page, nil
+}
+
+func items(urls ...string) []ListingItem {
+	var out []ListingItem
+	for _, u := range urls {
+		out = append(out, ListingItem{Name: u, URL: u})
+	}
+	return out
+}
+
+func TestCrawlCategory(t *testing.T) {
+	pages := fakeListing{
+		"https://shop.az/phones":       {Items: items("https://shop.az/a", "https://shop.az/b"), NextURL: "https://shop.az/phones?p=2"},
+		"https://shop.az/phones?p=2":   {Items: items("https://shop.az/b/", "https://www.shop.az/c"), NextURL: "https://shop.az/phones?p=3"},
+		"https://shop.az/phones?p=3":   {Items: items("https://shop.az/d"), NextURL: "https://shop.az/phones"},
+		"https://shop.az/broken-start": {Items: items("https://shop.az/x"), NextURL: "https://shop.az/missing"},
+	}
+
+	tests := []struct {
+		name    string
+		start   string
+		opts    CrawlOptions
+		want    []string
+		pages   int
+		failing bool
+	}{
+		{"first page only by default", "https://shop.az/phones", CrawlOptions{}, []string{"a", "b"}, 1, false},
+		{"dedup across pages and stop on loop", "https://shop.az/phones", CrawlOptions{MaxPages: 10}, []string{"a", "b", "c", "d"}, 3, false},
+		{"item limit", "https://shop.az/phones", CrawlOptions{MaxPages: 10, MaxItems: 3}, []string{"a", "b", "c"}, 2, false},
+		{"partial result on error", "https://shop.az/broken-start", CrawlOptions{MaxPages: 5}, []string{"x"}, 1, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := CrawlCategory(context.Background(), pages, tt.start, tt.opts)
+			if result.Pages != tt.pages {
+				t.Errorf("crawled %d pages, want %d", result.Pages, tt.pages)
+			}
+			if (result.Error != "") != tt.failing {
+				t.Errorf("unexpected error %q", result.Error)
+			}
+			if len(result.Items) != len(tt.want) {
+				t.Fatalf("got %d items, want %v", len(result.Items), tt.want)
+			}
+			for i, suffix := range tt.want {
+				if !strings.HasSuffix(result.Items[i].URL, "/"+suffix) {
+					t.Errorf("item %d is %s, want .../%s", i, result.Items[i].URL, suffix)
+				}
+			}
+		})
+	}
+}
+
+func TestFindNextPage(t *testing.T) {
+	tests := []struct {
+		html string
+		want string
+	}{
+		{`<link rel="next" href="/telefonlar?page=2">`, "https://irshad.az/telefonlar?page=2"},
+		{`<ul class="pagination"><li class="active"><a href="?page=1">1</a></li><li><a href="?page=2">2</a></li></ul>`, "https://irshad.az/telefonlar?page=2"},
+		{`<ul class="pagination"><li class="active"><a href="?page=1">1</a></li></ul>`, ""},
+		{`<a rel="next" href="/telefonlar?page=1">next</a>`, ""},
+	}
+
+	for _, tt := range tests {
+		doc, err := goquery.NewDocumentFromReader(strings.NewReader(tt.html))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := findNextPage(doc, "https://irshad.az/telefonlar?page=1", irshadPagination); got != tt.want {
+			t.Errorf("findNextPage(%s) = %q, want %q", tt.html, got, tt.want)
+		}
+	}
+}
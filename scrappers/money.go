@@ -0,0 +1,232 @@
+package scrappers
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Money is a monetary amount stored in minor units (qəpik for AZN) so values
+// from different sites can be compared and sorted exactly
+type Money struct {
+	Minor    int64  `json:"minor_units"`
+	Currency string `json:"currency"`
+}
+
+// currencySymbols maps the currency markers seen on Azerbaijani stores to ISO codes
+var currencySymbols = []struct {
+	marker string
+	code   string
+}{
+	{"₼", "AZN"},
+	{"azn", "AZN"},
+	{"manat", "AZN"},
+	{"man.", "AZN"},
+	{"$", "USD"},
+	{"usd", "USD"},
+	{"€", "EUR"},
+	{"eur", "EUR"},
+	{"₽", "RUB"},
+	{"rub", "RUB"},
+}
+
+// ParseMoney parses a display price such as "1.379,99 ₼", "1639.99 AZN" or
+// "-230 manat". Spaces separate thousands; dots and commas are read as
+// described on normalizeDecimal. defaultCurrency is used when no currency
+// marker is present.
+func ParseMoney(s string, defaultCurrency string) (Money, error) {
+	lower := strings.ToLower(strings.TrimSpace(s))
+	if lower == "" {
+		return Money{}, fmt.Errorf("empty price")
+	}
+
+	currency := defaultCurrency
+	for _, c := range currencySymbols {
+		if strings.Contains(lower, c.marker) {
+			currency = c.code
+			lower = strings.ReplaceAll(lower, c.marker, " ")
+			break
+		}
+	}
+
+	// Keep only the first run of digits and separators, remembering the sign
+	var number strings.Builder
+	negative := false
+	started := false
+scan:
+	for _, r := range lower {
+		switch {
+		case unicode.IsDigit(r):
+			started = true
+			number.WriteRune(r)
+		case r == '.' || r == ',':
+			if started {
+				number.WriteRune(r)
+			}
+		case r == ' ' || r == ' ' || r == ' ':
+			// Spaces are thousands separators inside a number
+		case (r == '-' || r == '−') && !started:
+			negative = true
+		default:
+			if started {
+				break scan
+			}
+		}
+	}
+	raw := strings.TrimRight(number.String(), ".,")
+	if raw == "" {
+		return Money{}, fmt.Errorf("no amount found in price %q", s)
+	}
+
+	normalized, err := normalizeDecimal(raw)
+	if err != nil {
+		return Money{}, fmt.Errorf("invalid amount in price %q: %w", s, err)
+	}
+	value, err := strconv.ParseFloat(normalized, 64)
+	if err != nil {
+		return Money{}, fmt.Errorf("invalid amount in price %q: %w", s, err)
+	}
+
+	minor := int64(math.Round(value * 100))
+	if negative {
+		minor = -minor
+	}
+	return Money{Minor: minor, Currency: currency}, nil
+}
+
+// normalizeDecimal rewrites a number using '.' or ',' separators into the
+// plain "1234.56" form understood by strconv. The same rule applies to
+// both: a separator followed by exactly three digits groups thousands
+// ("1.379", "1,379"), unless it follows a lone zero ("0.500"); one followed
+// by one or two digits is the decimal separator. Anything else is ambiguous
+// and rejected rather than guessed.
+func normalizeDecimal(raw string) (string, error) {
+	lastDot := strings.LastIndex(raw, ".")
+	lastComma := strings.LastIndex(raw, ",")
+
+	if lastDot >= 0 && lastComma >= 0 {
+		// Both present: whichever comes last is the decimal separator
+		decimal, thousands := ",", "."
+		if lastDot > lastComma {
+			decimal, thousands = ".", ","
+		}
+		parts := strings.Split(raw, decimal)
+		if len(parts) != 2 || len(parts[1]) > 2 || strings.Contains(parts[1], thousands) {
+			return "", fmt.Errorf("ambiguous number %q", raw)
+		}
+		integer, err := joinThousands(strings.Split(parts[0], thousands))
+		if err != nil {
+			return "", fmt.Errorf("ambiguous number %q", raw)
+		}
+		return integer + "." + parts[1], nil
+	}
+
+	sep := "."
+	if lastComma >= 0 {
+		sep = ","
+	} else if lastDot < 0 {
+		return raw, nil
+	}
+
+	groups := strings.Split(raw, sep)
+	if len(groups) == 2 {
+		switch last := groups[1]; {
+		case len(last) <= 2 || (len(last) == 3 && groups[0] == "0"):
+			return groups[0] + "." + last, nil
+		case len(last) != 3:
+			return "", fmt.Errorf("ambiguous number %q", raw)
+		}
+	}
+	integer, err := joinThousands(groups)
+	if err != nil {
+		return "", fmt.Errorf("ambiguous number %q", raw)
+	}
+	return integer, nil
+}
+
+// joinThousands joins digit groups split by a thousands separator, checking
+// that every group after the first has three digits
+func joinThousands(groups []string) (string, error) {
+	if groups[0] == "" || (len(groups) > 1 && len(groups[0]) > 3) {
+		return "", fmt.Errorf("invalid thousands group %q", groups[0])
+	}
+	for _, g := range groups[1:] {
+		if len(g) != 3 {
+			return "", fmt.Errorf("invalid thousands group %q", g)
+		}
+	}
+	return strings.Join(groups, ""), nil
+}
+
+// Float returns the amount in major units
+func (m Money) Float() float64 {
+	return float64(m.Minor) / 100
+}
+
+// String formats the amount as "1379.99 AZN"
+func (m Money) String() string {
+	sign := ""
+	minor := m.Minor
+	if minor < 0 {
+		sign = "-"
+		minor = -minor
+	}
+	s := fmt.Sprintf("%s%d.%02d", sign, minor/100, minor%100)
+	if m.Currency != "" {
+		s += " " + m.Currency
+	}
+	return s
+}
+
+// MarshalJSON adds the major-unit amount next to the minor units
+func (m Money) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Amount   float64 `json:"amount"`
+		Minor    int64   `json:"minor_units"`
+		Currency string  `json:"currency"`
+	}{m.Float(), m.Minor, m.Currency})
+}
+
+// fillPrices parses the display price strings of p into typed Money values
+// and derives the discount amount and percentage when both prices are known
+func (p *Product) fillPrices() {
+	if m, err := ParseMoney(p.CurrentPrice, p.Currency); err == nil {
+		p.CurrentPriceValue = &m
+		if p.Currency == "" {
+			p.Currency = m.Currency
+		}
+	}
+	if m, err := ParseMoney(p.OriginalPrice, p.Currency); err == nil {
+		p.OriginalPriceValue = &m
+	}
+
+	// Structured data and page markup may both give the regular price,
+	// written differently; it is no original price then
+	if p.CurrentPriceValue != nil && p.OriginalPriceValue != nil && *p.OriginalPriceValue == *p.CurrentPriceValue {
+		p.OriginalPrice = ""
+		p.OriginalPriceValue = nil
+	}
+
+	if p.CurrentPriceValue != nil && p.OriginalPriceValue != nil && p.OriginalPriceValue.Minor > p.CurrentPriceValue.Minor &&
+		p.CurrentPriceValue.Currency == p.OriginalPriceValue.Currency {
+		p.DiscountValue = &Money{
+			Minor:    p.OriginalPriceValue.Minor - p.CurrentPriceValue.Minor,
+			Currency: p.CurrentPriceValue.Currency,
+		}
+		p.DiscountPercent = math.Round(float64(p.DiscountValue.Minor)/float64(p.OriginalPriceValue.Minor)*10000) / 100
+		return
+	}
+
+	// Otherwise fall back to an explicit amount discount such as "-230₼"
+	if p.Discount != "" && !strings.Contains(p.Discount, "%") {
+		if m, err := ParseMoney(p.Discount, p.Currency); err == nil {
+			if m.Minor < 0 {
+				m.Minor = -m.Minor
+			}
+			p.DiscountValue = &m
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package scrappers
+
+import "testing"
+
+func TestParseMoney(t *testing.T) {
+	tests := []struct {
+		in    string
+		minor int64
+		cur   string
+	}{
+		{"1 379,99 ₼", 137999, "AZN"},
+		{"1.379,99 ₼", 137999, "AZN"},
+		{"1.379,00", 137900, "AZN"},
+		{"1,379.50 USD", 137950, "USD"},
+		{"1639.99 AZN", 163999, "AZN"},
+		{"1.379", 137900, "AZN"},
+		{"1,379 AZN", 137900, "AZN"},
+		{"1.379.000", 137900000, "AZN"},
+		{"0.500", 50, "AZN"},
+		{"0,500 man.", 50, "AZN"},
+		{"12,5 €", 1250, "EUR"},
+		{"-230 manat", -23000, "AZN"},
+		{"999", 99900, "AZN"},
+	}
+
+	for _, tt := range tests {
+		m, err := ParseMoney(tt.in, "AZN")
+		if err != nil {
+			t.Errorf("ParseMoney(%q): %v", tt.in, err)
+			continue
+		}
+		if m.Minor != tt.minor || m.Currency != tt.cur {
+			t.Errorf("ParseMoney(%q) = %v, want %d %s", tt.in, m, tt.minor, tt.cur)
+		}
+	}
+
+	for _, bad := range []string{"", "AZN", "1,3795", "1.37.9", "1379.5,00", "1.379,999", "12345.678"} {
+		if m, err := ParseMoney(bad, "AZN"); err == nil {
+			t.Errorf("ParseMoney(%q) = %v, want an error", bad, m)
+		}
+	}
+}
+
+func TestNormalizeDecimal(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{"1379,99", "1379.99"},
+		{"1379.99", "1379.99"},
+		{"1.379", "1379"},
+		{"1,379", "1379"},
+		{"0.500", "0.500"},
+		{"1.379,00", "1379.00"},
+		{"1,379,000.5", "1379000.5"},
+		{"1379", "1379"},
+	}
+
+	for _, tt := range tests {
+		if got, err := normalizeDecimal(tt.in); err != nil || got != tt.want {
+			t.Errorf("normalizeDecimal(%q) = %q, %v; want %q", tt.in, got, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,197 @@
+package scrappers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// optimalDefaultTimeout bounds an optimal.az scrape when the caller sets no timeout
+const optimalDefaultTimeout = 30 * time.Second
+
+// OptimalScraper implements the Scraper interface for optimal.az
+type OptimalScraper struct {
+	fetcher *Fetcher
+}
+
+// NewOptimalScraper creates a new instance of OptimalScraper backed by the
+// shared fetcher
+func NewOptimalScraper() *OptimalScraper {
+	return &OptimalScraper{fetcher: SharedFetcher()}
+}
+
+// GetSiteName returns the site name
+func (o *OptimalScraper) GetSiteName() string {
+	return "Optimal.az"
+}
+
+// IsValidURL checks if the URL belongs to optimal.az
+func (o *OptimalScraper) IsValidURL(url string) bool {
+	return hostMatches(url, "optimal.az")
+}
+
+// IsProductURL reports whether url is a product page; Optimal puts
+// products at the root of the site under long slugs
+func (o *OptimalScraper) IsProductURL(url string) bool {
+	return isSlugProductPath(url)
+}
+
+// Scrape extracts product information from optimal.az URL
+func (o *OptimalScraper) Scrape(url string) (*Product, error) {
+	return o.ScrapeContext(context.Background(), url, ScrapeOptions{})
+}
+
+// ScrapeContext extracts product information from optimal.az URL, aborting
+// the request as soon as ctx is done
+func (o *OptimalScraper) ScrapeContext(ctx context.Context, url string, opts ScrapeOptions) (*Product, error) {
+	if !o.IsValidURL(url) {
+		return nil, fmt.Errorf("URL does not belong to optimal.az: %s", url)
+	}
+
+	ctx, cancel := opts.withTimeout(ctx, optimalDefaultTimeout)
+	defer cancel()
+
+	page, err := o.fetcher.Get(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+
+	return o.parse(url, string(page.Body))
+}
+
+// parse extracts product information from an optimal.az product page using
+// the same Magento-style page-title, price box and additional-attributes
+// markup that kontakt.az uses
+func (o *OptimalScraper) parse(url string, htmlContent string) (*Product, error) {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(htmlContent))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse HTML: %w", err)
+	}
+
+	product := &Product{
+		URL:       url,
+		Site:      "optimal",
+		ScrapedAt: time.Now().UTC().Format(time.RFC3339),
+	}
+
+	// Structured data comes first; the selectors below fill what it lacks
+	data := ExtractStructuredData(doc)
+	data.apply(product)
+
+	// Extract product name
+	doc.Find("h1.page-title span, h1.page-title, h1.product-name").Each(func(i int, s *goquery.Selection) {
+		if product.Name == "" {
+			product.Name = strings.Join(strings.Fields(s.Text()), " ")
+		}
+	})
+
+	// Extract SKU
+	doc.Find("div.product.attribute.sku div.value, [itemprop='sku']").Each(func(i int, s *goquery.Selection) {
+		if product.SKU == "" {
+			product.SKU = strings.TrimSpace(s.Text())
+		}
+	})
+
+	// Extract prices - the special price is the current one and the old
+	// price box holds the regular price when a discount applies
+	doc.Find("div.product-info-main span.special-price span.price, div.product-info-main span[data-price-type='finalPrice'] span.price").Each(func(i int, s *goquery.Selection) {
+		if product.CurrentPrice == "" {
+			product.CurrentPrice = strings.Join(strings.Fields(s.Text()), " ")
+		}
+	})
+	doc.Find("div.product-info-main span.old-price span.price, div.product-info-main span[data-price-type='oldPrice'] span.price").Each(func(i int, s *goquery.Selection) {
+		if product.OriginalPrice == "" {
+			product.OriginalPrice = strings.Join(strings.Fields(s.Text()), " ")
+		}
+	})
+
+	// Drop the original price when it equals the current one
+	if product.OriginalPrice == product.CurrentPrice {
+		product.OriginalPrice = ""
+	}
+
+	// Extract discount
+	doc.Find("div.product-info-main span.discount-percent, div.product-info-main div.label-discount span").Each(func(i int, s *goquery.Selection) {
+		text := strings.Join(strings.Fields(s.Text()), " ")
+		if product.Discount == "" && strings.Contains(text, "-") {
+			product.Discount = text
+		}
+	})
+
+	// Set currency
+	if strings.Contains(product.CurrentPrice, "₼") || strings.Contains(strings.ToUpper(product.CurrentPrice), "AZN") {
+		product.Currency = "AZN"
+	}
+
+	// Extract availability
+	doc.Find("div.product-info-main div.stock span, div.product-info-main div.stock").Each(func(i int, s *goquery.Selection) {
+		if product.Availability == "" {
+			product.Availability = strings.TrimSpace(s.Text())
+		}
+	})
+
+	// Extract rating
+	doc.Find("div.rating-summary span.rating-result span, span[itemprop='ratingValue']").Each(func(i int, s *goquery.Selection) {
+		if product.Rating == "" {
+			product.Rating = strings.TrimSpace(s.Text())
+		}
+	})
+
+	// Extract review count
+	doc.Find("div.reviews-actions a.action.view, span[itemprop='reviewCount']").Each(func(i int, s *goquery.Selection) {
+		if product.ReviewCount == "" {
+			product.ReviewCount = strings.TrimSpace(s.Text())
+		}
+	})
+
+	// Extract specifications from the additional attributes table
+	doc.Find("table.data.table.additional-attributes tr, table.additional-attributes tr").Each(func(i int, s *goquery.Selection) {
+		label := strings.TrimSpace(s.Find("th").Text())
+		value := strings.TrimSpace(s.Find("td").Text())
+		if label == "" || value == "" {
+			return
+		}
+		product.addSpec(label, value)
+		labelLower := strings.ToLower(label)
+		switch {
+		case strings.Contains(labelLower, "brend") && product.Brand == "":
+			product.Brand = value
+		case strings.Contains(labelLower, "daxili yaddaş") && product.InternalMemory == "":
+			product.InternalMemory = value
+		case strings.Contains(labelLower, "operativ yaddaş") && product.RAM == "":
+			product.RAM = value
+		case strings.Contains(labelLower, "əsas kamera") && product.MainCamera == "":
+			product.MainCamera = value
+		case strings.Contains(labelLower, "ön kamera") && product.FrontCamera == "":
+			product.FrontCamera = value
+		case strings.Contains(labelLower, "prosessor") && product.Processor == "":
+			product.Processor = value
+		case strings.Contains(labelLower, "əməliyyat sistemi") && product.OS == "":
+			product.OS = value
+		case (strings.Contains(labelLower, "displey") || strings.Contains(labelLower, "ekran")) && product.Display == "":
+			product.Display = value
+		}
+	})
+
+	// The OpenGraph title names any page, so it only counts on a page
+	// that has a price
+	if product.Name == "" && product.CurrentPrice != "" {
+		product.Name = data.Title
+	}
+	if product.Name == "" {
+		return nil, fmt.Errorf("no product found on page")
+	}
+
+	// Parse display prices into comparable values
+	product.fillPrices()
+
+	return product, nil
+}
+
+// init function registers the OptimalScraper when the package is imported
+func init() {
+	RegisterScraper("optimal", NewOptimalScraper())
+}
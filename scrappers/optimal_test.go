@@ -0,0 +1,104 @@
+package scrappers
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestOptimalParse(t *testing.T) {
+	tests := []struct {
+		fixture string
+		want    Product
+		minor   int64
+	}{
+		{
+			fixture: "optimal_product.html",
+			want: Product{
+				Name:           "Apple iPhone 15 128 GB Black",
+				SKU:            "IP15-128-BLK",
+				CurrentPrice:   "1.849,99 ₼",
+				OriginalPrice:  "2.099,99 ₼",
+				Discount:       "-12%",
+				Currency:       "AZN",
+				Availability:   "Mövcuddur",
+				Rating:         "4.5",
+				ReviewCount:    "7 Rəy",
+				Brand:          "Apple",
+				InternalMemory: "128 GB",
+				RAM:            "6 GB",
+				MainCamera:     "48 MP + 12 MP",
+				FrontCamera:    "12 MP",
+				Processor:      "Apple A16 Bionic",
+				OS:             "iOS 17",
+				Display:        "Super Retina XDR OLED",
+				Specs: map[string]SpecValue{
+					"brand":           {Label: "Brend", Value: "Apple"},
+					"internal_memory": {Label: "Daxili yaddaş", Value: "128 GB", Quantity: quantity(128<<30, UnitBytes)},
+					"ram":             {Label: "Operativ yaddaş", Value: "6 GB", Quantity: quantity(6<<30, UnitBytes)},
+					"main_camera":     {Label: "Əsas kamera", Value: "48 MP + 12 MP", Quantity: quantity(48, UnitMegapixels)},
+					"front_camera":    {Label: "Ön kamera", Value: "12 MP", Quantity: quantity(12, UnitMegapixels)},
+					"processor":       {Label: "Prosessor", Value: "Apple A16 Bionic"},
+					"os":              {Label: "Əməliyyat sistemi", Value: "iOS 17"},
+					"display_type":    {Label: "Displey növü", Value: "Super Retina XDR OLED"},
+				},
+			},
+			minor: 184999,
+		},
+		{
+			fixture: "optimal_no_discount.html",
+			want: Product{
+				Name:         "Xiaomi Redmi Note 13 8/256 GB",
+				SKU:          "RN13-8256",
+				CurrentPrice: "499,99 ₼",
+				Currency:     "AZN",
+				Availability: "Stokda yoxdur",
+				Brand:        "Xiaomi",
+				RAM:          "8 GB",
+				Specs: map[string]SpecValue{
+					"brand": {Label: "Brend", Value: "Xiaomi"},
+					"ram":   {Label: "Operativ yaddaş", Value: "8 GB", Quantity: quantity(8<<30, UnitBytes)},
+				},
+			},
+			minor: 49999,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.fixture, func(t *testing.T) {
+			html, err := os.ReadFile(filepath.Join("testdata", tt.fixture))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			url := "https://optimal.az/" + tt.fixture
+			got, err := NewOptimalScraper().parse(url, string(html))
+			if err != nil {
+				t.Fatalf("parse: %v", err)
+			}
+
+			if got.CurrentPriceValue == nil || got.CurrentPriceValue.Minor != tt.minor {
+				t.Errorf("CurrentPriceValue = %v, want %d minor units", got.CurrentPriceValue, tt.minor)
+			}
+
+			// Compare the display fields only
+			tt.want.URL, tt.want.Site = url, "optimal"
+			got.ScrapedAt = ""
+			got.CurrentPriceValue, got.OriginalPriceValue, got.DiscountValue, got.DiscountPercent = nil, nil, nil, 0
+			if !reflect.DeepEqual(*got, tt.want) {
+				t.Errorf("parse mismatch\n got: %+v\nwant: %+v", *got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOptimalRegistered(t *testing.T) {
+	s, err := GetScraper("optimal")
+	if err != nil {
+		t.Fatalf("optimal scraper not registered: %v", err)
+	}
+	if !s.IsValidURL("https://optimal.az/apple-iphone-15-128-gb-black") {
+		t.Error("expected optimal.az URL to be valid")
+	}
+}
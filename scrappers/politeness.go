@@ -0,0 +1,275 @@
+package scrappers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/temoto/robotstxt"
+	"golang.org/x/time/rate"
+)
+
+// ErrDisallowedByRobots is returned when robots.txt forbids fetching a URL
+var ErrDisallowedByRobots = errors.New("disallowed by robots.txt")
+
+// ErrRateLimitWait is returned when the wait for a host's rate limit would
+// not end before the context's deadline
+var ErrRateLimitWait = errors.New("rate limit wait exceeds deadline")
+
+// robotsAgent is the user-agent token matched against robots.txt groups
+const robotsAgent = "ucuzu"
+
+// robotsTTL is how long a host's robots.txt is cached
+const robotsTTL = 6 * time.Hour
+
+// SiteLimits are the politeness settings applied to one site
+type SiteLimits struct {
+	// RPS is the sustained request rate; zero disables rate limiting
+	RPS float64 `json:"rps"`
+
+	// Burst is the number of requests allowed at once before RPS applies
+	Burst int `json:"burst"`
+
+	// MaxConcurrency caps requests in flight to the host; zero means unlimited
+	MaxConcurrency int `json:"max_concurrency"`
+
+	// RespectRobots enables robots.txt rules and Crawl-delay
+	RespectRobots bool `json:"respect_robots"`
+}
+
+// defaultSiteLimits are the built-in limits per site identifier. Kontakt is
+// rendered in Chrome and has soft-blocked us before, so it gets less.
+var defaultSiteLimits = map[string]SiteLimits{
+	"kontakt": {RPS: 0.5, Burst: 1, MaxConcurrency: 2},
+}
+
+// fallbackSiteLimits apply to sites without an explicit entry
+var fallbackSiteLimits = SiteLimits{RPS: 1, Burst: 3, MaxConcurrency: 4}
+
+// hostState holds the limiter state of one host
+type hostState struct {
+	limiter *rate.Limiter
+	slots   chan struct{}
+}
+
+// robotsEntry is a cached robots.txt
+type robotsEntry struct {
+	group     *robotstxt.Group // nil allows everything
+	fetchedAt time.Time
+}
+
+// HostLimiter enforces per-host rate limits, concurrency caps and
+// robots.txt for every fetch, whether over HTTP or through Chrome
+type HostLimiter struct {
+	// RobotsEverywhere enables robots.txt for every site regardless of its limits
+	RobotsEverywhere bool
+
+	mu     sync.Mutex
+	limits map[string]SiteLimits // by site identifier
+	hosts  map[string]*hostState
+	robots map[string]*robotsEntry
+	client *http.Client
+}
+
+// NewHostLimiter creates a limiter using the given per-site limits on top of
+// the built-in defaults
+func NewHostLimiter(limits map[string]SiteLimits) *HostLimiter {
+	merged := make(map[string]SiteLimits, len(defaultSiteLimits)+len(limits))
+	for site, l := range defaultSiteLimits {
+		merged[site] = l
+	}
+	for site, l := range limits {
+		merged[site] = l
+	}
+
+	return &HostLimiter{
+		limits: merged,
+		hosts:  make(map[string]*hostState),
+		robots: make(map[string]*robotsEntry),
+		client: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+var (
+	sharedLimiter     *HostLimiter
+	sharedLimiterOnce sync.Once
+)
+
+// SharedHostLimiter returns the process-wide limiter. Limits can be
+// overridden with SITE_LIMITS, a JSON object keyed by site identifier, e.g.
+// {"irshad":{"rps":2,"burst":4,"max_concurrency":4,"respect_robots":true}}.
+// RESPECT_ROBOTS=1 turns on robots.txt for every site.
+func SharedHostLimiter() *HostLimiter {
+	sharedLimiterOnce.Do(func() {
+		var limits map[string]SiteLimits
+		if raw := os.Getenv("SITE_LIMITS"); raw != "" {
+			if err := json.Unmarshal([]byte(raw), &limits); err != nil {
+				log.Printf("Ignoring invalid SITE_LIMITS: %v", err)
+				limits = nil
+			}
+		}
+		sharedLimiter = NewHostLimiter(limits)
+		sharedLimiter.RobotsEverywhere = os.Getenv("RESPECT_ROBOTS") == "1"
+	})
+	return sharedLimiter
+}
+
+// SetSiteLimits changes the limits of a site. New rates apply immediately;
+// concurrency caps of hosts already seen are kept until restart.
+func (l *HostLimiter) SetSiteLimits(site string, limits SiteLimits) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.limits[site] = limits
+}
+
+// limitsFor returns the limits of the site owning rawURL
+func (l *HostLimiter) limitsFor(rawURL string) SiteLimits {
+	site, _, _ := DetectScraper(rawURL)
+
+	l.mu.Lock()
+	limits, ok := l.limits[site]
+	l.mu.Unlock()
+	if !ok {
+		limits = fallbackSiteLimits
+	}
+	if l.RobotsEverywhere {
+		limits.RespectRobots = true
+	}
+	return limits
+}
+
+// Acquire blocks until a request to rawURL may be made and returns a release
+// function that must be called once the request is finished
+func (l *HostLimiter) Acquire(ctx context.Context, rawURL string) (func(), error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("invalid URL %q", rawURL)
+	}
+	host := strings.ToLower(u.Host)
+	limits := l.limitsFor(rawURL)
+
+	var crawlDelay time.Duration
+	if limits.RespectRobots {
+		group := l.robotsGroup(ctx, u)
+		if group != nil {
+			// Rules such as "Disallow: /*?sort=" match the query too
+			path := u.EscapedPath()
+			if u.RawQuery != "" {
+				path += "?" + u.RawQuery
+			}
+			if !group.Test(path) {
+				return nil, fmt.Errorf("%w: %s", ErrDisallowedByRobots, rawURL)
+			}
+			crawlDelay = group.CrawlDelay
+		}
+	}
+
+	state := l.hostState(host, limits, crawlDelay)
+
+	if state.slots != nil {
+		select {
+		case state.slots <- struct{}{}:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	release := func() {
+		if state.slots != nil {
+			<-state.slots
+		}
+	}
+
+	if state.limiter != nil {
+		if err := state.limiter.Wait(ctx); err != nil {
+			release()
+			if ctxErr := ctx.Err(); ctxErr != nil {
+				return nil, ctxErr
+			}
+			return nil, fmt.Errorf("%w: %v", ErrRateLimitWait, err)
+		}
+	}
+
+	var once sync.Once
+	return func() { once.Do(release) }, nil
+}
+
+// hostState returns the limiter state of host, creating it on first use.
+// A robots.txt Crawl-delay lowers the rate to at most one request per delay.
+func (l *HostLimiter) hostState(host string, limits SiteLimits, crawlDelay time.Duration) *hostState {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	rps := limits.RPS
+	if crawlDelay > 0 {
+		if delayRPS := 1 / crawlDelay.Seconds(); rps <= 0 || delayRPS < rps {
+			rps = delayRPS
+		}
+	}
+
+	state, ok := l.hosts[host]
+	if !ok {
+		state = &hostState{}
+		if limits.MaxConcurrency > 0 {
+			state.slots = make(chan struct{}, limits.MaxConcurrency)
+		}
+		l.hosts[host] = state
+	}
+
+	// The rate may change when robots.txt is refreshed
+	if rps > 0 {
+		burst := limits.Burst
+		if burst <= 0 || crawlDelay > 0 {
+			burst = 1
+		}
+		if state.limiter == nil {
+			state.limiter = rate.NewLimiter(rate.Limit(rps), burst)
+		} else if state.limiter.Limit() != rate.Limit(rps) {
+			state.limiter.SetLimit(rate.Limit(rps))
+			state.limiter.SetBurst(burst)
+		}
+	}
+	return state
+}
+
+// robotsGroup returns the robots.txt rules that apply to us on u's host,
+// fetching and caching them as needed. Unreachable robots.txt allows all.
+func (l *HostLimiter) robotsGroup(ctx context.Context, u *url.URL) *robotstxt.Group {
+	key := u.Scheme + "://" + strings.ToLower(u.Host)
+
+	l.mu.Lock()
+	entry, ok := l.robots[key]
+	l.mu.Unlock()
+	if ok && time.Since(entry.fetchedAt) < robotsTTL {
+		return entry.group
+	}
+
+	entry = &robotsEntry{fetchedAt: time.Now()}
+	req, err := http.NewRequestWithContext(ctx, "GET", key+"/robots.txt", nil)
+	if err == nil {
+		req.Header.Set("User-Agent", DefaultFetcherConfig().Headers["User-Agent"])
+		if resp, err := l.client.Do(req); err == nil {
+			body, _ := io.ReadAll(io.LimitReader(resp.Body, 512<<10))
+			resp.Body.Close()
+			if data, err := robotstxt.FromStatusAndBytes(resp.StatusCode, body); err == nil {
+				entry.group = data.FindGroup(robotsAgent)
+			}
+		} else if ctx.Err() != nil {
+			// Do not cache a lookup the caller abandoned
+			return nil
+		}
+	}
+
+	l.mu.Lock()
+	l.robots[key] = entry
+	l.mu.Unlock()
+	return entry.group
+}
@@ -0,0 +1,67 @@
+package scrappers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestHostLimiterRobots(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/robots.txt" {
+			w.Write([]byte("User-agent: *\nDisallow: /private\nDisallow: /*?sort=\nCrawl-delay: 1\n"))
+			return
+		}
+		w.Write([]byte("ok"))
+	}))
+	defer srv.Close()
+
+	l := NewHostLimiter(nil)
+	l.RobotsEverywhere = true
+
+	if _, err := l.Acquire(context.Background(), srv.URL+"/private/page"); !errors.Is(err, ErrDisallowedByRobots) {
+		t.Fatalf("expected ErrDisallowedByRobots, got %v", err)
+	}
+	if _, err := l.Acquire(context.Background(), srv.URL+"/catalog?sort=price"); !errors.Is(err, ErrDisallowedByRobots) {
+		t.Fatalf("expected the query rule to apply, got %v", err)
+	}
+
+	// Crawl-delay: 1 allows one request per second
+	start := time.Now()
+	for i := 0; i < 2; i++ {
+		release, err := l.Acquire(context.Background(), srv.URL+"/product?page=2")
+		if err != nil {
+			t.Fatal(err)
+		}
+		release()
+	}
+	if elapsed := time.Since(start); elapsed < 900*time.Millisecond {
+		t.Errorf("second request was not delayed by Crawl-delay, took %v", elapsed)
+	}
+}
+
+func TestHostLimiterConcurrency(t *testing.T) {
+	l := NewHostLimiter(nil)
+	l.SetSiteLimits("kontakt", SiteLimits{MaxConcurrency: 1})
+
+	release, err := l.Acquire(context.Background(), "https://kontakt.az/a")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := l.Acquire(ctx, "https://kontakt.az/b"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected second request to wait for a slot, got %v", err)
+	}
+
+	release()
+	release2, err := l.Acquire(context.Background(), "https://kontakt.az/b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	release2()
+}
@@ -0,0 +1,317 @@
+package scrappers
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Canonical units of a Quantity
+const (
+	UnitBytes         = "B"
+	UnitMegapixels    = "MP"
+	UnitKilograms     = "kg"
+	UnitInches        = "inch"
+	UnitMilliampHours = "mAh"
+	UnitHertz         = "Hz"
+)
+
+// Quantity is a measured amount in a canonical unit, so that values written
+// as "1 TB" and "1024 GB", or "171 q" and "0,171 kg", compare equal
+type Quantity struct {
+	Value float64 `json:"value"`
+	Unit  string  `json:"unit"`
+}
+
+// String formats the quantity with its unit
+func (q Quantity) String() string {
+	return strconv.FormatFloat(q.Value, 'f', -1, 64) + " " + q.Unit
+}
+
+// quantityUnit is one spelling of a unit and its scale to the canonical unit
+type quantityUnit struct {
+	unit  string
+	scale float64
+
+	// contextual spellings, such as "g" or "in", are too common in other
+	// words ("5G", "2 in 1") to be read unless the value is known to be
+	// in that unit
+	contextual bool
+}
+
+// quantityUnits are the lowercased unit spellings stores use, in English,
+// Azerbaijani and Russian. Memory sizes are binary, 1 GB being 1024 MB, as
+// stores use them for both RAM and storage.
+var quantityUnits = map[string]quantityUnit{
+	"b": {UnitBytes, 1, true}, "byte": {UnitBytes, 1, false}, "bayt": {UnitBytes, 1, false}, "байт": {UnitBytes, 1, false},
+	"kb": {UnitBytes, 1 << 10, false}, "кб": {UnitBytes, 1 << 10, false},
+	"mb": {UnitBytes, 1 << 20, false}, "мб": {UnitBytes, 1 << 20, false}, "meqabayt": {UnitBytes, 1 << 20, false},
+	"gb": {UnitBytes, 1 << 30, false}, "qb": {UnitBytes, 1 << 30, false}, "гб": {UnitBytes, 1 << 30, false},
+	"giqabayt": {UnitBytes, 1 << 30, false}, "gigabayt": {UnitBytes, 1 << 30, false},
+	"tb": {UnitBytes, 1 << 40, false}, "тб": {UnitBytes, 1 << 40, false}, "terabayt": {UnitBytes, 1 << 40, false},
+
+	"mp": {UnitMegapixels, 1, false}, "mpx": {UnitMegapixels, 1, false}, "мп": {UnitMegapixels, 1, false},
+	"megapixel": {UnitMegapixels, 1, false}, "megapiksel": {UnitMegapixels, 1, false}, "meqapiksel": {UnitMegapixels, 1, false},
+	"мегапиксель": {UnitMegapixels, 1, false}, "мегапикселя": {UnitMegapixels, 1, false}, "мегапикселей": {UnitMegapixels, 1, false},
+
+	"kg": {UnitKilograms, 1, false}, "kq": {UnitKilograms, 1, false}, "кг": {UnitKilograms, 1, false},
+	"g": {UnitKilograms, 0.001, true}, "q": {UnitKilograms, 0.001, true}, "г": {UnitKilograms, 0.001, true},
+	"qr": {UnitKilograms, 0.001, false}, "qram": {UnitKilograms, 0.001, false}, "gram": {UnitKilograms, 0.001, false}, "гр": {UnitKilograms, 0.001, false},
+
+	`"`: {UnitInches, 1, false}, "″": {UnitInches, 1, false}, "”": {UnitInches, 1, false},
+	"inch": {UnitInches, 1, false}, "in": {UnitInches, 1, true}, "düym": {UnitInches, 1, false}, "duym": {UnitInches, 1, false},
+	"дюйм": {UnitInches, 1, false}, "дюйма": {UnitInches, 1, false}, "дюймов": {UnitInches, 1, false},
+	"cm": {UnitInches, 1 / 2.54, true}, "sm": {UnitInches, 1 / 2.54, true}, "см": {UnitInches, 1 / 2.54, true},
+
+	"mah": {UnitMilliampHours, 1, false}, "mas": {UnitMilliampHours, 1, false}, "мач": {UnitMilliampHours, 1, false},
+
+	"hz": {UnitHertz, 1, false}, "hs": {UnitHertz, 1, false}, "гц": {UnitHertz, 1, false},
+	"khz": {UnitHertz, 1e3, false}, "khs": {UnitHertz, 1e3, false}, "кгц": {UnitHertz, 1e3, false},
+	"mhz": {UnitHertz, 1e6, false}, "mhs": {UnitHertz, 1e6, false}, "мгц": {UnitHertz, 1e6, false},
+	"ghz": {UnitHertz, 1e9, false}, "ghs": {UnitHertz, 1e9, false}, "ггц": {UnitHertz, 1e9, false},
+}
+
+// quantityPattern finds an amount, with a decimal point or comma and
+// thousands groups, and the unit written after it, e.g. "6,1 düym", "1TB",
+// `6.1"` or "5,000 mAh"
+var quantityPattern = regexp.MustCompile(`(\d+(?:[.,]\d+)*)\s*("|″|”|\p{L}+)?`)
+
+// spacedThousandsPattern matches an amount opening a value with its
+// thousands separated by spaces, e.g. "5 000 mAh". Elsewhere, as in
+// "iPhone 15 128 GB", spaces separate different numbers.
+var spacedThousandsPattern = regexp.MustCompile(`^(\d{1,3}(?:[ \x{a0}\x{202f}]\d{3})+)(?:\D|$)`)
+
+// ParseQuantity reads the first amount in s written with a unit it knows,
+// skipping amounts without one, as in "8/256 GB" or "2 x 12 MP"
+func ParseQuantity(s string) (Quantity, bool) {
+	return scanQuantity(s, "")
+}
+
+// parseQuantityIn reads the first amount in s measured like the unit
+// spelling as, e.g. "gb" for memory. A value that is just a number is
+// taken to be in as.
+func parseQuantityIn(s, as string) (Quantity, bool) {
+	return scanQuantity(s, strings.ToLower(as))
+}
+
+func scanQuantity(s, as string) (Quantity, bool) {
+	s = strings.TrimSpace(s)
+	if m := spacedThousandsPattern.FindStringSubmatchIndex(s); m != nil {
+		s = strings.Join(strings.Fields(s[:m[3]]), "") + s[m[3]:]
+	}
+
+	expected, hasExpected := quantityUnits[as]
+	for _, match := range quantityPattern.FindAllStringSubmatch(s, -1) {
+		var unit quantityUnit
+		if match[2] == "" {
+			// Only a value that is just a number is in the expected unit
+			if !hasExpected || s != strings.TrimSpace(match[0]) {
+				continue
+			}
+			unit = expected
+		} else {
+			var ok bool
+			if unit, ok = quantityUnits[strings.ToLower(match[2])]; !ok {
+				continue
+			}
+			if hasExpected && unit.unit != expected.unit {
+				continue
+			}
+			if unit.contextual && !hasExpected {
+				continue
+			}
+		}
+
+		number, ok := unit.parseAmount(match[1])
+		if !ok {
+			continue
+		}
+		return Quantity{Value: number * unit.scale, Unit: unit.unit}, true
+	}
+	return Quantity{}, false
+}
+
+// parseAmount reads an amount written in u. Kilograms, inches and
+// megapixels are never in the thousands, so a single separator is their
+// decimal point, as in "1.375 kq". Other amounts follow the same rules as
+// prices, reading "5,000 mAh" as five thousand; an ambiguous amount such as
+// "1.2345" is skipped rather than guessed.
+func (u quantityUnit) parseAmount(raw string) (float64, bool) {
+	digits := raw
+	small := (u.unit == UnitKilograms && u.scale == 1) || u.unit == UnitInches || u.unit == UnitMegapixels
+	if separators := strings.Count(raw, ".") + strings.Count(raw, ","); small && separators == 1 {
+		digits = strings.Replace(raw, ",", ".", 1)
+	} else {
+		var err error
+		if digits, err = normalizeDecimal(raw); err != nil {
+			return 0, false
+		}
+	}
+	number, err := strconv.ParseFloat(digits, 64)
+	return number, err == nil
+}
+
+// nameQuantityKeys are the specifications a quantity in a product name
+// describes, by its unit
+var nameQuantityKeys = map[string]string{
+	UnitBytes:         "internal_memory",
+	UnitMegapixels:    "main_camera",
+	UnitInches:        "screen_size",
+	UnitMilliampHours: "battery",
+	UnitHertz:         "refresh_rate",
+	UnitKilograms:     "weight",
+}
+
+// nameMemoryPattern matches "8/256 GB" style RAM and storage pairs in names
+// and specification values
+var nameMemoryPattern = regexp.MustCompile(`(?i)\b(\d{1,2})\s*(?:gb|qb|гб)?\s*/\s*(\d{2,4}\s*(?:gb|qb|гб|tb|тб))`)
+
+// NameQuantities reads the specifications a product name states, such as
+// "Galaxy A55 8/256 GB" or `iPhone 15 6.1" 128 GB`, keyed like Product.Specs.
+// Of memory sizes written separately, as in "MacBook Air 8GB 256GB", the
+// largest is taken to be the storage and a smaller one the RAM.
+func NameQuantities(name string) map[string]Quantity {
+	quantities := make(map[string]Quantity)
+	if m := nameMemoryPattern.FindStringSubmatch(name); m != nil {
+		if q, ok := parseQuantityIn(m[1], "gb"); ok {
+			quantities["ram"] = q
+		}
+		if q, ok := ParseQuantity(m[2]); ok {
+			quantities["internal_memory"] = q
+		}
+		name = strings.Replace(name, m[0], " ", 1)
+	}
+	var memory []Quantity
+	for _, match := range quantityPattern.FindAllString(name, -1) {
+		q, ok := ParseQuantity(match)
+		if !ok {
+			continue
+		}
+		if q.Unit == UnitBytes {
+			memory = append(memory, q)
+			continue
+		}
+		if key := nameQuantityKeys[q.Unit]; key != "" {
+			if _, exists := quantities[key]; !exists {
+				quantities[key] = q
+			}
+		}
+	}
+	addNameMemory(quantities, memory)
+	if len(quantities) == 0 {
+		return nil
+	}
+	return quantities
+}
+
+// addNameMemory stores the largest of the memory sizes a name lists as the
+// storage and, when there is a smaller one, the smallest as the RAM, unless
+// a "8/256 GB" pair already gave them
+func addNameMemory(quantities map[string]Quantity, memory []Quantity) {
+	if len(memory) == 0 {
+		return
+	}
+	largest, smallest := memory[0], memory[0]
+	for _, q := range memory[1:] {
+		if q.Value > largest.Value {
+			largest = q
+		}
+		if q.Value < smallest.Value {
+			smallest = q
+		}
+	}
+	if _, exists := quantities["internal_memory"]; !exists {
+		quantities["internal_memory"] = largest
+	}
+	if _, exists := quantities["ram"]; !exists && smallest.Value < largest.Value {
+		quantities["ram"] = smallest
+	}
+}
+
+// SpecFilter selects products by a measured specification, e.g. "ram>=8GB"
+type SpecFilter struct {
+	Key   string
+	Op    string
+	Value Quantity
+}
+
+// specFilterPattern splits a filter into key, operator and amount
+var specFilterPattern = regexp.MustCompile(`^\s*([^<>=!]+?)\s*(>=|<=|!=|=|>|<)\s*(.+?)\s*$`)
+
+// ParseSpecFilter reads a filter written as <key><op><amount>, where op is
+// one of >=, <=, >, <, = and !=. The key may be any label the spec
+// dictionary knows, and an amount without a unit is in the key's usual
+// unit, so "ram>=8" means 8 GB.
+func ParseSpecFilter(s string) (SpecFilter, error) {
+	m := specFilterPattern.FindStringSubmatch(s)
+	if m == nil {
+		return SpecFilter{}, fmt.Errorf("invalid filter '%s', expected a comparison such as ram>=8GB", s)
+	}
+	key := NormalizeSpecKey(m[1])
+	if key == "" {
+		return SpecFilter{}, fmt.Errorf("invalid filter '%s': missing specification", s)
+	}
+
+	var value Quantity
+	var ok bool
+	if unit := specUnits[key]; unit != "" {
+		value, ok = parseQuantityIn(m[3], unit)
+	} else {
+		value, ok = ParseQuantity(m[3])
+	}
+	if !ok {
+		return SpecFilter{}, fmt.Errorf("invalid filter '%s': '%s' is not an amount with a known unit", s, m[3])
+	}
+	return SpecFilter{Key: key, Op: m[2], Value: value}, nil
+}
+
+// ParseSpecFilters reads every filter of filters
+func ParseSpecFilters(filters []string) ([]SpecFilter, error) {
+	parsed := make([]SpecFilter, 0, len(filters))
+	for _, s := range filters {
+		f, err := ParseSpecFilter(s)
+		if err != nil {
+			return nil, err
+		}
+		parsed = append(parsed, f)
+	}
+	return parsed, nil
+}
+
+// Match reports whether quantities has the filtered specification in the
+// filter's unit and it satisfies the comparison
+func (f SpecFilter) Match(quantities map[string]Quantity) bool {
+	q, ok := quantities[f.Key]
+	if !ok || q.Unit != f.Value.Unit {
+		return false
+	}
+	// Conversions such as cm to inches are not exact
+	equal := math.Abs(q.Value-f.Value.Value) <= 1e-9*math.Max(math.Abs(q.Value), math.Abs(f.Value.Value))
+	switch f.Op {
+	case "=":
+		return equal
+	case "!=":
+		return !equal
+	case ">=":
+		return equal || q.Value > f.Value.Value
+	case "<=":
+		return equal || q.Value < f.Value.Value
+	case ">":
+		return !equal && q.Value > f.Value.Value
+	case "<":
+		return !equal && q.Value < f.Value.Value
+	}
+	return false
+}
+
+// MatchSpecFilters reports whether quantities satisfy every filter
+func MatchSpecFilters(filters []SpecFilter, quantities map[string]Quantity) bool {
+	for _, f := range filters {
+		if !f.Match(quantities) {
+			return false
+		}
+	}
+	return true
+}
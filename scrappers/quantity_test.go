@@ -0,0 +1,169 @@
+package scrappers
+
+import (
+	"reflect"
+	"testing"
+)
+
+// quantity returns a pointer to a Quantity, for SpecValue.Quantity
+func quantity(value float64, unit string) *Quantity {
+	return &Quantity{Value: value, Unit: unit}
+}
+
+func TestParseQuantity(t *testing.T) {
+	tests := []struct {
+		in   string
+		want *Quantity
+	}{
+		{"128 GB", quantity(128<<30, UnitBytes)},
+		{"1 TB", quantity(1<<40, UnitBytes)},
+		{"256 QB", quantity(256<<30, UnitBytes)},
+		{"512 МБ", quantity(512<<20, UnitBytes)},
+		{"12 MP", quantity(12, UnitMegapixels)},
+		{"50 Мп + 12 Мп", quantity(50, UnitMegapixels)},
+		{"3,7 kq", quantity(3.7, UnitKilograms)},
+		{"1.2 кг", quantity(1.2, UnitKilograms)},
+		{"250 qr", quantity(0.25, UnitKilograms)},
+		{`6.1"`, quantity(6.1, UnitInches)},
+		{"6,7 düym", quantity(6.7, UnitInches)},
+		{"5000 mAh", quantity(5000, UnitMilliampHours)},
+		{"5,000 mAh", quantity(5000, UnitMilliampHours)},
+		{"5 000 mAh", quantity(5000, UnitMilliampHours)},
+		{"1.000.000 bayt", quantity(1e6, UnitBytes)},
+		{"0,171 kq", quantity(0.171, UnitKilograms)},
+		{"1.375 kq", quantity(1.375, UnitKilograms)},
+		{"1,234 kg", quantity(1.234, UnitKilograms)},
+		{"1.234 kg", quantity(1.234, UnitKilograms)},
+		{"1.375 qr", quantity(1.375, UnitKilograms)},
+		{"15.600 düym", quantity(15.6, UnitInches)},
+		{"50.000 MP", quantity(50, UnitMegapixels)},
+		{"1.024 GB", quantity(1024<<30, UnitBytes)},
+		{"1,200 Hz", quantity(1200, UnitHertz)},
+		{"4500 mAs", quantity(4500, UnitMilliampHours)},
+		{"120 Hs", quantity(120, UnitHertz)},
+		{"2,4 ГГц", quantity(2.4e9, UnitHertz)},
+		{"8/256 GB", quantity(256<<30, UnitBytes)},
+		{"5G", nil},
+		{"2 in 1", nil},
+		{"12 ay", nil},
+		{"Apple A16 Bionic", nil},
+	}
+
+	for _, tt := range tests {
+		q, ok := ParseQuantity(tt.in)
+		if tt.want == nil {
+			if ok {
+				t.Errorf("ParseQuantity(%q) = %v, want none", tt.in, q)
+			}
+			continue
+		}
+		if !ok || q != *tt.want {
+			t.Errorf("ParseQuantity(%q) = %v, %v; want %v", tt.in, q, ok, *tt.want)
+		}
+	}
+}
+
+func TestNameQuantities(t *testing.T) {
+	tests := []struct {
+		name string
+		want map[string]Quantity
+	}{
+		{"Samsung Galaxy A55 8/256 GB Navy", map[string]Quantity{
+			"ram":             {8 << 30, UnitBytes},
+			"internal_memory": {256 << 30, UnitBytes},
+		}},
+		{`Apple iPhone 15 6.1" 128 GB Black`, map[string]Quantity{
+			"screen_size":     {6.1, UnitInches},
+			"internal_memory": {128 << 30, UnitBytes},
+		}},
+		{"Xiaomi Redmi 13C 5G 6000 mAh 50 MP", map[string]Quantity{
+			"battery":     {6000, UnitMilliampHours},
+			"main_camera": {50, UnitMegapixels},
+		}},
+		{"Apple iPhone 15 128 GB", map[string]Quantity{
+			"internal_memory": {128 << 30, UnitBytes},
+		}},
+		{"MacBook Air 13 M2 8GB 256GB", map[string]Quantity{
+			"ram":             {8 << 30, UnitBytes},
+			"internal_memory": {256 << 30, UnitBytes},
+		}},
+		{"Lenovo IdeaPad 16GB RAM 512GB SSD", map[string]Quantity{
+			"ram":             {16 << 30, UnitBytes},
+			"internal_memory": {512 << 30, UnitBytes},
+		}},
+		{"Lenovo IdeaPad 15.6\" 1TB SSD 16 GB", map[string]Quantity{
+			"screen_size":     {15.6, UnitInches},
+			"ram":             {16 << 30, UnitBytes},
+			"internal_memory": {1 << 40, UnitBytes},
+		}},
+		{"Sony PlayStation 5", nil},
+	}
+
+	for _, tt := range tests {
+		if got := NameQuantities(tt.name); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("NameQuantities(%q) = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestSpecValueQuantity(t *testing.T) {
+	tests := []struct {
+		label, value string
+		want         *Quantity
+	}{
+		{"Batareya", "5,000 mAh", quantity(5000, UnitMilliampHours)},
+		{"Batareya", "5 000 mAh", quantity(5000, UnitMilliampHours)},
+		{"Operativ yaddaş", "8/256 GB", quantity(8<<30, UnitBytes)},
+		{"Operativ yaddaş", "8 GB / 256 GB", quantity(8<<30, UnitBytes)},
+		{"Daxili yaddaş", "8/256 GB", quantity(256<<30, UnitBytes)},
+	}
+
+	for _, tt := range tests {
+		_, spec := NewSpecValue(tt.label, tt.value)
+		if !reflect.DeepEqual(spec.Quantity, tt.want) {
+			t.Errorf("NewSpecValue(%q, %q).Quantity = %v, want %v", tt.label, tt.value, spec.Quantity, tt.want)
+		}
+	}
+}
+
+func TestSpecFilter(t *testing.T) {
+	quantities := map[string]Quantity{
+		"ram":         {8 << 30, UnitBytes},
+		"screen_size": {6.1, UnitInches},
+		"weight":      {0.171, UnitKilograms},
+	}
+
+	tests := []struct {
+		filter string
+		want   bool
+	}{
+		{"ram>=8GB", true},
+		{"ram >= 8", true},
+		{"RAM>8GB", false},
+		{"operativ yaddaş<12 GB", true},
+		{"ram=8192 MB", true},
+		{"ram!=8GB", false},
+		{"screen_size<=6.1", true},
+		{"screen_size<15.5 cm", true},
+		{"weight<200 q", true},
+		{"weight<=0,17 kq", false},
+		{"internal_memory>=128GB", false},
+	}
+
+	for _, tt := range tests {
+		f, err := ParseSpecFilter(tt.filter)
+		if err != nil {
+			t.Errorf("ParseSpecFilter(%q): %v", tt.filter, err)
+			continue
+		}
+		if got := f.Match(quantities); got != tt.want {
+			t.Errorf("%q matched %v, want %v", tt.filter, got, tt.want)
+		}
+	}
+
+	for _, bad := range []string{"ram", ">=8GB", "ram>=lots", "ram>=12 MP"} {
+		if _, err := ParseSpecFilter(bad); err == nil {
+			t.Errorf("ParseSpecFilter(%q) accepted", bad)
+		}
+	}
+}
@@ -0,0 +1,199 @@
+package scrappers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/chromedp"
+)
+
+// readyPollInterval is how often readiness conditions are re-evaluated
+const readyPollInterval = 200 * time.Millisecond
+
+// challengeClearedJS is true once no Cloudflare interstitial is showing
+const challengeClearedJS = `!(document.title.indexOf('Just a moment') >= 0 ||
+	document.querySelector('#challenge-form, #challenge-running, #cf-challenge-running, .cf-browser-verification'))`
+
+// ReadySpec describes when a page rendered by chromedp is complete enough to
+// be captured. Conditions are checked in order: challenge cleared, selectors
+// present, network idle. A page still behind the challenge or without the
+// selectors after Timeout is an error; one whose network never settles is
+// captured as it is, so pages with endless background requests degrade
+// instead of failing.
+type ReadySpec struct {
+	// ChallengeCleared waits until the Cloudflare challenge page is gone
+	ChallengeCleared bool
+
+	// Selectors waits until any of these CSS selectors matches an element
+	Selectors []string
+
+	// NetworkIdle waits until no request has been in flight for this long
+	NetworkIdle time.Duration
+
+	// Timeout bounds the wait; only the network idle wait falls back to
+	// capturing the page when it expires
+	Timeout time.Duration
+}
+
+// errNetworkBusy marks a page that was otherwise ready but whose requests
+// did not settle
+var errNetworkBusy = errors.New("network still busy")
+
+// NavigateAndWait navigates the current tab to url and blocks until the page
+// satisfies the spec or its timeout expires
+func (r ReadySpec) NavigateAndWait(url string) chromedp.Action {
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		// Start watching the network before navigating so no request is missed
+		var idle *networkWatcher
+		if r.NetworkIdle > 0 {
+			listenCtx, stopListening := context.WithCancel(ctx)
+			defer stopListening()
+			idle = watchNetwork(listenCtx)
+		}
+
+		if err := chromedp.Navigate(url).Do(ctx); err != nil {
+			return err
+		}
+		return r.waitReady(ctx, idle)
+	})
+}
+
+// waitReady waits for the spec's conditions within Timeout. A network that
+// is still busy then is accepted; any other unmet condition is an error.
+func (r ReadySpec) waitReady(ctx context.Context, idle *networkWatcher) error {
+	waitCtx := ctx
+	if r.Timeout > 0 {
+		var cancel context.CancelFunc
+		waitCtx, cancel = context.WithTimeout(ctx, r.Timeout)
+		defer cancel()
+	}
+
+	start := time.Now()
+	err := r.wait(waitCtx, idle)
+	if err != nil && ctx.Err() == nil && waitCtx.Err() != nil {
+		if errors.Is(err, errNetworkBusy) {
+			// The content is there; capture it without waiting for the network
+			if os.Getenv("DEBUG") == "1" {
+				fmt.Printf("DEBUG: network not idle after %v, capturing anyway\n", r.Timeout)
+			}
+			return nil
+		}
+		return fmt.Errorf("page not ready after %v: %w", r.Timeout, err)
+	}
+	if err == nil && os.Getenv("DEBUG") == "1" {
+		fmt.Printf("DEBUG: page ready after %v\n", time.Since(start))
+	}
+	return err
+}
+
+// wait blocks until every configured condition holds
+func (r ReadySpec) wait(ctx context.Context, idle *networkWatcher) error {
+	if r.ChallengeCleared {
+		if err := waitForJS(ctx, challengeClearedJS); err != nil {
+			return fmt.Errorf("challenge page still showing: %w", err)
+		}
+	}
+
+	if len(r.Selectors) > 0 {
+		quoted, err := json.Marshal(strings.Join(r.Selectors, ", "))
+		if err != nil {
+			return err
+		}
+		if err := waitForJS(ctx, fmt.Sprintf("document.querySelector(%s) !== null", quoted)); err != nil {
+			return fmt.Errorf("none of %s appeared: %w", strings.Join(r.Selectors, ", "), err)
+		}
+	}
+
+	if idle != nil {
+		if err := idle.waitIdle(ctx, r.NetworkIdle); err != nil {
+			return fmt.Errorf("%w: %w", errNetworkBusy, err)
+		}
+	}
+	return nil
+}
+
+// evaluateJS evaluates a boolean expression in the tab of ctx; tests
+// replace it to run without a browser
+var evaluateJS = func(ctx context.Context, expression string) (bool, error) {
+	var ok bool
+	err := chromedp.Evaluate(expression, &ok).Do(ctx)
+	return ok, err
+}
+
+// waitForJS evaluates expression until it is true. Evaluation errors are
+// ignored because the page may be navigating (e.g. after a challenge).
+func waitForJS(ctx context.Context, expression string) error {
+	for {
+		if ok, err := evaluateJS(ctx, expression); err == nil && ok {
+			return nil
+		}
+		if err := sleepCtx(ctx, readyPollInterval); err != nil {
+			return err
+		}
+	}
+}
+
+// sleepCtx sleeps for d or until ctx is done
+func sleepCtx(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// networkWatcher tracks in-flight requests of a tab
+type networkWatcher struct {
+	mu           sync.Mutex
+	inflight     map[network.RequestID]struct{}
+	lastActivity time.Time
+}
+
+// watchNetwork starts tracking requests of the tab in ctx until ctx is done
+func watchNetwork(ctx context.Context) *networkWatcher {
+	w := &networkWatcher{
+		inflight:     make(map[network.RequestID]struct{}),
+		lastActivity: time.Now(),
+	}
+	chromedp.ListenTarget(ctx, func(ev interface{}) {
+		w.mu.Lock()
+		defer w.mu.Unlock()
+		switch e := ev.(type) {
+		case *network.EventRequestWillBeSent:
+			w.inflight[e.RequestID] = struct{}{}
+		case *network.EventLoadingFinished:
+			delete(w.inflight, e.RequestID)
+		case *network.EventLoadingFailed:
+			delete(w.inflight, e.RequestID)
+		default:
+			return
+		}
+		w.lastActivity = time.Now()
+	})
+	return w
+}
+
+// waitIdle blocks until no request has been in flight for quiet
+func (w *networkWatcher) waitIdle(ctx context.Context, quiet time.Duration) error {
+	for {
+		w.mu.Lock()
+		idle := len(w.inflight) == 0 && time.Since(w.lastActivity) >= quiet
+		w.mu.Unlock()
+		if idle {
+			return nil
+		}
+		if err := sleepCtx(ctx, readyPollInterval); err != nil {
+			return err
+		}
+	}
+}
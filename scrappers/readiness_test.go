@@ -0,0 +1,103 @@
+package scrappers
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/chromedp/cdproto/network"
+)
+
+// fakePage answers readiness expressions as a page would: the challenge
+// clears after clearAfter evaluations and the selector matches once present
+type fakePage struct {
+	challenge  bool
+	clearAfter int32
+	present    bool
+	evaluated  atomic.Int32
+}
+
+// install makes waitForJS evaluate against the fake page until the test ends
+func (p *fakePage) install(t *testing.T) {
+	previous := evaluateJS
+	evaluateJS = func(ctx context.Context, expression string) (bool, error) {
+		n := p.evaluated.Add(1)
+		if expression == challengeClearedJS {
+			return !p.challenge || n > p.clearAfter, nil
+		}
+		return p.present, nil
+	}
+	t.Cleanup(func() { evaluateJS = previous })
+}
+
+// busyNetwork returns a watcher with a request that never finishes
+func busyNetwork() *networkWatcher {
+	return &networkWatcher{
+		inflight:     map[network.RequestID]struct{}{"1": {}},
+		lastActivity: time.Now(),
+	}
+}
+
+func TestReadySpecWaitReady(t *testing.T) {
+	spec := ReadySpec{
+		ChallengeCleared: true,
+		Selectors:        []string{"div.price"},
+		NetworkIdle:      50 * time.Millisecond,
+		Timeout:          300 * time.Millisecond,
+	}
+
+	t.Run("ready", func(t *testing.T) {
+		page := &fakePage{challenge: true, clearAfter: 1, present: true}
+		page.install(t)
+		idle := &networkWatcher{inflight: map[network.RequestID]struct{}{}, lastActivity: time.Now().Add(-time.Second)}
+		if err := spec.waitReady(context.Background(), idle); err != nil {
+			t.Errorf("waitReady = %v", err)
+		}
+	})
+
+	t.Run("busy network falls back", func(t *testing.T) {
+		(&fakePage{present: true}).install(t)
+		if err := spec.waitReady(context.Background(), busyNetwork()); err != nil {
+			t.Errorf("waitReady = %v, want the page captured", err)
+		}
+	})
+
+	t.Run("challenge never clears", func(t *testing.T) {
+		(&fakePage{challenge: true, clearAfter: 1 << 30, present: true}).install(t)
+		err := spec.waitReady(context.Background(), nil)
+		if err == nil || !strings.Contains(err.Error(), "challenge") {
+			t.Errorf("waitReady = %v, want a challenge error", err)
+		}
+	})
+
+	t.Run("selector never appears", func(t *testing.T) {
+		(&fakePage{}).install(t)
+		err := spec.waitReady(context.Background(), nil)
+		if err == nil || !strings.Contains(err.Error(), "div.price") {
+			t.Errorf("waitReady = %v, want a selector error", err)
+		}
+	})
+
+	t.Run("caller cancelled", func(t *testing.T) {
+		(&fakePage{}).install(t)
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		if err := spec.waitReady(ctx, busyNetwork()); !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("waitReady = %v, want the caller's deadline", err)
+		}
+	})
+}
+
+func TestReadySpecWaitWithoutConditions(t *testing.T) {
+	page := &fakePage{}
+	page.install(t)
+	if err := (ReadySpec{}).wait(context.Background(), nil); err != nil {
+		t.Errorf("wait = %v", err)
+	}
+	if page.evaluated.Load() != 0 {
+		t.Error("evaluated expressions without conditions")
+	}
+}
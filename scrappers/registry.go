@@ -1,17 +1,32 @@
 package scrappers
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
 )
 
 // Product represents a standardized product structure for all scrapers
 type Product struct {
-	Name           string `json:"name"`
-	SKU            string `json:"sku,omitempty"`
-	CurrentPrice   string `json:"current_price"`
-	OriginalPrice  string `json:"original_price,omitempty"`
-	Discount       string `json:"discount,omitempty"`
-	Currency       string `json:"currency"`
+	Name          string `json:"name"`
+	SKU           string `json:"sku,omitempty"`
+	EAN           string `json:"ean,omitempty"` // manufacturer barcode, when the page exposes one
+	CurrentPrice  string `json:"current_price"`
+	OriginalPrice string `json:"original_price,omitempty"`
+	Discount      string `json:"discount,omitempty"`
+	Currency      string `json:"currency"`
+
+	// Typed counterparts of the display prices above, for sorting and comparison
+	CurrentPriceValue  *Money  `json:"current_price_value,omitempty"`
+	OriginalPriceValue *Money  `json:"original_price_value,omitempty"`
+	DiscountValue      *Money  `json:"discount_value,omitempty"`
+	DiscountPercent    float64 `json:"discount_percent,omitempty"`
+
 	Availability   string `json:"availability"`
 	Rating         string `json:"rating,omitempty"`
 	ReviewCount    string `json:"review_count,omitempty"`
@@ -23,9 +38,20 @@ type Product struct {
 	Processor      string `json:"processor,omitempty"`
 	OS             string `json:"os,omitempty"`
 	Display        string `json:"display,omitempty"`
-	URL            string `json:"url"`
-	Site           string `json:"site"`
-	ScrapedAt      string `json:"scraped_at"`
+
+	// Specs holds every specification row of the page by canonical key,
+	// such as "internal_memory" or "weight"
+	Specs map[string]SpecValue `json:"specs,omitempty"`
+
+	URL       string `json:"url"`
+	Site      string `json:"site"`
+	ScrapedAt string `json:"scraped_at"`
+}
+
+// ScrapeOptions controls a single scrape call
+type ScrapeOptions struct {
+	// Timeout bounds the whole scrape; zero means the scraper's default
+	Timeout time.Duration
 }
 
 // Scraper interface that all site scrapers must implement
@@ -33,6 +59,10 @@ type Scraper interface {
 	// Scrape extracts product information from the given URL
 	Scrape(url string) (*Product, error)
 
+	// ScrapeContext is like Scrape but stops as soon as ctx is cancelled
+	// or its deadline (or opts.Timeout) expires
+	ScrapeContext(ctx context.Context, url string, opts ScrapeOptions) (*Product, error)
+
 	// GetSiteName returns the name/identifier of the site
 	GetSiteName() string
 
@@ -40,41 +70,121 @@ type Scraper interface {
 	IsValidURL(url string) bool
 }
 
+// withTimeout derives a context bounded by opts.Timeout, or by fallback
+// when no timeout was requested
+func (opts ScrapeOptions) withTimeout(ctx context.Context, fallback time.Duration) (context.Context, context.CancelFunc) {
+	timeout := opts.Timeout
+	if timeout <= 0 {
+		timeout = fallback
+	}
+	if timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, timeout)
+}
+
 // SiteInfo contains information about a supported site
 type SiteInfo struct {
 	Name        string `json:"name"`
 	Identifier  string `json:"identifier"`
 	BaseURL     string `json:"base_url"`
 	Description string `json:"description"`
+	Searchable  bool   `json:"searchable"`
 }
 
-// scraperRegistry holds all registered scrapers
-var scraperRegistry = make(map[string]Scraper)
+var (
+	registryMu sync.RWMutex
+
+	// scraperRegistry holds all registered scrapers. It is copied on every
+	// change and never modified in place, so a snapshot taken by
+	// registeredScrapers can be read without holding the lock.
+	scraperRegistry = make(map[string]Scraper)
+)
+
+// registeredScrapers returns the current registry; callers must not modify it
+func registeredScrapers() map[string]Scraper {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	return scraperRegistry
+}
 
 // RegisterScraper registers a new scraper for a site
 func RegisterScraper(identifier string, scraper Scraper) {
-	scraperRegistry[identifier] = scraper
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	next := make(map[string]Scraper, len(scraperRegistry)+1)
+	for id, s := range scraperRegistry {
+		next[id] = s
+	}
+	next[identifier] = scraper
+	scraperRegistry = next
 }
 
 // GetScraper returns a scraper for the given site identifier
 func GetScraper(siteIdentifier string) (Scraper, error) {
-	scraper, exists := scraperRegistry[siteIdentifier]
+	scraper, exists := registeredScrapers()[siteIdentifier]
 	if !exists {
 		return nil, fmt.Errorf("no scraper found for site: %s", siteIdentifier)
 	}
 	return scraper, nil
 }
 
+// ErrUnsupportedSite is returned by DetectScraper when no scraper claims a URL
+var ErrUnsupportedSite = errors.New("unsupported site")
+
+// ErrAmbiguousSite is returned by DetectScraper when several scrapers claim a URL
+var ErrAmbiguousSite = errors.New("ambiguous site")
+
+// DetectScraper finds the registered scraper whose IsValidURL accepts rawURL
+// and returns it together with its site identifier
+func DetectScraper(rawURL string) (string, Scraper, error) {
+	registry := registeredScrapers()
+	var matches []string
+	for identifier, scraper := range registry {
+		if scraper.IsValidURL(rawURL) {
+			matches = append(matches, identifier)
+		}
+	}
+
+	switch len(matches) {
+	case 0:
+		return "", nil, fmt.Errorf("%w: no scraper accepts %s", ErrUnsupportedSite, rawURL)
+	case 1:
+		return matches[0], registry[matches[0]], nil
+	default:
+		sort.Strings(matches)
+		return "", nil, fmt.Errorf("%w: %s is claimed by %s", ErrAmbiguousSite, rawURL, strings.Join(matches, ", "))
+	}
+}
+
+// hostMatches reports whether rawURL is an http(s) URL whose host is domain
+// or one of its subdomains. Only the parsed host is checked, so URLs like
+// https://evil.com/?kontakt.az are rejected.
+func hostMatches(rawURL string, domain string) bool {
+	u, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil {
+		return false
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return false
+	}
+	host := strings.TrimSuffix(strings.ToLower(u.Hostname()), ".")
+	return host == domain || strings.HasSuffix(host, "."+domain)
+}
+
 // GetAvailableSites returns information about all supported sites
 func GetAvailableSites() []SiteInfo {
 	var sites []SiteInfo
 
-	for identifier, scraper := range scraperRegistry {
+	for identifier, scraper := range registeredScrapers() {
+		_, searchable := scraper.(Searcher)
 		sites = append(sites, SiteInfo{
 			Name:        scraper.GetSiteName(),
 			Identifier:  identifier,
 			BaseURL:     getBaseURL(identifier),
 			Description: fmt.Sprintf("Scraper for %s", scraper.GetSiteName()),
+			Searchable:  searchable,
 		})
 	}
 
@@ -83,10 +193,16 @@ func GetAvailableSites() []SiteInfo {
 
 // getBaseURL returns the base URL for known sites
 func getBaseURL(identifier string) string {
+	// Scrapers defined in config know their own
+	if s, ok := registeredScrapers()[identifier].(interface{ BaseURL() string }); ok {
+		return s.BaseURL()
+	}
+
 	baseURLs := map[string]string{
-		"kontakt": "https://kontakt.az",
-		"irshad":  "https://irshad.az",
-		"optimal": "https://optimal.az",
+		"kontakt":         "https://kontakt.az",
+		"irshad":          "https://irshad.az",
+		"optimal":         "https://optimal.az",
+		"bakuelectronics": "https://www.bakuelectronics.az",
 	}
 
 	if url, exists := baseURLs[identifier]; exists {
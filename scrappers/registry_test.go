@@ -0,0 +1,36 @@
+package scrappers
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestDetectScraper(t *testing.T) {
+	tests := []struct {
+		url  string
+		want string
+		err  error
+	}{
+		{"https://kontakt.az/iphone-13-128-gb-midnight", "kontakt", nil},
+		{"https://www.kontakt.az/iphone-13-128-gb-midnight", "kontakt", nil},
+		{"https://irshad.az/az/mehsullar/sony-playstation-5-slim-1tb", "irshad", nil},
+		{"https://www.bakuelectronics.az/mehsul/samsung-galaxy-a55", "bakuelectronics", nil},
+		{"https://optimal.az/apple-iphone-15-128-gb-black", "optimal", nil},
+		{"https://evil.com/?kontakt.az", "", ErrUnsupportedSite},
+		{"https://kontakt.az.evil.com/iphone", "", ErrUnsupportedSite},
+		{"https://notkontakt.az/iphone", "", ErrUnsupportedSite},
+		{"ftp://kontakt.az/iphone", "", ErrUnsupportedSite},
+		{"kontakt.az/iphone", "", ErrUnsupportedSite},
+	}
+
+	for _, tt := range tests {
+		got, _, err := DetectScraper(tt.url)
+		if !errors.Is(err, tt.err) {
+			t.Errorf("DetectScraper(%q) error = %v, want %v", tt.url, err, tt.err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("DetectScraper(%q) = %q, want %q", tt.url, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,140 @@
+package scrappers
+
+import (
+	"context"
+	"net/url"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// ListingItem is a product as shown in search results or category listings,
+// with less detail than a scraped Product
+type ListingItem struct {
+	Name          string `json:"name"`
+	URL           string `json:"url"`
+	Site          string `json:"site"`
+	Price         string `json:"price,omitempty"`
+	OriginalPrice string `json:"original_price,omitempty"`
+	ImageURL      string `json:"image_url,omitempty"`
+
+	// Typed counterparts of the display prices above
+	PriceValue         *Money `json:"price_value,omitempty"`
+	OriginalPriceValue *Money `json:"original_price_value,omitempty"`
+
+	// Quantities are the specifications stated in the name, keyed like
+	// Product.Specs
+	Quantities map[string]Quantity `json:"quantities,omitempty"`
+}
+
+// Searcher is implemented by scrapers whose site has a search page
+type Searcher interface {
+	// Search returns the products the site lists for query, in the site's order
+	Search(ctx context.Context, query string) ([]ListingItem, error)
+}
+
+// GetSearchers returns the registered scrapers that implement Searcher,
+// keyed by site identifier
+func GetSearchers() map[string]Searcher {
+	searchers := make(map[string]Searcher)
+	for identifier, scraper := range registeredScrapers() {
+		if s, ok := scraper.(Searcher); ok {
+			searchers[identifier] = s
+		}
+	}
+	return searchers
+}
+
+// listingSelectors locate the parts of a product card on a listing page.
+// Fields may list comma-separated alternatives; within a card the first
+// matching element in document order is used.
+type listingSelectors struct {
+	Card          string
+	Link          string
+	Name          string
+	Price         string
+	OriginalPrice string
+	Image         string
+}
+
+// parseListing extracts product cards from a listing page, resolving links
+// against pageURL and dropping cards without a name or link
+func parseListing(doc *goquery.Document, pageURL, site string, sel listingSelectors) []ListingItem {
+	base, _ := url.Parse(pageURL)
+	seen := make(map[string]bool)
+	items := []ListingItem{}
+
+	doc.Find(sel.Card).Each(func(i int, card *goquery.Selection) {
+		item := ListingItem{Site: site}
+
+		link := card.Find(sel.Link).First()
+		href, _ := link.Attr("href")
+		item.URL = resolveURL(base, href)
+
+		item.Name = collapseSpace(card.Find(sel.Name).First().Text())
+		if item.Name == "" {
+			item.Name = collapseSpace(link.AttrOr("title", ""))
+		}
+		if sel.Price != "" {
+			item.Price = collapseSpace(card.Find(sel.Price).First().Text())
+		}
+		if sel.OriginalPrice != "" {
+			item.OriginalPrice = collapseSpace(card.Find(sel.OriginalPrice).First().Text())
+		}
+		if sel.Image != "" {
+			img := card.Find(sel.Image).First()
+			src := img.AttrOr("data-src", img.AttrOr("src", ""))
+			item.ImageURL = resolveURL(base, src)
+		}
+
+		if item.Name == "" || item.URL == "" || seen[item.URL] {
+			return
+		}
+		seen[item.URL] = true
+		item.fillPrices()
+		item.Quantities = NameQuantities(item.Name)
+		items = append(items, item)
+	})
+	return items
+}
+
+// fillPrices parses the display prices of a listing item
+func (l *ListingItem) fillPrices() {
+	if m, err := ParseMoney(l.Price, "AZN"); err == nil {
+		l.PriceValue = &m
+	}
+	if m, err := ParseMoney(l.OriginalPrice, "AZN"); err == nil {
+		l.OriginalPriceValue = &m
+	}
+	// A lone old price means the site showed no discount
+	if l.PriceValue == nil && l.OriginalPriceValue != nil {
+		l.Price, l.PriceValue = l.OriginalPrice, l.OriginalPriceValue
+		l.OriginalPrice, l.OriginalPriceValue = "", nil
+	}
+}
+
+// resolveURL makes href absolute relative to base, dropping fragments;
+// it returns "" for empty or non-http links
+func resolveURL(base *url.URL, href string) string {
+	href = strings.TrimSpace(href)
+	if href == "" || strings.HasPrefix(href, "javascript:") || strings.HasPrefix(href, "#") {
+		return ""
+	}
+	u, err := url.Parse(href)
+	if err != nil {
+		return ""
+	}
+	if base != nil {
+		u = base.ResolveReference(u)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return ""
+	}
+	u.Fragment = ""
+	return u.String()
+}
+
+// collapseSpace trims s and joins runs of whitespace into single spaces
+func collapseSpace(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
@@ -0,0 +1,108 @@
+package scrappers
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestParseSearchListings(t *testing.T) {
+	tests := []struct {
+		fixture string
+		parse   func(pageURL string, html []byte) ([]ListingItem, error)
+		pageURL string
+		want    []ListingItem
+	}{
+		{
+			fixture: "kontakt_search.html",
+			parse: func(pageURL string, html []byte) ([]ListingItem, error) {
+				return parseKontaktListing(pageURL, string(html))
+			},
+			pageURL: "https://kontakt.az/catalogsearch/result/?q=iphone+13",
+			want: []ListingItem{
+				{
+					Name:          "iPhone 13 128 GB Midnight",
+					URL:           "https://kontakt.az/iphone-13-128-gb-midnight",
+					Site:          "kontakt",
+					Price:         "1.379,99 ₼",
+					OriginalPrice: "1.599,99 ₼",
+					ImageURL:      "https://kontakt.az/media/catalog/product/iphone13-midnight.jpg",
+					Quantities:    map[string]Quantity{"internal_memory": {128 << 30, UnitBytes}},
+				},
+				{
+					Name:       "iPhone 13 256 GB Blue",
+					URL:        "https://kontakt.az/iphone-13-256-gb-blue",
+					Site:       "kontakt",
+					Price:      "1.699,99 ₼",
+					ImageURL:   "https://kontakt.az/media/catalog/product/iphone13-blue.jpg",
+					Quantities: map[string]Quantity{"internal_memory": {256 << 30, UnitBytes}},
+				},
+			},
+		},
+		{
+			fixture: "irshad_search.html",
+			parse: func(pageURL string, html []byte) ([]ListingItem, error) {
+				page, err := parseIrshadListingPage(pageURL, html)
+				if err != nil {
+					return nil, err
+				}
+				return page.Items, nil
+			},
+			pageURL: "https://irshad.az/az/search?q=iphone+13",
+			want: []ListingItem{
+				{
+					Name:          "Apple iPhone 13 128GB Starlight",
+					URL:           "https://irshad.az/az/mehsullar/apple-iphone-13-128gb-starlight",
+					Site:          "irshad",
+					Price:         "1 329.00 AZN",
+					OriginalPrice: "1 499.00 AZN",
+					ImageURL:      "https://irshad.az/storage/products/iphone-13-starlight.jpg",
+					Quantities:    map[string]Quantity{"internal_memory": {128 << 30, UnitBytes}},
+				},
+				{
+					Name:       "Apple iPhone 13 mini 128GB",
+					URL:        "https://irshad.az/az/mehsullar/apple-iphone-13-mini-128gb",
+					Site:       "irshad",
+					Price:      "1 199.00 AZN",
+					Quantities: map[string]Quantity{"internal_memory": {128 << 30, UnitBytes}},
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.fixture, func(t *testing.T) {
+			html, err := os.ReadFile(filepath.Join("testdata", tt.fixture))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := tt.parse(tt.pageURL, html)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %d items, want %d: %+v", len(got), len(tt.want), got)
+			}
+			for i, item := range got {
+				if item.PriceValue == nil {
+					t.Errorf("item %d: price was not parsed", i)
+				}
+				item.PriceValue, item.OriginalPriceValue = nil, nil
+				if !reflect.DeepEqual(item, tt.want[i]) {
+					t.Errorf("item %d:\n got %+v\nwant %+v", i, item, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestSearchersRegistered(t *testing.T) {
+	searchers := GetSearchers()
+	for _, site := range []string{"kontakt", "irshad"} {
+		if _, ok := searchers[site]; !ok {
+			t.Errorf("expected %s to support search", site)
+		}
+	}
+}
@@ -0,0 +1,274 @@
+package scrappers
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/temoto/robotstxt"
+)
+
+// maxSitemapSize is the largest uncompressed sitemap accepted; the sitemap
+// protocol allows 50 MB
+const maxSitemapSize = 50 << 20
+
+// defaultMaxSitemaps caps the sitemap files read by one discovery
+const defaultMaxSitemaps = 50
+
+// SitemapURL is a page listed in a sitemap
+type SitemapURL struct {
+	Loc string `json:"loc"`
+
+	// LastMod is when the site says the page last changed; zero when not given
+	LastMod time.Time `json:"lastmod,omitempty"`
+}
+
+// ProductURLMatcher is implemented by scrapers that can tell product pages
+// from other pages of their site. Sitemap discovery keeps every page
+// accepted by IsValidURL for scrapers that do not implement it.
+type ProductURLMatcher interface {
+	IsProductURL(url string) bool
+}
+
+// SitemapOptions limits a sitemap discovery
+type SitemapOptions struct {
+	// MaxSitemaps is the number of sitemap files read; zero means 50
+	MaxSitemaps int
+
+	// MaxURLs stops the discovery once this many product pages were found;
+	// zero means no limit
+	MaxURLs int
+}
+
+// SitemapResult is the outcome of a sitemap discovery
+type SitemapResult struct {
+	URLs     []SitemapURL `json:"urls"`
+	Sitemaps int          `json:"sitemaps"`
+
+	// Skipped counts listed pages that are not product pages of the site
+	Skipped int `json:"skipped"`
+
+	// Errors describes sitemaps that could not be read; pages found in the
+	// others are kept
+	Errors []string `json:"errors,omitempty"`
+}
+
+var (
+	sitemapFetcher     *Fetcher
+	sitemapFetcherOnce sync.Once
+)
+
+// SitemapFetcher returns the fetcher used for sitemaps, which allows larger
+// bodies than the one used for product pages
+func SitemapFetcher() *Fetcher {
+	sitemapFetcherOnce.Do(func() {
+		cfg := DefaultFetcherConfig()
+		cfg.Limiter = SharedHostLimiter()
+		cfg.MaxBodySize = maxSitemapSize
+		sitemapFetcher = NewFetcher(cfg)
+	})
+	return sitemapFetcher
+}
+
+// DiscoverSitemap lists the product pages of a site from its sitemaps. The
+// sitemaps named in robots.txt are read, or /sitemap.xml when there are
+// none, following sitemap indexes and gzipped files.
+func DiscoverSitemap(ctx context.Context, site string, opts SitemapOptions) (*SitemapResult, error) {
+	scraper, err := GetScraper(site)
+	if err != nil {
+		return nil, err
+	}
+	base := getBaseURL(site)
+	if base == "" {
+		return nil, fmt.Errorf("no base URL known for site: %s", site)
+	}
+
+	accept := scraper.IsValidURL
+	if matcher, ok := scraper.(ProductURLMatcher); ok {
+		accept = func(url string) bool {
+			return scraper.IsValidURL(url) && matcher.IsProductURL(url)
+		}
+	}
+	return walkSitemaps(ctx, SitemapFetcher(), base, accept, opts)
+}
+
+// walkSitemaps reads the sitemaps of the site at base breadth-first,
+// keeping the pages accepted by accept
+func walkSitemaps(ctx context.Context, f *Fetcher, base string, accept func(string) bool, opts SitemapOptions) (*SitemapResult, error) {
+	maxSitemaps := opts.MaxSitemaps
+	if maxSitemaps <= 0 {
+		maxSitemaps = defaultMaxSitemaps
+	}
+
+	queue, err := robotsSitemaps(ctx, f, base)
+	if err != nil {
+		return nil, err
+	}
+	if len(queue) == 0 {
+		queue = []string{strings.TrimRight(base, "/") + "/sitemap.xml"}
+	}
+
+	result := &SitemapResult{URLs: []SitemapURL{}}
+	seenSitemaps := make(map[string]bool)
+	seenURLs := make(map[string]bool)
+
+	for len(queue) > 0 && result.Sitemaps < maxSitemaps {
+		sitemapURL := queue[0]
+		queue = queue[1:]
+		if seenSitemaps[sitemapURL] {
+			continue
+		}
+		seenSitemaps[sitemapURL] = true
+
+		doc, err := fetchSitemap(ctx, f, sitemapURL)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			result.Errors = append(result.Errors, fmt.Sprintf("%s: %v", sitemapURL, err))
+			continue
+		}
+		result.Sitemaps++
+
+		for _, child := range doc.Sitemaps {
+			if loc := strings.TrimSpace(child.Loc); loc != "" {
+				queue = append(queue, loc)
+			}
+		}
+		for _, entry := range doc.URLs {
+			loc := strings.TrimSpace(entry.Loc)
+			if loc == "" || seenURLs[listingKey(loc)] {
+				continue
+			}
+			seenURLs[listingKey(loc)] = true
+			if !accept(loc) {
+				result.Skipped++
+				continue
+			}
+			result.URLs = append(result.URLs, SitemapURL{Loc: loc, LastMod: parseLastMod(entry.LastMod)})
+			if opts.MaxURLs > 0 && len(result.URLs) >= opts.MaxURLs {
+				return result, nil
+			}
+		}
+
+		if os.Getenv("DEBUG") == "1" {
+			fmt.Printf("DEBUG: read sitemap %s, %d product pages so far\n", sitemapURL, len(result.URLs))
+		}
+	}
+
+	if result.Sitemaps == 0 && len(result.Errors) > 0 {
+		return nil, errors.New(result.Errors[0])
+	}
+	return result, nil
+}
+
+// robotsSitemaps returns the Sitemap entries of base's robots.txt; a
+// missing robots.txt yields none
+func robotsSitemaps(ctx context.Context, f *Fetcher, base string) ([]string, error) {
+	page, err := f.Get(ctx, strings.TrimRight(base, "/")+"/robots.txt")
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		var httpErr *HTTPError
+		if errors.As(err, &httpErr) || errors.Is(err, ErrDisallowedByRobots) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	data, err := robotstxt.FromStatusAndBytes(page.StatusCode, page.Body)
+	if err != nil {
+		return nil, nil
+	}
+	return data.Sitemaps, nil
+}
+
+// sitemapDoc covers both <urlset> and <sitemapindex> documents
+type sitemapDoc struct {
+	URLs []struct {
+		Loc     string `xml:"loc"`
+		LastMod string `xml:"lastmod"`
+	} `xml:"url"`
+	Sitemaps []struct {
+		Loc string `xml:"loc"`
+	} `xml:"sitemap"`
+}
+
+// fetchSitemap downloads and parses one sitemap, gunzipping it if needed
+func fetchSitemap(ctx context.Context, f *Fetcher, sitemapURL string) (*sitemapDoc, error) {
+	page, err := f.Get(ctx, sitemapURL)
+	if err != nil {
+		return nil, err
+	}
+
+	body := page.Body
+	// Gzipped sitemaps are served as files, not with a Content-Encoding
+	if len(body) > 2 && body[0] == 0x1f && body[1] == 0x8b {
+		zr, err := gzip.NewReader(bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		defer zr.Close()
+		body, err = io.ReadAll(io.LimitReader(zr, maxSitemapSize+1))
+		if err != nil {
+			return nil, err
+		}
+		if len(body) > maxSitemapSize {
+			return nil, ErrBodyTooLarge
+		}
+	}
+
+	var doc sitemapDoc
+	if err := xml.Unmarshal(body, &doc); err != nil {
+		return nil, fmt.Errorf("invalid sitemap: %w", err)
+	}
+	return &doc, nil
+}
+
+// parseLastMod parses a W3C datetime as used by <lastmod>; invalid values
+// yield the zero time
+func parseLastMod(s string) time.Time {
+	s = strings.TrimSpace(s)
+	for _, layout := range []string{time.RFC3339, "2006-01-02T15:04Z07:00", "2006-01-02"} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t.UTC()
+		}
+	}
+	return time.Time{}
+}
+
+// isSlugProductPath reports whether rawURL looks like a product page of a
+// shop that puts products at the root, e.g. /apple-iphone-15-128-gb-black.
+// Category pages at the root have short names such as /telefonlar.
+func isSlugProductPath(rawURL string) bool {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return false
+	}
+	path := strings.Trim(u.Path, "/")
+	return path != "" && !strings.Contains(path, "/") && strings.Count(path, "-") >= 2
+}
+
+// hasPathPrefix reports whether rawURL's path continues below one of the
+// given sections, e.g. /az/mehsullar/<slug>
+func hasPathPrefix(rawURL string, sections ...string) bool {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return false
+	}
+	for _, section := range sections {
+		if rest, ok := strings.CutPrefix(u.Path, section); ok && strings.Trim(rest, "/") != "" {
+			return true
+		}
+	}
+	return false
+}
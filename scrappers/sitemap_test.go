@@ -0,0 +1,107 @@
+package scrappers
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWalkSitemaps(t *testing.T) {
+	var srv *httptest.Server
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/robots.txt":
+			fmt.Fprintf(w, "User-agent: *\nDisallow:\nSitemap: %s/sitemap_index.xml\n", srv.URL)
+		case "/sitemap_index.xml":
+			w.Header().Set("Content-Type", "application/xml")
+			fmt.Fprintf(w, `<?xml version="1.0" encoding="UTF-8"?>
+<sitemapindex xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+  <sitemap><loc>%[1]s/products.xml.gz</loc></sitemap>
+  <sitemap><loc>%[1]s/missing.xml</loc></sitemap>
+</sitemapindex>`, srv.URL)
+		case "/products.xml.gz":
+			var buf bytes.Buffer
+			zw := gzip.NewWriter(&buf)
+			fmt.Fprintf(zw, `<?xml version="1.0" encoding="UTF-8"?>
+<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+  <url><loc>%[1]s/apple-iphone-15-128-gb</loc><lastmod>2025-10-01T10:00:00+04:00</lastmod></url>
+  <url><loc>%[1]s/telefonlar</loc></url>
+  <url><loc>%[1]s/samsung-galaxy-a55</loc><lastmod>2025-09-30</lastmod></url>
+  <url><loc>%[1]s/apple-iphone-15-128-gb/</loc></url>
+</urlset>`, srv.URL)
+			zw.Close()
+			w.Header().Set("Content-Type", "application/x-gzip")
+			w.Write(buf.Bytes())
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	f := NewFetcher(FetcherConfig{Timeout: 5 * time.Second, MaxBodySize: maxSitemapSize})
+	accept := func(url string) bool {
+		return strings.HasPrefix(url, srv.URL) && isSlugProductPath(url)
+	}
+
+	result, err := walkSitemaps(context.Background(), f, srv.URL, accept, SitemapOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Sitemaps != 2 || result.Skipped != 1 || len(result.Errors) != 1 {
+		t.Errorf("got %d sitemaps, %d skipped, errors %v", result.Sitemaps, result.Skipped, result.Errors)
+	}
+	if len(result.URLs) != 2 {
+		t.Fatalf("expected 2 product pages, got %+v", result.URLs)
+	}
+	if want := time.Date(2025, 10, 1, 6, 0, 0, 0, time.UTC); !result.URLs[0].LastMod.Equal(want) {
+		t.Errorf("lastmod = %v, want %v", result.URLs[0].LastMod, want)
+	}
+	if result.URLs[1].Loc != srv.URL+"/samsung-galaxy-a55" || result.URLs[1].LastMod.IsZero() {
+		t.Errorf("unexpected second page %+v", result.URLs[1])
+	}
+
+	limited, err := walkSitemaps(context.Background(), f, srv.URL, accept, SitemapOptions{MaxURLs: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(limited.URLs) != 1 {
+		t.Errorf("MaxURLs: expected 1 page, got %d", len(limited.URLs))
+	}
+}
+
+func TestIsProductURL(t *testing.T) {
+	tests := []struct {
+		site string
+		url  string
+		want bool
+	}{
+		{"kontakt", "https://kontakt.az/iphone-13-128-gb-midnight", true},
+		{"kontakt", "https://kontakt.az/telefonlar/smartfonlar", false},
+		{"kontakt", "https://kontakt.az/telefonlar", false},
+		{"irshad", "https://irshad.az/az/mehsullar/apple-iphone-13-128gb-starlight", true},
+		{"irshad", "https://irshad.az/az/mehsullar/", false},
+		{"irshad", "https://irshad.az/az/telefonlar", false},
+		{"bakuelectronics", "https://www.bakuelectronics.az/mehsul/samsung-galaxy-a55", true},
+		{"bakuelectronics", "https://www.bakuelectronics.az/mehsul/", false},
+		{"optimal", "https://optimal.az/apple-iphone-15-128-gb-black", true},
+	}
+	for _, tt := range tests {
+		scraper, err := GetScraper(tt.site)
+		if err != nil {
+			t.Fatal(err)
+		}
+		matcher, ok := scraper.(ProductURLMatcher)
+		if !ok {
+			t.Fatalf("%s does not implement ProductURLMatcher", tt.site)
+		}
+		if got := matcher.IsProductURL(tt.url); got != tt.want {
+			t.Errorf("%s IsProductURL(%q) = %v, want %v", tt.site, tt.url, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,157 @@
+package scrappers
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// SpecValue is one specification of a product as shown on the page, with
+// its amount when the value is a measurement
+type SpecValue struct {
+	// Label and Value are the texts shown on the page
+	Label string `json:"label"`
+	Value string `json:"value"`
+
+	// Quantity is the amount Value states, in its canonical unit
+	Quantity *Quantity `json:"quantity,omitempty"`
+}
+
+// specKey is a canonical specification key with the labels stores use for
+// it and the unit a value that is just a number is in
+type specKey struct {
+	key    string
+	unit   string
+	labels []string
+}
+
+// specDictionary maps Azerbaijani, Russian and English labels, lowercased,
+// to canonical keys. Labels match whole words anywhere in the label, and
+// longer labels are tried first, so "qrafik prosessor" is a GPU and not a
+// processor.
+var specDictionary = []specKey{
+	{"brand", "", []string{"brend", "istehsalçı", "бренд", "производитель", "brand", "manufacturer"}},
+	{"model", "", []string{"model", "модель"}},
+	{"color", "", []string{"rəng", "rəngi", "цвет", "color", "colour"}},
+	{"internal_memory", "GB", []string{"daxili yaddaş", "yaddaş həcmi", "встроенная память", "объем памяти", "internal memory", "internal storage", "storage"}},
+	{"ram", "GB", []string{"operativ yaddaş", "оперативная память", "озу", "ram"}},
+	{"memory_card", "", []string{"yaddaş kartı", "карта памяти", "memory card"}},
+	{"main_camera", "MP", []string{"əsas kamera", "arxa kamera", "основная камера", "тыловая камера", "main camera", "rear camera"}},
+	{"front_camera", "MP", []string{"ön kamera", "selfi kamera", "фронтальная камера", "front camera", "selfie camera"}},
+	{"processor", "", []string{"prosessor", "prosessorun adı", "prosessorun növü", "çipset", "процессор", "чипсет", "processor", "chipset", "cpu"}},
+	{"processor_cores", "", []string{"nüvələrin sayı", "nüvə sayı", "количество ядер", "processor cores", "cores"}},
+	{"gpu", "", []string{"qrafik prosessor", "videokart", "video kart", "графический процессор", "видеокарта", "gpu", "graphics"}},
+	{"os", "", []string{"əməliyyat sistemi", "операционная система", "operating system", "os"}},
+	{"display_type", "", []string{"displey növü", "ekran növü", "ekranın növü", "тип экрана", "тип дисплея", "display type", "screen type"}},
+	{"screen_size", "inch", []string{"ekran ölçüsü", "ekranın ölçüsü", "ekranın diaqonalı", "diaqonal", "диагональ", "диагональ экрана", "screen size", "display size", "diagonal"}},
+	{"resolution", "", []string{"ekran icazəsi", "ekranın icazəsi", "icazə", "разрешение экрана", "разрешение", "resolution"}},
+	{"refresh_rate", "Hz", []string{"yenilənmə tezliyi", "ekranın tezliyi", "частота обновления", "refresh rate"}},
+	{"battery_type", "", []string{"batareya növü", "batareyanın növü", "akkumulyatorun növü", "тип аккумулятора", "тип батареи", "battery type"}},
+	{"battery", "mAh", []string{"batareya", "batareyanın tutumu", "akkumulyator", "аккумулятор", "емкость аккумулятора", "battery", "battery capacity"}},
+	{"weight", "kg", []string{"çəki", "çəkisi", "вес", "weight"}},
+	{"dimensions", "", []string{"ölçülər", "габариты", "размеры", "dimensions"}},
+	{"sim", "", []string{"sim kart", "sim", "sim-карта", "sim card"}},
+	{"warranty", "", []string{"zəmanət", "гарантия", "warranty"}},
+}
+
+// specLabels are the dictionary labels, longest first
+var specLabels = func() []struct{ label, key string } {
+	var labels []struct{ label, key string }
+	for _, entry := range specDictionary {
+		for _, label := range entry.labels {
+			labels = append(labels, struct{ label, key string }{specWords(label), entry.key})
+		}
+	}
+	sort.SliceStable(labels, func(i, j int) bool {
+		return len(labels[i].label) > len(labels[j].label)
+	})
+	return labels
+}()
+
+// specUnits are the units of the canonical keys
+var specUnits = func() map[string]string {
+	units := make(map[string]string)
+	for _, entry := range specDictionary {
+		units[entry.key] = entry.unit
+	}
+	return units
+}()
+
+// specWords lowercases label and joins its words with single spaces,
+// dropping punctuation such as trailing colons
+func specWords(label string) string {
+	words := strings.FieldsFunc(strings.ToLower(label), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '-'
+	})
+	return strings.Join(words, " ")
+}
+
+// NormalizeSpecKey returns the canonical key of a specification label, or
+// the label itself in snake case when it is not in the dictionary
+func NormalizeSpecKey(label string) string {
+	words := specWords(label)
+	if words == "" {
+		return ""
+	}
+	padded := " " + words + " "
+	for _, l := range specLabels {
+		if strings.Contains(padded, " "+l.label+" ") {
+			return l.key
+		}
+	}
+	return strings.ReplaceAll(words, " ", "_")
+}
+
+// NewSpecValue normalizes a specification row into its key and value
+func NewSpecValue(label, value string) (string, SpecValue) {
+	label, value = collapseSpace(strings.TrimSuffix(strings.TrimSpace(label), ":")), collapseSpace(value)
+	key := NormalizeSpecKey(label)
+	spec := SpecValue{Label: label, Value: value}
+
+	amount := value
+	if key == "ram" {
+		// "8/256 GB" gives RAM and storage together; RAM comes first
+		if m := nameMemoryPattern.FindStringSubmatch(value); m != nil {
+			amount = m[1]
+		}
+	}
+
+	var q Quantity
+	var ok bool
+	if unit := specUnits[key]; unit != "" {
+		q, ok = parseQuantityIn(amount, unit)
+	} else {
+		q, ok = ParseQuantity(value)
+	}
+	if ok {
+		spec.Quantity = &q
+	}
+	return key, spec
+}
+
+// addSpec records a specification row. The first row of a key wins,
+// unless only a later one states its amount.
+func (p *Product) addSpec(label, value string) {
+	key, spec := NewSpecValue(label, value)
+	if key == "" || spec.Value == "" {
+		return
+	}
+	if existing, exists := p.Specs[key]; exists && (existing.Quantity != nil || spec.Quantity == nil) {
+		return
+	}
+	if p.Specs == nil {
+		p.Specs = make(map[string]SpecValue)
+	}
+	p.Specs[key] = spec
+}
+
+// Quantities returns the measured specifications of the product by key
+func (p *Product) Quantities() map[string]Quantity {
+	quantities := make(map[string]Quantity)
+	for key, spec := range p.Specs {
+		if spec.Quantity != nil {
+			quantities[key] = *spec.Quantity
+		}
+	}
+	return quantities
+}
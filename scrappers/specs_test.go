@@ -0,0 +1,91 @@
+package scrappers
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNormalizeSpecKey(t *testing.T) {
+	tests := []struct {
+		label string
+		want  string
+	}{
+		{"Daxili yaddaş", "internal_memory"},
+		{"Daxili yaddaş:", "internal_memory"},
+		{"Встроенная память", "internal_memory"},
+		{"Internal memory", "internal_memory"},
+		{"Operativ yaddaş (RAM)", "ram"},
+		{"Оперативная память", "ram"},
+		{"Qrafik prosessor", "gpu"},
+		{"Prosessorun adı", "processor"},
+		{"Processor cores", "processor_cores"},
+		{"Ekranın diaqonalı", "screen_size"},
+		{"Диагональ экрана", "screen_size"},
+		{"Çəki", "weight"},
+		{"Вес", "weight"},
+		{"Əsas kamera", "main_camera"},
+		{"Ön kamera", "front_camera"},
+		{"Batareyanın tutumu", "battery"},
+		{"Batareya növü", "battery_type"},
+		{"Тип аккумулятора", "battery_type"},
+		{"Programs", "programs"},
+		{"Bluetooth versiyası", "bluetooth_versiyası"},
+		{" : ", ""},
+	}
+
+	for _, tt := range tests {
+		if got := NormalizeSpecKey(tt.label); got != tt.want {
+			t.Errorf("NormalizeSpecKey(%q) = %q, want %q", tt.label, got, tt.want)
+		}
+	}
+}
+
+func TestNewSpecValue(t *testing.T) {
+	tests := []struct {
+		label, value string
+		key          string
+		want         SpecValue
+	}{
+		{"Daxili yaddaş", "256 GB", "internal_memory", SpecValue{Label: "Daxili yaddaş", Value: "256 GB", Quantity: quantity(256<<30, UnitBytes)}},
+		{"Daxili yaddaş", "1 TB", "internal_memory", SpecValue{Label: "Daxili yaddaş", Value: "1 TB", Quantity: quantity(1024<<30, UnitBytes)}},
+		{"Встроенная память", "8/256 ГБ", "internal_memory", SpecValue{Label: "Встроенная память", Value: "8/256 ГБ", Quantity: quantity(256<<30, UnitBytes)}},
+		{"Əsas kamera", "2 x 12MP", "main_camera", SpecValue{Label: "Əsas kamera", Value: "2 x 12MP", Quantity: quantity(12, UnitMegapixels)}},
+		{"Ekran ölçüsü", `6.1"`, "screen_size", SpecValue{Label: "Ekran ölçüsü", Value: `6.1"`, Quantity: quantity(6.1, UnitInches)}},
+		{"Diaqonal", "6,7 düym", "screen_size", SpecValue{Label: "Diaqonal", Value: "6,7 düym", Quantity: quantity(6.7, UnitInches)}},
+		{"Ekran ölçüsü", "6.1", "screen_size", SpecValue{Label: "Ekran ölçüsü", Value: "6.1", Quantity: quantity(6.1, UnitInches)}},
+		{"Çəki", "3,7 kq", "weight", SpecValue{Label: "Çəki", Value: "3,7 kq", Quantity: quantity(3.7, UnitKilograms)}},
+		{"Çəki", "171 q", "weight", SpecValue{Label: "Çəki", Value: "171 q", Quantity: quantity(0.171, UnitKilograms)}},
+		{"Çəki", "yüngül", "weight", SpecValue{Label: "Çəki", Value: "yüngül"}},
+		{"Batareya", "5000 mAh", "battery", SpecValue{Label: "Batareya", Value: "5000 mAh", Quantity: quantity(5000, UnitMilliampHours)}},
+		{"Yenilənmə tezliyi", "120", "refresh_rate", SpecValue{Label: "Yenilənmə tezliyi", Value: "120", Quantity: quantity(120, UnitHertz)}},
+		{"Prosessorun tezliyi", "3,2 GHz", "prosessorun_tezliyi", SpecValue{Label: "Prosessorun tezliyi", Value: "3,2 GHz", Quantity: quantity(3.2e9, UnitHertz)}},
+		{"Şəbəkə", "5G", "şəbəkə", SpecValue{Label: "Şəbəkə", Value: "5G"}},
+		{"Prosessor", "Apple A16 Bionic", "processor", SpecValue{Label: "Prosessor", Value: "Apple A16 Bionic"}},
+	}
+
+	for _, tt := range tests {
+		key, got := NewSpecValue(tt.label, tt.value)
+		if key != tt.key || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("NewSpecValue(%q, %q) = %q, %+v; want %q, %+v", tt.label, tt.value, key, got, tt.key, tt.want)
+		}
+	}
+}
+
+func TestAddSpecKeepsFirst(t *testing.T) {
+	var p Product
+	p.addSpec("Operativ yaddaş", "8 GB")
+	p.addSpec("RAM", "12 GB")
+	p.addSpec("Rəng", "")
+
+	if len(p.Specs) != 1 || p.Specs["ram"].Value != "8 GB" {
+		t.Errorf("Specs = %+v", p.Specs)
+	}
+
+	// A later row stating the amount replaces one that does not
+	p.addSpec("Daxili yaddaş", "var")
+	p.addSpec("Yaddaş həcmi", "256 GB")
+	p.addSpec("Storage", "512 GB")
+	if spec := p.Specs["internal_memory"]; spec.Quantity == nil || spec.Value != "256 GB" {
+		t.Errorf("internal_memory = %+v, want the first row with an amount", spec)
+	}
+}
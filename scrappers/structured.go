@@ -0,0 +1,378 @@
+package scrappers
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// StructuredData is what a page declares about its product in schema.org
+// JSON-LD, schema.org microdata and OpenGraph tags. Prices are formatted as
+// "1849.99 AZN" so they parse like the display prices of the page.
+type StructuredData struct {
+	Name          string `json:"name,omitempty"`
+	SKU           string `json:"sku,omitempty"`
+	GTIN          string `json:"gtin,omitempty"`
+	Brand         string `json:"brand,omitempty"`
+	Price         string `json:"price,omitempty"`
+	OriginalPrice string `json:"original_price,omitempty"`
+	Currency      string `json:"currency,omitempty"`
+	Availability  string `json:"availability,omitempty"`
+	Rating        string `json:"rating,omitempty"`
+	ReviewCount   string `json:"review_count,omitempty"`
+
+	// Title is the OpenGraph title. It often carries the shop's name, so it
+	// is only a last resort for the product name.
+	Title string `json:"title,omitempty"`
+}
+
+// ExtractStructuredData reads the product described by the page's
+// structured data. JSON-LD is preferred over microdata, and both over
+// OpenGraph; a field missing from one source is taken from the next.
+func ExtractStructuredData(doc *goquery.Document) *StructuredData {
+	data := &StructuredData{}
+	doc.Find("script[type='application/ld+json']").Each(func(i int, s *goquery.Selection) {
+		var v any
+		if err := json.Unmarshal([]byte(s.Text()), &v); err != nil {
+			return
+		}
+		for _, node := range findJSONLDProducts(v) {
+			data.merge(jsonLDProduct(node))
+		}
+	})
+	doc.Find("[itemscope][itemtype*='schema.org/Product']").Each(func(i int, s *goquery.Selection) {
+		data.merge(microdataProduct(s))
+	})
+	data.merge(openGraphProduct(doc))
+	return data
+}
+
+// apply fills the fields of p that are still empty
+func (d *StructuredData) apply(p *Product) {
+	setIfEmpty(&p.Name, d.Name)
+	setIfEmpty(&p.SKU, d.SKU)
+	setIfEmpty(&p.EAN, d.GTIN)
+	setIfEmpty(&p.Brand, d.Brand)
+	if p.CurrentPrice == "" && d.Price != "" {
+		p.CurrentPrice = d.Price
+		p.OriginalPrice = d.OriginalPrice
+		setIfEmpty(&p.Currency, d.Currency)
+	}
+	setIfEmpty(&p.Availability, d.Availability)
+	setIfEmpty(&p.Rating, d.Rating)
+	setIfEmpty(&p.ReviewCount, d.ReviewCount)
+}
+
+// merge copies the fields of other that d does not have yet
+func (d *StructuredData) merge(other StructuredData) {
+	setIfEmpty(&d.Name, other.Name)
+	setIfEmpty(&d.SKU, other.SKU)
+	setIfEmpty(&d.GTIN, other.GTIN)
+	setIfEmpty(&d.Brand, other.Brand)
+	// The original price only makes sense together with its price
+	if d.Price == "" && other.Price != "" {
+		d.Price = other.Price
+		d.OriginalPrice = other.OriginalPrice
+		d.Currency = other.Currency
+	}
+	setIfEmpty(&d.Availability, other.Availability)
+	setIfEmpty(&d.Rating, other.Rating)
+	setIfEmpty(&d.ReviewCount, other.ReviewCount)
+	setIfEmpty(&d.Title, other.Title)
+}
+
+// findJSONLDProducts returns the Product nodes of a JSON-LD document,
+// looking through arrays and @graph
+func findJSONLDProducts(v any) []map[string]any {
+	var products []map[string]any
+	switch node := v.(type) {
+	case []any:
+		for _, item := range node {
+			products = append(products, findJSONLDProducts(item)...)
+		}
+	case map[string]any:
+		if hasJSONLDType(node, "Product") {
+			products = append(products, node)
+		}
+		if graph, ok := node["@graph"]; ok {
+			products = append(products, findJSONLDProducts(graph)...)
+		}
+	}
+	return products
+}
+
+// hasJSONLDType reports whether node's @type, a string or an array, is typ
+func hasJSONLDType(node map[string]any, typ string) bool {
+	switch t := node["@type"].(type) {
+	case string:
+		return schemaName(t) == typ
+	case []any:
+		for _, item := range t {
+			if s, ok := item.(string); ok && schemaName(s) == typ {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// jsonLDProduct reads a schema.org Product node
+func jsonLDProduct(node map[string]any) StructuredData {
+	data := StructuredData{
+		Name: jsonLDString(node["name"]),
+		SKU:  jsonLDString(node["sku"]),
+	}
+	for _, key := range []string{"gtin13", "gtin", "gtin14", "gtin12", "gtin8", "ean"} {
+		if data.GTIN == "" {
+			data.GTIN = jsonLDString(node[key])
+		}
+	}
+
+	switch brand := node["brand"].(type) {
+	case map[string]any:
+		data.Brand = jsonLDString(brand["name"])
+	default:
+		data.Brand = jsonLDString(brand)
+	}
+
+	for _, offer := range jsonLDNodes(node["offers"]) {
+		price := jsonLDString(offer["price"])
+		if price == "" {
+			// AggregateOffer
+			price = jsonLDString(offer["lowPrice"])
+		}
+		currency := jsonLDString(offer["priceCurrency"])
+
+		var original string
+		for _, spec := range jsonLDNodes(offer["priceSpecification"]) {
+			priceType := schemaName(jsonLDString(spec["priceType"]))
+			switch {
+			case priceType == "StrikethroughPrice" || priceType == "ListPrice":
+				original = jsonLDString(spec["price"])
+			case price == "":
+				price = jsonLDString(spec["price"])
+			}
+			if currency == "" {
+				currency = jsonLDString(spec["priceCurrency"])
+			}
+		}
+
+		if price != "" && data.Price == "" {
+			data.Price = formatStructuredPrice(price, currency)
+			data.OriginalPrice = formatStructuredPrice(original, currency)
+			data.Currency = strings.ToUpper(currency)
+		}
+		setIfEmpty(&data.Availability, schemaAvailability(jsonLDString(offer["availability"])))
+	}
+
+	if rating, ok := node["aggregateRating"].(map[string]any); ok {
+		data.Rating = jsonLDString(rating["ratingValue"])
+		data.ReviewCount = jsonLDString(rating["reviewCount"])
+		if data.ReviewCount == "" {
+			data.ReviewCount = jsonLDString(rating["ratingCount"])
+		}
+	}
+	return data
+}
+
+// jsonLDNodes returns v as a list of objects, whether it is one or many
+func jsonLDNodes(v any) []map[string]any {
+	switch node := v.(type) {
+	case map[string]any:
+		return []map[string]any{node}
+	case []any:
+		var nodes []map[string]any
+		for _, item := range node {
+			if m, ok := item.(map[string]any); ok {
+				nodes = append(nodes, m)
+			}
+		}
+		return nodes
+	}
+	return nil
+}
+
+// jsonLDString returns a JSON-LD string or number value as text
+func jsonLDString(v any) string {
+	switch value := v.(type) {
+	case string:
+		return collapseSpace(value)
+	case float64:
+		return strconv.FormatFloat(value, 'f', -1, 64)
+	case map[string]any:
+		// {"@value": ...} or {"@id": ...}
+		if inner, ok := value["@value"]; ok {
+			return jsonLDString(inner)
+		}
+		return jsonLDString(value["@id"])
+	}
+	return ""
+}
+
+// microdataProduct reads a schema.org Product microdata item
+func microdataProduct(scope *goquery.Selection) StructuredData {
+	data := StructuredData{
+		Name: itemPropValue(scope, "name"),
+		SKU:  itemPropValue(scope, "sku"),
+	}
+	for _, prop := range []string{"gtin13", "gtin", "gtin14", "gtin12", "gtin8"} {
+		setIfEmpty(&data.GTIN, itemPropValue(scope, prop))
+	}
+
+	if brand := itemProp(scope, "brand"); brand.Length() > 0 {
+		if _, nested := brand.Attr("itemscope"); nested {
+			data.Brand = itemPropValue(brand, "name")
+		} else {
+			data.Brand = microdataValue(brand)
+		}
+	}
+
+	if offer := itemProp(scope, "offers"); offer.Length() > 0 {
+		price := itemPropValue(offer, "price")
+		if price == "" {
+			price = itemPropValue(offer, "lowPrice")
+		}
+		currency := itemPropValue(offer, "priceCurrency")
+		if price != "" {
+			data.Price = formatStructuredPrice(price, currency)
+			data.Currency = strings.ToUpper(currency)
+		}
+		data.Availability = schemaAvailability(itemPropValue(offer, "availability"))
+	}
+
+	if rating := itemProp(scope, "aggregateRating"); rating.Length() > 0 {
+		data.Rating = itemPropValue(rating, "ratingValue")
+		data.ReviewCount = itemPropValue(rating, "reviewCount")
+		if data.ReviewCount == "" {
+			data.ReviewCount = itemPropValue(rating, "ratingCount")
+		}
+	}
+	return data
+}
+
+// itemProp returns the first element with the given itemprop that belongs
+// to scope itself rather than to an item nested in it
+func itemProp(scope *goquery.Selection, name string) *goquery.Selection {
+	return scope.Find(fmt.Sprintf("[itemprop~='%s']", name)).FilterFunction(func(i int, s *goquery.Selection) bool {
+		return s.Parent().Closest("[itemscope]").IsSelection(scope)
+	}).First()
+}
+
+// itemPropValue returns the value of scope's itemprop name, or ""
+func itemPropValue(scope *goquery.Selection, name string) string {
+	prop := itemProp(scope, name)
+	if prop.Length() == 0 {
+		return ""
+	}
+	return microdataValue(prop)
+}
+
+// microdataValue returns a property's value: its content attribute, the
+// URL of links and images, or its text
+func microdataValue(s *goquery.Selection) string {
+	if content, ok := s.Attr("content"); ok {
+		return collapseSpace(content)
+	}
+	switch goquery.NodeName(s) {
+	case "a", "link":
+		return s.AttrOr("href", "")
+	case "img":
+		return s.AttrOr("src", "")
+	case "data", "meter":
+		return s.AttrOr("value", "")
+	}
+	return collapseSpace(s.Text())
+}
+
+// openGraphProduct reads OpenGraph and Facebook product tags
+func openGraphProduct(doc *goquery.Document) StructuredData {
+	meta := func(properties ...string) string {
+		for _, property := range properties {
+			if v := collapseSpace(doc.Find(fmt.Sprintf("meta[property='%s']", property)).First().AttrOr("content", "")); v != "" {
+				return v
+			}
+		}
+		return ""
+	}
+
+	data := StructuredData{
+		Title: meta("og:title"),
+		SKU:   meta("product:retailer_item_id"),
+		Brand: meta("product:brand", "og:brand"),
+	}
+	price := meta("product:sale_price:amount", "product:price:amount", "og:price:amount")
+	currency := meta("product:sale_price:currency", "product:price:currency", "og:price:currency")
+	if price != "" {
+		data.Price = formatStructuredPrice(price, currency)
+		data.Currency = strings.ToUpper(currency)
+		if meta("product:sale_price:amount") != "" {
+			data.OriginalPrice = formatStructuredPrice(meta("product:price:amount"), currency)
+		}
+	}
+	data.Availability = schemaAvailability(meta("product:availability", "og:availability"))
+	return data
+}
+
+// formatStructuredPrice formats a machine-readable price such as "1849.5"
+// with two decimals and its currency. Separators follow the same rules as
+// displayed prices, so "1849,99" and "1.849,99" are read as written, and
+// non-numeric or ambiguous prices give "".
+func formatStructuredPrice(price, currency string) string {
+	money, err := ParseMoney(price, "")
+	if err != nil || money.Minor <= 0 {
+		return ""
+	}
+	formatted := strconv.FormatFloat(money.Float(), 'f', 2, 64)
+	if currency != "" {
+		formatted += " " + strings.ToUpper(currency)
+	}
+	return formatted
+}
+
+// schemaAvailabilities names schema.org ItemAvailability values, and the
+// short forms used by OpenGraph, in the words scrapers report
+var schemaAvailabilities = map[string]string{
+	"instock":             "In stock",
+	"in stock":            "In stock",
+	"outofstock":          "Out of stock",
+	"out of stock":        "Out of stock",
+	"oos":                 "Out of stock",
+	"soldout":             "Out of stock",
+	"discontinued":        "Out of stock",
+	"preorder":            "Pre-order",
+	"presale":             "Pre-order",
+	"backorder":           "Backorder",
+	"limitedavailability": "In stock",
+	"instoreonly":         "In stock",
+	"onlineonly":          "In stock",
+}
+
+// schemaAvailability turns "https://schema.org/InStock" or "instock" into
+// "In stock"; unknown values are returned as they are
+func schemaAvailability(v string) string {
+	if v == "" {
+		return ""
+	}
+	if name, ok := schemaAvailabilities[strings.ToLower(schemaName(v))]; ok {
+		return name
+	}
+	return v
+}
+
+// schemaName strips the schema.org prefix from a type or enumeration value
+func schemaName(v string) string {
+	if i := strings.LastIndex(v, "/"); i >= 0 {
+		return v[i+1:]
+	}
+	return strings.TrimPrefix(v, "schema:")
+}
+
+// setIfEmpty sets *field to value unless it already has one
+func setIfEmpty(field *string, value string) {
+	if *field == "" {
+		*field = value
+	}
+}
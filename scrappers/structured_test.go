@@ -0,0 +1,175 @@
+package scrappers
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+func TestExtractStructuredData(t *testing.T) {
+	tests := []struct {
+		name string
+		html string
+		want StructuredData
+	}{
+		{
+			name: "json-ld graph",
+			html: `<script type="application/ld+json">{
+				"@context": "https://schema.org",
+				"@graph": [
+					{"@type": "WebSite", "name": "Shop"},
+					{"@type": ["Product", "Thing"], "name": "Samsung Galaxy S24", "sku": 10452,
+					 "gtin13": "8806095300000", "brand": {"@type": "Brand", "name": "Samsung"},
+					 "offers": [{"@type": "Offer", "price": "1849.5", "priceCurrency": "azn",
+					   "availability": "https://schema.org/InStock",
+					   "priceSpecification": {"priceType": "https://schema.org/StrikethroughPrice", "price": 2099}}],
+					 "aggregateRating": {"ratingValue": 4.6, "ratingCount": "31"}}
+				]}</script>`,
+			want: StructuredData{
+				Name:          "Samsung Galaxy S24",
+				SKU:           "10452",
+				GTIN:          "8806095300000",
+				Brand:         "Samsung",
+				Price:         "1849.50 AZN",
+				OriginalPrice: "2099.00 AZN",
+				Currency:      "AZN",
+				Availability:  "In stock",
+				Rating:        "4.6",
+				ReviewCount:   "31",
+			},
+		},
+		{
+			name: "json-ld array with aggregate offer",
+			html: `<script type="application/ld+json">[{"@type": "BreadcrumbList"},
+				{"@type": "Product", "name": "Redmi Note 13", "brand": "Xiaomi",
+				 "offers": {"@type": "AggregateOffer", "lowPrice": 499, "priceCurrency": "AZN",
+				   "availability": "http://schema.org/OutOfStock"}}]</script>
+				<script type="application/ld+json">{not json</script>`,
+			want: StructuredData{
+				Name:         "Redmi Note 13",
+				Brand:        "Xiaomi",
+				Price:        "499.00 AZN",
+				Currency:     "AZN",
+				Availability: "Out of stock",
+			},
+		},
+		{
+			name: "microdata",
+			html: `<div itemscope itemtype="https://schema.org/Product">
+				<h1 itemprop="name"> Apple   iPhone 15 </h1>
+				<span itemprop="sku">IP15-128</span>
+				<div itemprop="brand" itemscope itemtype="https://schema.org/Brand"><span itemprop="name">Apple</span></div>
+				<div itemprop="offers" itemscope itemtype="https://schema.org/Offer">
+					<meta itemprop="price" content="1799.99"><meta itemprop="priceCurrency" content="AZN">
+					<link itemprop="availability" href="https://schema.org/PreOrder">
+				</div>
+				<div itemprop="review" itemscope itemtype="https://schema.org/Review"><span itemprop="name">Great phone</span></div>
+			</div>`,
+			want: StructuredData{
+				Name:         "Apple iPhone 15",
+				SKU:          "IP15-128",
+				Brand:        "Apple",
+				Price:        "1799.99 AZN",
+				Currency:     "AZN",
+				Availability: "Pre-order",
+			},
+		},
+		{
+			name: "opengraph",
+			html: `<meta property="og:title" content="PlayStation 5 | Shop">
+				<meta property="product:price:amount" content="1199">
+				<meta property="product:price:currency" content="AZN">
+				<meta property="product:availability" content="instock">
+				<meta property="product:brand" content="Sony">`,
+			want: StructuredData{
+				Brand:        "Sony",
+				Price:        "1199.00 AZN",
+				Currency:     "AZN",
+				Availability: "In stock",
+				Title:        "PlayStation 5 | Shop",
+			},
+		},
+		{
+			name: "json-ld before opengraph",
+			html: `<script type="application/ld+json">{"@type": "Product", "name": "TV",
+				"offers": {"price": 999, "priceCurrency": "AZN"}}</script>
+				<meta property="product:price:amount" content="1099">
+				<meta property="product:price:currency" content="AZN">
+				<meta property="product:brand" content="LG">`,
+			want: StructuredData{
+				Name:     "TV",
+				Brand:    "LG",
+				Price:    "999.00 AZN",
+				Currency: "AZN",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := goquery.NewDocumentFromReader(strings.NewReader("<html><head></head><body>" + tt.html + "</body></html>"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := ExtractStructuredData(doc); *got != tt.want {
+				t.Errorf("got %+v, want %+v", *got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormatStructuredPrice(t *testing.T) {
+	tests := []struct {
+		price, want string
+	}{
+		{"1849.5", "1849.50 AZN"},
+		{"1849,99", "1849.99 AZN"},
+		{"1.849,99", "1849.99 AZN"},
+		{"1,849.99", "1849.99 AZN"},
+		{"1849", "1849.00 AZN"},
+		{"1.2345", ""},
+		{"0", ""},
+		{"call us", ""},
+	}
+
+	for _, tt := range tests {
+		if got := formatStructuredPrice(tt.price, "azn"); got != tt.want {
+			t.Errorf("formatStructuredPrice(%q) = %q, want %q", tt.price, got, tt.want)
+		}
+	}
+}
+
+func TestParseUsesStructuredData(t *testing.T) {
+	// The price box is missing; the price comes from JSON-LD and the
+	// original price from the page
+	html := `<html><head><script type="application/ld+json">{"@type": "Product",
+		"name": "Apple iPhone 15", "sku": "IP15", "gtin13": "0195949035975",
+		"offers": {"price": "1849.99", "priceCurrency": "AZN", "availability": "https://schema.org/InStock"}}</script></head>
+		<body><div class="product-info-main">
+			<h1 class="page-title"><span class="base">Apple iPhone 15 128 GB</span></h1>
+			<span data-price-type="oldPrice"><span class="price">2.099,99 ₼</span></span>
+		</div></body></html>`
+
+	got, err := NewOptimalScraper().parse("https://optimal.az/iphone-15", html)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Name != "Apple iPhone 15" || got.SKU != "IP15" || got.EAN != "0195949035975" || got.Availability != "In stock" {
+		t.Errorf("got %+v", *got)
+	}
+	if got.CurrentPriceValue == nil || got.CurrentPriceValue.Minor != 184999 {
+		t.Fatalf("current price = %+v, want 184999 minor units", got.CurrentPriceValue)
+	}
+	if got.OriginalPriceValue == nil || got.OriginalPriceValue.Minor != 209999 || got.DiscountPercent == 0 {
+		t.Errorf("original price = %+v, discount %v%%", got.OriginalPriceValue, got.DiscountPercent)
+	}
+}
+
+func TestFillPricesDropsEqualOriginal(t *testing.T) {
+	p := Product{CurrentPrice: "1849.99 AZN", OriginalPrice: "1.849,99 ₼"}
+	p.fillPrices()
+	if p.OriginalPrice != "" || p.OriginalPriceValue != nil || p.DiscountValue != nil {
+		t.Errorf("original price kept: %+v", p)
+	}
+}
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"web-scrappers/scrappers"
+)
+
+// defaultSearchTimeout bounds a search fan-out; Kontakt renders in Chrome
+const defaultSearchTimeout = 45 * time.Second
+
+// SiteSearchResult reports how one site answered a search
+type SiteSearchResult struct {
+	Site  string         `json:"site"`
+	Count int            `json:"count"`
+	Error *ErrorResponse `json:"error,omitempty"`
+}
+
+// SearchResponse is returned by GET /api/v1/search
+type SearchResponse struct {
+	Query string                  `json:"query"`
+	Items []scrappers.ListingItem `json:"items"`
+	Count int                     `json:"count"`
+	Sites []SiteSearchResult      `json:"sites"`
+}
+
+func handleSearch(w http.ResponseWriter, r *http.Request) {
+	q := strings.TrimSpace(r.URL.Query().Get("q"))
+	if q == "" {
+		writeError(w, http.StatusBadRequest, ErrorResponse{
+			Error:   "missing_parameters",
+			Message: "The 'q' parameter is required",
+		})
+		return
+	}
+
+	searchers := scrappers.GetSearchers()
+	if sites := r.URL.Query().Get("sites"); sites != "" {
+		selected := make(map[string]scrappers.Searcher)
+		for _, site := range strings.Split(sites, ",") {
+			site = strings.TrimSpace(site)
+			searcher, ok := searchers[site]
+			if !ok {
+				writeError(w, http.StatusBadRequest, ErrorResponse{
+					Error:   "unsupported_site",
+					Message: fmt.Sprintf("Site '%s' does not support search. Use /api/v1/sites to see available sites", site),
+				})
+				return
+			}
+			selected[site] = searcher
+		}
+		searchers = selected
+	}
+
+	timeout := defaultSearchTimeout
+	if t := r.URL.Query().Get("timeout"); t != "" {
+		parsed, err := time.ParseDuration(t)
+		if err != nil || parsed <= 0 {
+			writeError(w, http.StatusBadRequest, ErrorResponse{
+				Error:   "invalid_parameters",
+				Message: fmt.Sprintf("Invalid 'timeout' value '%s', expected a duration such as 30s", t),
+			})
+			return
+		}
+		timeout = parsed
+	}
+
+	filters, err := scrappers.ParseSpecFilters(r.URL.Query()["spec"])
+	if err != nil {
+		writeError(w, http.StatusBadRequest, ErrorResponse{
+			Error:   "invalid_parameters",
+			Message: fmt.Sprintf("Invalid 'spec' value: %v", err),
+		})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), timeout)
+	defer cancel()
+
+	resp := searchSites(ctx, searchers, q, r.URL.Query().Get("sort") == "price")
+	if r.Context().Err() != nil {
+		log.Printf("search for %q abandoned: %v", q, r.Context().Err())
+		return
+	}
+	filterSearch(&resp, filters)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// searchSites queries every searcher concurrently. A failing site is
+// reported in Sites without failing the search. Items are interleaved so
+// each site's best matches come first, or sorted by price when byPrice is set.
+func searchSites(ctx context.Context, searchers map[string]scrappers.Searcher, query string, byPrice bool) SearchResponse {
+	sites := make([]string, 0, len(searchers))
+	for site := range searchers {
+		sites = append(sites, site)
+	}
+	sort.Strings(sites)
+
+	results := make([][]scrappers.ListingItem, len(sites))
+	resp := SearchResponse{Query: query, Sites: make([]SiteSearchResult, len(sites))}
+
+	var wg sync.WaitGroup
+	for i, site := range sites {
+		wg.Add(1)
+		go func(i int, site string) {
+			defer wg.Done()
+			resp.Sites[i].Site = site
+
+			items, err := searchers[site].Search(ctx, query)
+			if err != nil {
+				_, errorResp := scrapeErrorResponse(err)
+				resp.Sites[i].Error = &errorResp
+				return
+			}
+			results[i] = items
+			resp.Sites[i].Count = len(items)
+		}(i, site)
+	}
+	wg.Wait()
+
+	resp.Items = []scrappers.ListingItem{}
+	for rank := 0; ; rank++ {
+		added := false
+		for _, items := range results {
+			if rank < len(items) {
+				resp.Items = append(resp.Items, items[rank])
+				added = true
+			}
+		}
+		if !added {
+			break
+		}
+	}
+
+	if byPrice {
+		// Items without a price go last
+		sort.SliceStable(resp.Items, func(a, b int) bool {
+			pa, pb := resp.Items[a].PriceValue, resp.Items[b].PriceValue
+			if pa == nil || pb == nil {
+				return pb == nil && pa != nil
+			}
+			return cheaper(pa, pb)
+		})
+	}
+
+	resp.Count = len(resp.Items)
+	return resp
+}
+
+// filterSearch keeps the items of resp satisfying every filter and counts
+// what is left of each site's results
+func filterSearch(resp *SearchResponse, filters []scrappers.SpecFilter) {
+	if len(filters) == 0 {
+		return
+	}
+	resp.Items = filterListings(resp.Items, filters)
+	resp.Count = len(resp.Items)
+
+	counts := make(map[string]int)
+	for _, item := range resp.Items {
+		counts[item.Site]++
+	}
+	for i := range resp.Sites {
+		if resp.Sites[i].Error == nil {
+			resp.Sites[i].Count = counts[resp.Sites[i].Site]
+		}
+	}
+}
+
+// filterListings keeps the items whose name states specifications
+// satisfying every filter
+func filterListings(items []scrappers.ListingItem, filters []scrappers.SpecFilter) []scrappers.ListingItem {
+	if len(filters) == 0 {
+		return items
+	}
+	kept := []scrappers.ListingItem{}
+	for _, item := range items {
+		if scrappers.MatchSpecFilters(filters, item.Quantities) {
+			kept = append(kept, item)
+		}
+	}
+	return kept
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"web-scrappers/scrappers"
+)
+
+// fakeSearcher returns fixed items or an error
+type fakeSearcher struct {
+	items []scrappers.ListingItem
+	err   error
+}
+
+func (f fakeSearcher) Search(ctx context.Context, query string) ([]scrappers.ListingItem, error) {
+	return f.items, f.err
+}
+
+func TestSearchSites(t *testing.T) {
+	price := func(minor int64) *scrappers.Money { return &scrappers.Money{Minor: minor, Currency: "AZN"} }
+	searchers := map[string]scrappers.Searcher{
+		"a": fakeSearcher{items: []scrappers.ListingItem{
+			{Name: "a1", PriceValue: price(300)},
+			{Name: "a2"},
+			// Cheaper as a number, but not in manat
+			{Name: "a3", PriceValue: &scrappers.Money{Minor: 10, Currency: "USD"}},
+		}},
+		"b": fakeSearcher{items: []scrappers.ListingItem{
+			{Name: "b1", PriceValue: price(100)},
+			{Name: "b2", PriceValue: price(200)},
+			{Name: "b3", PriceValue: price(50)},
+		}},
+		"c": fakeSearcher{err: context.DeadlineExceeded},
+	}
+
+	resp := searchSites(context.Background(), searchers, "q", false)
+	want := []string{"a1", "b1", "a2", "b2", "a3", "b3"}
+	if len(resp.Items) != len(want) {
+		t.Fatalf("got %d items, want %d", len(resp.Items), len(want))
+	}
+	for i, name := range want {
+		if resp.Items[i].Name != name {
+			t.Errorf("item %d is %s, want %s", i, resp.Items[i].Name, name)
+		}
+	}
+
+	if len(resp.Sites) != 3 || resp.Sites[0].Count != 3 || resp.Sites[1].Count != 3 {
+		t.Errorf("unexpected site summary %+v", resp.Sites)
+	}
+	if resp.Sites[2].Error == nil || resp.Sites[2].Error.Error != "scrape_timeout" {
+		t.Errorf("expected a timeout error for site c, got %+v", resp.Sites[2])
+	}
+
+	byPrice := searchSites(context.Background(), searchers, "q", true)
+	want = []string{"b3", "b1", "b2", "a1", "a3", "a2"}
+	for i, name := range want {
+		if byPrice.Items[i].Name != name {
+			t.Errorf("sorted item %d is %s, want %s", i, byPrice.Items[i].Name, name)
+		}
+	}
+
+	failed := searchSites(context.Background(), map[string]scrappers.Searcher{"x": fakeSearcher{err: errors.New("boom")}}, "q", false)
+	if failed.Count != 0 || failed.Items == nil || failed.Sites[0].Error.Error != "scraping_failed" {
+		t.Errorf("unexpected response for a failing site %+v", failed)
+	}
+}
+
+func TestFilterListings(t *testing.T) {
+	var items []scrappers.ListingItem
+	for _, name := range []string{"Galaxy A15 4/128 GB", "Galaxy A55 8/256 GB", "Galaxy S24 12/512 GB", "Galaxy Buds"} {
+		items = append(items, scrappers.ListingItem{Name: name, Quantities: scrappers.NameQuantities(name)})
+	}
+
+	filters, err := scrappers.ParseSpecFilters([]string{"ram>=8GB", "internal_memory<512 GB"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	kept := filterListings(items, filters)
+	if len(kept) != 1 || kept[0].Name != "Galaxy A55 8/256 GB" {
+		t.Errorf("unexpected items %+v", kept)
+	}
+	if len(filterListings(items, nil)) != len(items) {
+		t.Error("items dropped without filters")
+	}
+}
+
+func TestFilterSearchCountsSites(t *testing.T) {
+	var items []scrappers.ListingItem
+	for _, item := range []struct{ site, name string }{
+		{"a", "Galaxy A15 4/128 GB"},
+		{"a", "Galaxy A55 8/256 GB"},
+		{"b", "Galaxy S24 12/512 GB"},
+	} {
+		items = append(items, scrappers.ListingItem{Site: item.site, Name: item.name, Quantities: scrappers.NameQuantities(item.name)})
+	}
+	resp := SearchResponse{Items: items, Count: 3, Sites: []SiteSearchResult{{Site: "a", Count: 2}, {Site: "b", Count: 1}}}
+
+	filters, err := scrappers.ParseSpecFilters([]string{"ram>=8GB", "internal_memory<512 GB"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	filterSearch(&resp, filters)
+	if resp.Count != 1 || resp.Sites[0].Count != 1 || resp.Sites[1].Count != 0 {
+		t.Errorf("counts do not match the filtered items: %d, %+v", resp.Count, resp.Sites)
+	}
+}
@@ -0,0 +1,416 @@
+// Package watch runs recurring scrapes of a persisted watchlist. Each watch
+// follows a cron expression or a fixed interval; runs are spread out with
+// jitter and a limited number run at the same time.
+package watch
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	mrand "math/rand"
+	"sort"
+	"sync"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// watchesBucket is the bbolt bucket holding one JSON document per watch
+var watchesBucket = []byte("watches")
+
+// ErrNotFound is returned when a watch ID is unknown
+var ErrNotFound = errors.New("watch not found")
+
+// ErrInvalid is returned, wrapped, for watches that cannot be scheduled
+var ErrInvalid = errors.New("invalid watch")
+
+// Watch is a page or search query scraped on a schedule
+type Watch struct {
+	ID string `json:"id"`
+
+	// Exactly one of URL and Query is set. A query watch searches the
+	// sites and scrapes the first Limit results.
+	URL   string `json:"url,omitempty"`
+	Query string `json:"query,omitempty"`
+	Site  string `json:"site,omitempty"`
+	Limit int    `json:"limit,omitempty"`
+
+	// Exactly one of Cron and Interval is set; Interval is a Go duration
+	Cron     string `json:"cron,omitempty"`
+	Interval string `json:"interval,omitempty"`
+
+	// Timeout bounds each product scrape, as a Go duration
+	Timeout string `json:"timeout,omitempty"`
+
+	CreatedAt  time.Time  `json:"created_at"`
+	NextRun    time.Time  `json:"next_run"`
+	LastRun    *time.Time `json:"last_run,omitempty"`
+	LastResult *RunResult `json:"last_result,omitempty"`
+}
+
+// RunResult summarizes one run of a watch
+type RunResult struct {
+	Succeeded int `json:"succeeded"`
+	Failed    int `json:"failed"`
+
+	// Error is set when the run failed as a whole, e.g. a search error
+	Error string `json:"error,omitempty"`
+
+	Duration string `json:"duration"`
+}
+
+// RunFunc executes one run of a watch
+type RunFunc func(ctx context.Context, w Watch) RunResult
+
+// Config controls how watches are run
+type Config struct {
+	// MaxConcurrent is the number of watches running at the same time
+	MaxConcurrent int
+
+	// MinInterval is the shortest time allowed between two runs of a watch
+	MinInterval time.Duration
+
+	// MaxJitter bounds the random delay added to every run; a run is never
+	// delayed by more than a tenth of the time to the following one
+	MaxJitter time.Duration
+}
+
+// entry is a loaded watch and its runtime state
+type entry struct {
+	watch    Watch
+	schedule Schedule
+	cancel   context.CancelFunc // set while running
+}
+
+// Scheduler owns the watchlist and runs due watches
+type Scheduler struct {
+	db  *bolt.DB
+	cfg Config
+	run RunFunc
+
+	mu      sync.Mutex
+	entries map[string]*entry
+	slots   chan struct{}
+	wake    chan struct{}
+
+	stop    context.Context
+	stopAll context.CancelFunc
+	wg      sync.WaitGroup
+}
+
+// NewScheduler loads the watchlist from db and starts running it. Watches
+// that came due while the process was stopped run shortly after start.
+func NewScheduler(db *bolt.DB, cfg Config, run RunFunc) (*Scheduler, error) {
+	if cfg.MaxConcurrent <= 0 {
+		cfg.MaxConcurrent = 1
+	}
+
+	s := &Scheduler{
+		db:      db,
+		cfg:     cfg,
+		run:     run,
+		entries: make(map[string]*entry),
+		slots:   make(chan struct{}, cfg.MaxConcurrent),
+		wake:    make(chan struct{}, 1),
+	}
+	s.stop, s.stopAll = context.WithCancel(context.Background())
+
+	now := time.Now()
+	err := db.Update(func(tx *bolt.Tx) error {
+		b, err := tx.CreateBucketIfNotExists(watchesBucket)
+		if err != nil {
+			return err
+		}
+		return b.ForEach(func(k, v []byte) error {
+			var w Watch
+			if err := json.Unmarshal(v, &w); err != nil {
+				log.Printf("watch: skipping unreadable watch %s: %v", k, err)
+				return nil
+			}
+			schedule, err := parseSchedule(w)
+			if err != nil {
+				log.Printf("watch: skipping watch %s: %v", k, err)
+				return nil
+			}
+			if w.NextRun.Before(now) {
+				w.NextRun = now.Add(randomDuration(cfg.MaxJitter)).UTC()
+			}
+			s.entries[w.ID] = &entry{watch: w, schedule: schedule}
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to load watches: %w", err)
+	}
+
+	s.wg.Add(1)
+	go s.loop()
+	return s, nil
+}
+
+// Add validates w, schedules its first run and stores it
+func (s *Scheduler) Add(w Watch) (*Watch, error) {
+	if (w.URL == "") == (w.Query == "") {
+		return nil, fmt.Errorf("%w: exactly one of 'url' and 'query' is required", ErrInvalid)
+	}
+	if w.Timeout != "" {
+		if timeout, err := time.ParseDuration(w.Timeout); err != nil || timeout <= 0 {
+			return nil, fmt.Errorf("%w: timeout '%s' is not a duration such as 30s", ErrInvalid, w.Timeout)
+		}
+	}
+	schedule, err := parseSchedule(w)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	if gap := shortestGap(schedule, now); gap < s.cfg.MinInterval {
+		return nil, fmt.Errorf("%w: runs must be at least %s apart, this schedule runs every %s", ErrInvalid, s.cfg.MinInterval, gap)
+	}
+
+	id, err := newID()
+	if err != nil {
+		return nil, err
+	}
+	w.ID = id
+	w.CreatedAt = now.UTC()
+	w.NextRun = s.nextRun(schedule, now)
+	w.LastRun, w.LastResult = nil, nil
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.stop.Err() != nil {
+		return nil, errors.New("watch scheduler closed")
+	}
+	if err := s.save(&w); err != nil {
+		return nil, err
+	}
+	s.entries[id] = &entry{watch: w, schedule: schedule}
+	s.notify()
+	return &w, nil
+}
+
+// List returns every watch, oldest first
+func (s *Scheduler) List() []Watch {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	watches := make([]Watch, 0, len(s.entries))
+	for _, e := range s.entries {
+		watches = append(watches, e.watch)
+	}
+	sort.Slice(watches, func(a, b int) bool { return watches[a].CreatedAt.Before(watches[b].CreatedAt) })
+	return watches
+}
+
+// Get returns the current state of a watch
+func (s *Scheduler) Get(id string) (*Watch, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, ok := s.entries[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	w := e.watch
+	return &w, nil
+}
+
+// Delete removes a watch, stopping its run if one is in progress
+func (s *Scheduler) Delete(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, ok := s.entries[id]
+	if !ok {
+		return ErrNotFound
+	}
+	if e.cancel != nil {
+		e.cancel()
+	}
+	delete(s.entries, id)
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(watchesBucket).Delete([]byte(id))
+	})
+}
+
+// Close stops scheduling, interrupts running watches and waits for them
+func (s *Scheduler) Close() {
+	s.stopAll()
+	s.wg.Wait()
+}
+
+// loop starts due watches and sleeps until the next one is due
+func (s *Scheduler) loop() {
+	defer s.wg.Done()
+
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+	for {
+		select {
+		case <-s.stop.Done():
+			return
+		case <-timer.C:
+		case <-s.wake:
+		}
+		timer.Reset(s.dispatch(time.Now()))
+	}
+}
+
+// dispatch starts every watch due at now and returns how long to wait for
+// the next one
+func (s *Scheduler) dispatch(now time.Time) time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	wait := time.Hour
+	for _, e := range s.entries {
+		if e.cancel == nil && !e.watch.NextRun.After(now) {
+			// The next run is scheduled now, so a slow run is not repeated
+			// back to back
+			e.watch.NextRun = s.nextRun(e.schedule, now)
+			if err := s.save(&e.watch); err != nil {
+				log.Printf("watch: failed to save watch %s: %v", e.watch.ID, err)
+			}
+
+			ctx, cancel := context.WithCancel(s.stop)
+			e.cancel = cancel
+			s.wg.Add(1)
+			go s.execute(ctx, e)
+		}
+		if e.cancel == nil {
+			if d := e.watch.NextRun.Sub(now); d < wait {
+				wait = d
+			}
+		}
+	}
+	return wait
+}
+
+// execute runs one watch once a slot is free and records the result
+func (s *Scheduler) execute(ctx context.Context, e *entry) {
+	defer s.wg.Done()
+
+	s.mu.Lock()
+	w := e.watch
+	s.mu.Unlock()
+
+	var result RunResult
+	var started time.Time
+	select {
+	case s.slots <- struct{}{}:
+		started = time.Now()
+		if ctx.Err() == nil {
+			result = s.run(ctx, w)
+			result.Duration = time.Since(started).Round(time.Millisecond).String()
+		}
+		<-s.slots
+	case <-ctx.Done():
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	interrupted := ctx.Err() != nil
+	e.cancel()
+	e.cancel = nil
+	s.notify()
+
+	// Deleted watches and runs interrupted by shutdown are not recorded
+	if interrupted {
+		return
+	}
+	at := started.UTC()
+	e.watch.LastRun = &at
+	e.watch.LastResult = &result
+	if err := s.save(&e.watch); err != nil {
+		log.Printf("watch: failed to save watch %s: %v", e.watch.ID, err)
+	}
+}
+
+// notify wakes the loop so it recomputes the next due watch
+func (s *Scheduler) notify() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// nextRun returns the run after now with jitter applied
+func (s *Scheduler) nextRun(schedule Schedule, now time.Time) time.Time {
+	next := schedule.Next(now)
+	if next.IsZero() {
+		// The schedule never matches again; park the watch far away
+		return now.Add(cronHorizon).UTC()
+	}
+	jitter := min(s.cfg.MaxJitter, next.Sub(now)/10)
+	return next.Add(randomDuration(jitter)).UTC()
+}
+
+// save writes w to the store
+func (s *Scheduler) save(w *Watch) error {
+	data, err := json.Marshal(w)
+	if err != nil {
+		return fmt.Errorf("failed to encode watch: %w", err)
+	}
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(watchesBucket).Put([]byte(w.ID), data)
+	})
+}
+
+// parseSchedule returns the schedule described by w's Cron or Interval
+func parseSchedule(w Watch) (Schedule, error) {
+	switch {
+	case (w.Cron == "") == (w.Interval == ""):
+		return nil, fmt.Errorf("%w: exactly one of 'cron' and 'interval' is required", ErrInvalid)
+	case w.Cron != "":
+		schedule, err := ParseCron(w.Cron)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalid, err)
+		}
+		return schedule, nil
+	default:
+		d, err := time.ParseDuration(w.Interval)
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("%w: interval '%s' is not a duration such as 6h", ErrInvalid, w.Interval)
+		}
+		return Every(d), nil
+	}
+}
+
+// shortestGap returns the shortest time between the next few runs of
+// schedule after now
+func shortestGap(schedule Schedule, now time.Time) time.Duration {
+	gap := time.Duration(math.MaxInt64)
+	prev := schedule.Next(now)
+	for i := 0; i < 24 && !prev.IsZero(); i++ {
+		next := schedule.Next(prev)
+		if next.IsZero() {
+			break
+		}
+		gap = min(gap, next.Sub(prev))
+		prev = next
+	}
+	return gap
+}
+
+// randomDuration returns a random duration in [0, max)
+func randomDuration(max time.Duration) time.Duration {
+	if max <= 0 {
+		return 0
+	}
+	return time.Duration(mrand.Int63n(int64(max)))
+}
+
+// newID returns a random watch identifier
+func newID() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate watch ID: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
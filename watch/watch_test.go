@@ -0,0 +1,131 @@
+package watch
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+func TestParseCron(t *testing.T) {
+	base := time.Date(2025, 10, 17, 10, 7, 30, 0, time.UTC) // a Friday
+	tests := []struct {
+		expr string
+		want time.Time
+	}{
+		{"*/15 * * * *", time.Date(2025, 10, 17, 10, 15, 0, 0, time.UTC)},
+		{"0 9,18 * * *", time.Date(2025, 10, 17, 18, 0, 0, 0, time.UTC)},
+		{"30 8 * * 1-5", time.Date(2025, 10, 20, 8, 30, 0, 0, time.UTC)},
+		{"0 0 * * 7", time.Date(2025, 10, 19, 0, 0, 0, 0, time.UTC)},
+		{"0 0 1 * 5", time.Date(2025, 10, 24, 0, 0, 0, 0, time.UTC)},
+		{"@daily", time.Date(2025, 10, 18, 0, 0, 0, 0, time.UTC)},
+		{"0 12 29 2 *", time.Date(2028, 2, 29, 12, 0, 0, 0, time.UTC)},
+		{"0 0 30 2 *", time.Time{}},
+	}
+	for _, tt := range tests {
+		s, err := ParseCron(tt.expr)
+		if err != nil {
+			t.Fatalf("ParseCron(%q): %v", tt.expr, err)
+		}
+		if got := s.Next(base); !got.Equal(tt.want) {
+			t.Errorf("%q: next = %v, want %v", tt.expr, got, tt.want)
+		}
+	}
+
+	for _, bad := range []string{"* * * *", "60 * * * *", "*/0 * * * *", "5-1 * * * *", "a * * * *"} {
+		if _, err := ParseCron(bad); err == nil {
+			t.Errorf("ParseCron(%q) succeeded, want error", bad)
+		}
+	}
+}
+
+func openTestDB(t *testing.T) *bolt.DB {
+	t.Helper()
+	db, err := bolt.Open(filepath.Join(t.TempDir(), "watch.db"), 0600, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func TestSchedulerRunsAndPersists(t *testing.T) {
+	db := openTestDB(t)
+	var runs atomic.Int32
+	run := func(ctx context.Context, w Watch) RunResult {
+		runs.Add(1)
+		return RunResult{Succeeded: 1}
+	}
+
+	s, err := NewScheduler(db, Config{MaxConcurrent: 1}, run)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w, err := s.Add(Watch{URL: "https://kontakt.az/a-b-c", Interval: "50ms"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	var got *Watch
+	for time.Now().Before(deadline) {
+		if got, err = s.Get(w.ID); err != nil {
+			t.Fatal(err)
+		}
+		if runs.Load() >= 2 && got.LastResult != nil {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if runs.Load() < 2 {
+		t.Fatalf("expected the watch to run repeatedly, ran %d times", runs.Load())
+	}
+	if got.LastRun == nil || got.LastResult == nil || got.LastResult.Succeeded != 1 {
+		t.Errorf("last run not recorded: %+v", got)
+	}
+	s.Close()
+
+	// The watchlist survives a restart
+	s, err = NewScheduler(db, Config{}, func(ctx context.Context, w Watch) RunResult { return RunResult{} })
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	if list := s.List(); len(list) != 1 || list[0].ID != w.ID {
+		t.Fatalf("expected the watch to be reloaded, got %+v", list)
+	}
+	if err := s.Delete(w.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Get(w.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound after delete, got %v", err)
+	}
+}
+
+func TestAddValidation(t *testing.T) {
+	s, err := NewScheduler(openTestDB(t), Config{MinInterval: time.Hour}, func(ctx context.Context, w Watch) RunResult { return RunResult{} })
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	for _, w := range []Watch{
+		{Interval: "2h"},
+		{URL: "https://kontakt.az/x", Query: "iphone", Interval: "2h"},
+		{URL: "https://kontakt.az/x"},
+		{URL: "https://kontakt.az/x", Interval: "2h", Cron: "@daily"},
+		{URL: "https://kontakt.az/x", Interval: "10m"},
+		{Query: "iphone", Cron: "*/5 * * * *"},
+	} {
+		if _, err := s.Add(w); !errors.Is(err, ErrInvalid) {
+			t.Errorf("Add(%+v): expected ErrInvalid, got %v", w, err)
+		}
+	}
+	if _, err := s.Add(Watch{Query: "iphone 15", Cron: "0 */6 * * *"}); err != nil {
+		t.Errorf("valid watch rejected: %v", err)
+	}
+}
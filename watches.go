@@ -0,0 +1,238 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"web-scrappers/scrappers"
+	"web-scrappers/watch"
+
+	"github.com/gorilla/mux"
+	bolt "go.etcd.io/bbolt"
+)
+
+const (
+	// defaultWatchConcurrency is how many watches run at the same time
+	defaultWatchConcurrency = 2
+
+	// defaultWatchMinInterval is the shortest schedule a watch may have
+	defaultWatchMinInterval = 5 * time.Minute
+
+	// defaultWatchJitter bounds the random delay added to every watch run
+	defaultWatchJitter = 2 * time.Minute
+
+	// defaultWatchQueryLimit is how many search results a query watch scrapes
+	defaultWatchQueryLimit = 5
+)
+
+// watchScheduler runs the watchlist; set up in main
+var watchScheduler *watch.Scheduler
+
+// WatchRequest is the body of POST /api/v1/watches
+type WatchRequest struct {
+	// URL is a product page to scrape; Site may be empty to detect it
+	URL  string `json:"url,omitempty"`
+	Site string `json:"site,omitempty"`
+
+	// Query is searched on every searchable site, or only Site, and the
+	// first Limit results are scraped
+	Query string `json:"query,omitempty"`
+	Limit int    `json:"limit,omitempty"`
+
+	// Cron is a five-field cron expression and Interval a Go duration;
+	// exactly one is required
+	Cron     string `json:"cron,omitempty"`
+	Interval string `json:"interval,omitempty"`
+
+	// Timeout bounds each product scrape, as a Go duration
+	Timeout string `json:"timeout,omitempty"`
+}
+
+// WatchListResponse is returned by GET /api/v1/watches
+type WatchListResponse struct {
+	Watches []watch.Watch `json:"watches"`
+	Count   int           `json:"count"`
+}
+
+// openWatches starts the watch scheduler configured from the environment.
+// WATCH_CONCURRENCY limits watches running at once, WATCH_MIN_INTERVAL is
+// the shortest schedule accepted and WATCH_JITTER the largest random delay
+// added to a run.
+func openWatches(db *bolt.DB) (*watch.Scheduler, error) {
+	cfg := watch.Config{
+		MaxConcurrent: envInt("WATCH_CONCURRENCY", defaultWatchConcurrency),
+		MinInterval:   defaultWatchMinInterval,
+		MaxJitter:     defaultWatchJitter,
+	}
+	for name, target := range map[string]*time.Duration{
+		"WATCH_MIN_INTERVAL": &cfg.MinInterval,
+		"WATCH_JITTER":       &cfg.MaxJitter,
+	} {
+		if raw := os.Getenv(name); raw != "" {
+			d, err := time.ParseDuration(raw)
+			if err != nil {
+				return nil, fmt.Errorf("invalid %s: %w", name, err)
+			}
+			*target = d
+		}
+	}
+	return watch.NewScheduler(db, cfg, runWatch)
+}
+
+// runWatch is the watch.RunFunc: it scrapes the watched page, or the top
+// search results of the watched query. Scrapes go through the coalescer,
+// so every product scraped is recorded in price history.
+func runWatch(ctx context.Context, w watch.Watch) watch.RunResult {
+	opts := scrappers.ScrapeOptions{}
+	if w.Timeout != "" {
+		opts.Timeout, _ = time.ParseDuration(w.Timeout)
+	}
+
+	items := []ScrapRequest{{Site: w.Site, URI: w.URL}}
+	if w.Query != "" {
+		searchers := scrappers.GetSearchers()
+		if w.Site != "" {
+			// A stored watch outlives changes to the scrapers
+			searcher, ok := searchers[w.Site]
+			if !ok {
+				return watch.RunResult{Error: fmt.Sprintf("site '%s' is no longer searchable", w.Site)}
+			}
+			searchers = map[string]scrappers.Searcher{w.Site: searcher}
+		}
+
+		searchCtx, cancel := context.WithTimeout(ctx, defaultSearchTimeout)
+		resp := searchSites(searchCtx, searchers, w.Query, false)
+		cancel()
+
+		limit := w.Limit
+		if limit <= 0 {
+			limit = defaultWatchQueryLimit
+		}
+		items = items[:0]
+		for _, item := range resp.Items[:min(limit, len(resp.Items))] {
+			items = append(items, ScrapRequest{Site: item.Site, URI: item.URL})
+		}
+		if len(items) == 0 {
+			var failures []string
+			for _, site := range resp.Sites {
+				if site.Error != nil {
+					failures = append(failures, site.Site+": "+site.Error.Message)
+				}
+			}
+			if len(failures) > 0 {
+				return watch.RunResult{Error: "search failed on " + strings.Join(failures, "; ")}
+			}
+			return watch.RunResult{}
+		}
+	}
+
+	perSite := envInt("BATCH_SITE_CONCURRENCY", defaultBatchSiteConcurrency)
+	batch := newBatchResponse(runBatch(ctx, items, perSite, opts))
+	result := watch.RunResult{Succeeded: batch.Succeeded, Failed: batch.Failed}
+	if len(items) == 1 && batch.Failed == 1 {
+		result.Error = batch.Results[0].Error.Message
+	}
+	return result
+}
+
+func handleListWatches(w http.ResponseWriter, r *http.Request) {
+	watches := watchScheduler.List()
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(WatchListResponse{Watches: watches, Count: len(watches)})
+}
+
+func handleCreateWatch(w http.ResponseWriter, r *http.Request) {
+	var req WatchRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, ErrorResponse{
+			Error:   "invalid_body",
+			Message: fmt.Sprintf("Request body must be JSON: %v", err),
+		})
+		return
+	}
+
+	// Check the target now rather than failing on every run
+	site := req.Site
+	if req.URL != "" {
+		siteID, _, err := resolveScraper(req.Site, req.URL)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, resolveErrorResponse(req.Site, req.URL, err))
+			return
+		}
+		site = siteID
+	} else if req.Site != "" {
+		if _, ok := scrappers.GetSearchers()[req.Site]; !ok {
+			writeError(w, http.StatusBadRequest, ErrorResponse{
+				Error:   "unsupported_site",
+				Message: fmt.Sprintf("Site '%s' does not support search. Use /api/v1/sites to see available sites", req.Site),
+			})
+			return
+		}
+	}
+
+	created, err := watchScheduler.Add(watch.Watch{
+		URL:      req.URL,
+		Site:     site,
+		Query:    strings.TrimSpace(req.Query),
+		Limit:    req.Limit,
+		Cron:     req.Cron,
+		Interval: req.Interval,
+		Timeout:  req.Timeout,
+	})
+	if err != nil {
+		if errors.Is(err, watch.ErrInvalid) {
+			writeError(w, http.StatusBadRequest, ErrorResponse{
+				Error:   "invalid_parameters",
+				Message: err.Error(),
+			})
+			return
+		}
+		writeWatchError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Location", "/api/v1/watches/"+created.ID)
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(created)
+}
+
+func handleGetWatch(w http.ResponseWriter, r *http.Request) {
+	found, err := watchScheduler.Get(mux.Vars(r)["id"])
+	if err != nil {
+		writeWatchError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(found)
+}
+
+func handleDeleteWatch(w http.ResponseWriter, r *http.Request) {
+	if err := watchScheduler.Delete(mux.Vars(r)["id"]); err != nil {
+		writeWatchError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// writeWatchError reports a failed watch lookup or update
+func writeWatchError(w http.ResponseWriter, err error) {
+	if errors.Is(err, watch.ErrNotFound) {
+		writeError(w, http.StatusNotFound, ErrorResponse{
+			Error:   "watch_not_found",
+			Message: "No watch exists with this ID",
+		})
+		return
+	}
+	writeError(w, http.StatusInternalServerError, ErrorResponse{
+		Error:   "watch_store_failed",
+		Message: fmt.Sprintf("Failed to access watch: %v", err),
+	})
+}
@@ -0,0 +1,17 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"web-scrappers/watch"
+)
+
+func TestRunWatchUnsearchableSite(t *testing.T) {
+	// A watch stored before its site lost search support
+	result := runWatch(context.Background(), watch.Watch{Site: "gone", Query: "iphone"})
+	if !strings.Contains(result.Error, "no longer searchable") {
+		t.Errorf("result = %+v, want the site reported as not searchable", result)
+	}
+}